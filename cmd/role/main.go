@@ -10,6 +10,7 @@ import (
 
 	"github.com/joho/godotenv"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -20,6 +21,8 @@ func printUsage() {
 	fmt.Println("  go run ./cmd/role revoke <email>")
 	fmt.Println("  go run ./cmd/role show <email>")
 	fmt.Println("  go run ./cmd/role list")
+	fmt.Println("  go run ./cmd/role reset-2fa <email>")
+	fmt.Println("  go run ./cmd/role logout <email>")
 }
 
 func main() {
@@ -27,11 +30,11 @@ func main() {
 
 	action := strings.ToLower(strings.TrimSpace(argAt(1)))
 	emailArg := strings.ToLower(strings.TrimSpace(argAt(2)))
-	if action != "grant" && action != "revoke" && action != "show" && action != "list" {
+	if action != "grant" && action != "revoke" && action != "show" && action != "list" && action != "reset-2fa" && action != "logout" {
 		printUsage()
 		os.Exit(1)
 	}
-	if (action == "grant" || action == "revoke" || action == "show") && emailArg == "" {
+	if action != "list" && emailArg == "" {
 		fmt.Fprintln(os.Stderr, "Email is required for this action.")
 		printUsage()
 		os.Exit(1)
@@ -58,11 +61,13 @@ func main() {
 		_ = client.Disconnect(context.Background())
 	}()
 
-	users := client.Database(dbName).Collection("users")
+	database := client.Database(dbName)
+	users := database.Collection("users")
+	sessions := database.Collection("sessions")
 
 	switch action {
 	case "list":
-		cursor, err := users.Find(ctx, bson.M{}, options.Find().SetProjection(bson.M{"email": 1, "role": 1}).SetSort(bson.D{{Key: "email", Value: 1}}))
+		cursor, err := users.Find(ctx, bson.M{}, options.Find().SetProjection(bson.M{"email": 1, "role": 1, "emailVerified": 1, "totpEnabled": 1}).SetSort(bson.D{{Key: "email", Value: 1}}))
 		if err != nil {
 			log.Fatalf("Role command failed: %v", err)
 		}
@@ -84,13 +89,13 @@ func main() {
 			if role == "" || role == "<nil>" {
 				role = "user"
 			}
-			fmt.Printf("%s | role=%s\n", defaultIfEmpty(email, "-"), role)
+			fmt.Printf("%s | role=%s | verified=%s | 2fa=%s\n", defaultIfEmpty(email, "-"), role, verificationLabel(item), twoFactorLabel(item))
 		}
 		return
 	}
 
 	var user bson.M
-	err = users.FindOne(ctx, bson.M{"email": emailArg}, options.FindOne().SetProjection(bson.M{"email": 1, "role": 1})).Decode(&user)
+	err = users.FindOne(ctx, bson.M{"email": emailArg}, options.FindOne().SetProjection(bson.M{"email": 1, "role": 1, "emailVerified": 1, "emailVerifiedAt": 1, "totpEnabled": 1})).Decode(&user)
 	if err == mongo.ErrNoDocuments {
 		fmt.Fprintf(os.Stderr, "User not found: %s\n", emailArg)
 		os.Exit(1)
@@ -99,6 +104,7 @@ func main() {
 		log.Fatalf("Role command failed: %v", err)
 	}
 
+	userID, _ := user["_id"].(primitive.ObjectID)
 	email := strings.TrimSpace(fmt.Sprint(user["email"]))
 	role := strings.TrimSpace(fmt.Sprint(user["role"]))
 	if role == "" || role == "<nil>" {
@@ -106,7 +112,31 @@ func main() {
 	}
 
 	if action == "show" {
-		fmt.Printf("%s | role=%s\n", defaultIfEmpty(email, "-"), role)
+		fmt.Printf("%s | role=%s | verified=%s | 2fa=%s\n", defaultIfEmpty(email, "-"), role, verificationLabel(user), twoFactorLabel(user))
+		if verifiedAt, ok := user["emailVerifiedAt"].(primitive.DateTime); ok {
+			fmt.Printf("  email verified at %s\n", verifiedAt.Time().UTC().Format(time.RFC3339))
+		}
+		return
+	}
+
+	if action == "reset-2fa" {
+		_, err = users.UpdateOne(ctx, bson.M{"email": emailArg}, bson.M{
+			"$set":   bson.M{"totpEnabled": false, "updatedAt": time.Now().UTC()},
+			"$unset": bson.M{"totpSecret": "", "totpPendingSecret": "", "totpLastStep": "", "recoveryCodes": ""},
+		})
+		if err != nil {
+			log.Fatalf("Role command failed: %v", err)
+		}
+		fmt.Printf("Updated: two-factor authentication reset for %s\n", emailArg)
+		return
+	}
+
+	if action == "logout" {
+		result, err := sessions.DeleteMany(ctx, bson.M{"userId": userID.Hex()})
+		if err != nil {
+			log.Fatalf("Role command failed: %v", err)
+		}
+		fmt.Printf("Signed out: %s (%d session(s) removed)\n", emailArg, result.DeletedCount)
 		return
 	}
 
@@ -125,7 +155,14 @@ func main() {
 		log.Fatalf("Role command failed: %v", err)
 	}
 
-	fmt.Printf("Updated: %s -> role='%s'\n", emailArg, targetRole)
+	// Active sessions carry a copy of the role; update them so the change applies
+	// on the next request instead of after the session expires.
+	sessionResult, err := sessions.UpdateMany(ctx, bson.M{"userId": userID.Hex()}, bson.M{"$set": bson.M{"role": targetRole, "updatedAt": time.Now().UTC()}})
+	if err != nil {
+		log.Fatalf("Role command failed: %v", err)
+	}
+
+	fmt.Printf("Updated: %s -> role='%s' (%d active session(s) updated)\n", emailArg, targetRole, sessionResult.ModifiedCount)
 }
 
 func argAt(index int) string {
@@ -135,6 +172,21 @@ func argAt(index int) string {
 	return os.Args[index]
 }
 
+func verificationLabel(user bson.M) string {
+	verified, ok := user["emailVerified"].(bool)
+	if !ok || verified {
+		return "yes"
+	}
+	return "no"
+}
+
+func twoFactorLabel(user bson.M) string {
+	if enabled, ok := user["totpEnabled"].(bool); ok && enabled {
+		return "on"
+	}
+	return "off"
+}
+
 func defaultIfEmpty(value, fallback string) string {
 	if strings.TrimSpace(value) == "" {
 		return fallback
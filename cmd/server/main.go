@@ -15,6 +15,7 @@ import (
 	"easybook/internal/handlers"
 	"easybook/internal/middleware"
 	"easybook/internal/models"
+	"easybook/internal/ratelimit"
 	"easybook/internal/session"
 	"easybook/internal/view"
 )
@@ -49,10 +50,25 @@ func main() {
 	if err != nil {
 		log.Fatalf("Startup failed: %v", err)
 	}
+	sessionManager.SetTimeouts(session.Timeouts{
+		Idle:             time.Duration(env.SessionIdleMinutes) * time.Minute,
+		Absolute:         time.Duration(env.SessionAbsoluteHours) * time.Hour,
+		RememberIdle:     time.Duration(env.SessionRememberIdleDays) * 24 * time.Hour,
+		RememberAbsolute: time.Duration(env.SessionRememberDays) * 24 * time.Hour,
+	})
 
 	store := models.NewStore(database)
 	renderer := view.NewRenderer("views")
 	app := handlers.NewApp(env, store, sessionManager, renderer, "views")
+	if env.RateLimitBackend == "mongo" {
+		app.RateLimits = ratelimit.NewMongoStore(database.Collection("rate_limits"))
+	}
+
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+	app.WatchNotifications(backgroundCtx)
+	go app.Jobs.Run(backgroundCtx)
+	go app.Scheduler.Run(backgroundCtx)
 
 	server := &http.Server{
 		Addr:         fmt.Sprintf(":%d", env.Port),
@@ -61,6 +77,7 @@ func main() {
 		WriteTimeout: 30 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
+	server.RegisterOnShutdown(app.Notifications.Close)
 
 	go func() {
 		middleware.LogStartup(env.Port)
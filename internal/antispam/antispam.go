@@ -0,0 +1,164 @@
+// Package antispam screens anonymous form submissions. Each check adds to a
+// score instead of rejecting on its own, so callers decide what a suspicious
+// submission means for their form; only the per-IP quota blocks outright.
+package antispam
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"easybook/internal/ratelimit"
+)
+
+const (
+	// HoneypotField is hidden from people; anything typed into it came from
+	// a bot filling every input.
+	HoneypotField = "website"
+	// TimestampField carries the signed time the form was rendered.
+	TimestampField = "_form_ts"
+
+	honeypotScore       = 100
+	missingStampScore   = 60
+	tooFastScore        = 60
+	staleFormScore      = 30
+	allowedClockSkew    = 5 * time.Second
+	signatureHexLength  = 32
+	defaultMaxFormAge   = 24 * time.Hour
+	quotaKeyPrefix      = "antispam:"
+	reasonHoneypot      = "honeypot field filled"
+	reasonMissingStamp  = "missing or invalid form timestamp"
+	reasonTooFast       = "form submitted too fast"
+	reasonStaleForm     = "form rendered too long ago"
+	reasonQuotaExceeded = "submission quota exceeded"
+)
+
+// Submission is one form post being screened.
+type Submission struct {
+	Form   string
+	IP     string
+	Fields map[string]string
+}
+
+// Scorer is a content check plugged into the guard. It returns the points to
+// add, zero for clean content, and a short reason for the log.
+type Scorer func(ctx context.Context, submission Submission) (int, string)
+
+type Config struct {
+	Secret      string
+	MinFillTime time.Duration
+	// MaxFormAge defaults to a day.
+	MaxFormAge time.Duration
+	// Threshold is the score at which a submission counts as spam. Zero
+	// turns scoring off.
+	Threshold int
+	// Quotas limits submissions per client IP for each form name.
+	Quotas map[string]ratelimit.Limit
+}
+
+type Verdict struct {
+	Score   int
+	Reasons []string
+	Spam    bool
+	// Blocked is set when the IP is over its quota for the form; the
+	// submission should not be processed at all.
+	Blocked    bool
+	RetryAfter time.Duration
+}
+
+type Guard struct {
+	config  Config
+	secret  []byte
+	scorers []Scorer
+	now     func() time.Time
+}
+
+func NewGuard(config Config) *Guard {
+	if config.MaxFormAge <= 0 {
+		config.MaxFormAge = defaultMaxFormAge
+	}
+	mac := hmac.New(sha256.New, []byte(config.Secret))
+	mac.Write([]byte("antispam"))
+	return &Guard{config: config, secret: mac.Sum(nil), now: time.Now}
+}
+
+func (g *Guard) AddScorer(scorer Scorer) {
+	g.scorers = append(g.scorers, scorer)
+}
+
+// Token returns the value for TimestampField when rendering form.
+func (g *Guard) Token(form string) string {
+	stamp := strconv.FormatInt(g.now().Unix(), 10)
+	return stamp + "." + g.sign(form, stamp)
+}
+
+// Check scores the submission. The quota is taken from store, so it is shared
+// the same way the app's rate limits are; a store error skips the quota.
+func (g *Guard) Check(ctx context.Context, store ratelimit.Store, submission Submission) Verdict {
+	var verdict Verdict
+	add := func(score int, reason string) {
+		if score <= 0 {
+			return
+		}
+		verdict.Score += score
+		verdict.Reasons = append(verdict.Reasons, reason)
+	}
+
+	if limit, ok := g.config.Quotas[submission.Form]; ok && limit.Enabled() && store != nil {
+		result, err := store.Take(ctx, quotaKeyPrefix+submission.Form+":"+submission.IP, limit)
+		switch {
+		case err != nil:
+			log.Printf("antispam quota for %s unavailable: %v", submission.Form, err)
+		case !result.Allowed:
+			verdict.Blocked = true
+			verdict.RetryAfter = result.RetryAfter
+			verdict.Reasons = append(verdict.Reasons, reasonQuotaExceeded)
+			return verdict
+		}
+	}
+
+	if strings.TrimSpace(submission.Fields[HoneypotField]) != "" {
+		add(honeypotScore, reasonHoneypot)
+	}
+
+	renderedAt, ok := g.verify(submission.Form, submission.Fields[TimestampField])
+	now := g.now()
+	switch {
+	case !ok || renderedAt.After(now.Add(allowedClockSkew)):
+		add(missingStampScore, reasonMissingStamp)
+	case now.Sub(renderedAt) < g.config.MinFillTime:
+		add(tooFastScore, reasonTooFast)
+	case now.Sub(renderedAt) > g.config.MaxFormAge:
+		add(staleFormScore, reasonStaleForm)
+	}
+
+	for _, scorer := range g.scorers {
+		add(scorer(ctx, submission))
+	}
+
+	verdict.Spam = g.config.Threshold > 0 && verdict.Score >= g.config.Threshold
+	return verdict
+}
+
+func (g *Guard) verify(form, token string) (time.Time, bool) {
+	stamp, signature, found := strings.Cut(strings.TrimSpace(token), ".")
+	if !found || !hmac.Equal([]byte(signature), []byte(g.sign(form, stamp))) {
+		return time.Time{}, false
+	}
+	seconds, err := strconv.ParseInt(stamp, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(seconds, 0), true
+}
+
+func (g *Guard) sign(form, stamp string) string {
+	mac := hmac.New(sha256.New, g.secret)
+	mac.Write([]byte(form + "|" + stamp))
+	return hex.EncodeToString(mac.Sum(nil))[:signatureHexLength]
+}
@@ -0,0 +1,82 @@
+package antispam
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"easybook/internal/ratelimit"
+)
+
+func newTestGuard(now *time.Time) *Guard {
+	guard := NewGuard(Config{
+		Secret:      "antispam-test-secret",
+		MinFillTime: 3 * time.Second,
+		Threshold:   50,
+		Quotas:      map[string]ratelimit.Limit{"contact": {Requests: 2, Period: time.Hour}},
+	})
+	guard.now = func() time.Time { return *now }
+	return guard
+}
+
+func TestGuardScoresFormSignals(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	guard := newTestGuard(&now)
+	token := guard.Token("contact")
+
+	now = now.Add(10 * time.Second)
+	clean := guard.Check(context.Background(), nil, Submission{Form: "contact", Fields: map[string]string{TimestampField: token}})
+	if clean.Spam || clean.Score != 0 {
+		t.Fatalf("expected a clean verdict, got %+v", clean)
+	}
+
+	honeypot := guard.Check(context.Background(), nil, Submission{Form: "contact", Fields: map[string]string{TimestampField: token, HoneypotField: "http://spam"}})
+	if !honeypot.Spam {
+		t.Fatalf("expected a filled honeypot to be spam, got %+v", honeypot)
+	}
+
+	if verdict := guard.Check(context.Background(), nil, Submission{Form: "register", Fields: map[string]string{TimestampField: token}}); !verdict.Spam {
+		t.Fatalf("expected a token signed for another form to be rejected, got %+v", verdict)
+	}
+	if verdict := guard.Check(context.Background(), nil, Submission{Form: "contact"}); !verdict.Spam {
+		t.Fatalf("expected a missing timestamp to be spam, got %+v", verdict)
+	}
+
+	fast := guard.Token("contact")
+	now = now.Add(time.Second)
+	if verdict := guard.Check(context.Background(), nil, Submission{Form: "contact", Fields: map[string]string{TimestampField: fast}}); !verdict.Spam {
+		t.Fatalf("expected a one-second fill to be spam, got %+v", verdict)
+	}
+}
+
+func TestGuardScorersAndQuota(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	guard := newTestGuard(&now)
+	guard.AddScorer(LinkScorer("message", 1, 30))
+	guard.AddScorer(KeywordScorer([]string{"message"}, []string{"casino"}, 25))
+	token := guard.Token("contact")
+	now = now.Add(time.Minute)
+
+	verdict := guard.Check(context.Background(), nil, Submission{Form: "contact", Fields: map[string]string{
+		TimestampField: token,
+		"message":      "Visit https://a.example and https://b.example for CASINO bonuses",
+	}})
+	if verdict.Score != 55 || !verdict.Spam || len(verdict.Reasons) != 2 {
+		t.Fatalf("expected both scorers to contribute, got %+v", verdict)
+	}
+
+	store := ratelimit.NewMemoryStore()
+	submission := Submission{Form: "contact", IP: "203.0.113.7", Fields: map[string]string{TimestampField: token}}
+	for i := 0; i < 2; i++ {
+		if verdict := guard.Check(context.Background(), store, submission); verdict.Blocked {
+			t.Fatalf("submission %d: expected to be within quota", i)
+		}
+	}
+	if verdict := guard.Check(context.Background(), store, submission); !verdict.Blocked || verdict.RetryAfter <= 0 {
+		t.Fatalf("expected the third submission to be blocked, got %+v", verdict)
+	}
+	submission.IP = "203.0.113.8"
+	if verdict := guard.Check(context.Background(), store, submission); verdict.Blocked {
+		t.Fatal("expected quotas to be per IP")
+	}
+}
@@ -0,0 +1,47 @@
+package antispam
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// LinkScorer adds points when field contains more than maxLinks links.
+func LinkScorer(field string, maxLinks, points int) Scorer {
+	return func(_ context.Context, submission Submission) (int, string) {
+		text := strings.ToLower(submission.Fields[field])
+		links := strings.Count(text, "http://") + strings.Count(text, "https://")
+		if links <= maxLinks {
+			return 0, ""
+		}
+		return points, fmt.Sprintf("%d links in %s", links, field)
+	}
+}
+
+// KeywordScorer adds points for each listed word found in one of fields.
+// Matching ignores case. Only fields the user typed should be listed, since
+// tokens and other generated values can contain any word by chance.
+func KeywordScorer(fields, words []string, points int) Scorer {
+	cleaned := make([]string, 0, len(words))
+	for _, word := range words {
+		if word = strings.ToLower(strings.TrimSpace(word)); word != "" {
+			cleaned = append(cleaned, word)
+		}
+	}
+
+	return func(_ context.Context, submission Submission) (int, string) {
+		var found []string
+		for _, word := range cleaned {
+			for _, field := range fields {
+				if strings.Contains(strings.ToLower(submission.Fields[field]), word) {
+					found = append(found, word)
+					break
+				}
+			}
+		}
+		if len(found) == 0 {
+			return 0, ""
+		}
+		return points * len(found), "blocked words: " + strings.Join(found, ", ")
+	}
+}
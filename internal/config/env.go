@@ -2,9 +2,11 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -25,6 +27,74 @@ type Env struct {
 	PresenceTTLSeconds         int
 	PresenceCapacity           int
 	PresenceMinIntervalSeconds int
+	AppBaseURL                 string
+	MailDriver                 string
+	MailFrom                   string
+	MailFileDir                string
+	SMTPHost                   string
+	SMTPPort                   int
+	SMTPUsername               string
+	SMTPPassword               string
+	PasswordResetTTLMinutes    int
+	EmailVerificationTTLHours  int
+	LoginMaxFailures           int
+	LoginIPMaxFailures         int
+	LoginFailureWindowMinutes  int
+	LoginLockoutMinutes        int
+	TOTPIssuer                 string
+	TOTPRequiredRoles          []string
+	SessionIdleMinutes         int
+	SessionAbsoluteHours       int
+	SessionRememberIdleDays    int
+	SessionRememberDays        int
+	OIDCProviders              []OIDCProvider
+	CSPReportOnly              bool
+	HSTSMaxAgeDays             int
+	TrustedProxies             []*net.IPNet
+	RateLimitBackend           string
+	RateLimitDefault           RateLimit
+	RateLimitAPI               RateLimit
+	RateLimitAuth              RateLimit
+	DataExportSyncLimit        int
+	DataExportTTLHours         int
+	AntiSpamMinFillSeconds     int
+	AntiSpamScoreThreshold     int
+	AntiSpamContactQuota       RateLimit
+	AntiSpamRegisterQuota      RateLimit
+	AntiSpamMaxLinks           int
+	AntiSpamBlockedWords       []string
+	NotificationStreamPingSecs int
+	NotificationEmailTypes     []string
+	JobWorkers                 int
+	JobMaxAttempts             int
+	JobRetrySeconds            int
+	JobPollSeconds             int
+	JobRetentionDays           int
+	BookingReminderDays        []int
+	ReviewRequestAfterDays     int
+	SchedulerIntervalMinutes   int
+	WaitlistHoldMinutes        int
+	BroadcastBatchSize         int
+	NotificationRetentionDays  int
+}
+
+// RateLimit is a "<requests>/<period>" setting such as "120/1m". A zero
+// value disables the limit.
+type RateLimit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// OIDCProvider is one single sign-on identity provider, configured through
+// OIDC_PROVIDERS=<id>,... and OIDC_<ID>_* variables.
+type OIDCProvider struct {
+	ID           string
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	AllowSignup  bool
 }
 
 func parseNumber(value string, fallback int) int {
@@ -47,6 +117,46 @@ func splitAndTrimCSV(value string) []string {
 	return out
 }
 
+// parseNumberList reads a comma-separated list of positive integers.
+func parseNumberList(value string) ([]int, bool) {
+	parts := splitAndTrimCSV(value)
+	numbers := make([]int, 0, len(parts))
+	for _, part := range parts {
+		number, err := strconv.Atoi(part)
+		if err != nil || number <= 0 {
+			return nil, false
+		}
+		numbers = append(numbers, number)
+	}
+	return numbers, true
+}
+
+// parseNetworks reads a comma-separated list of IP addresses and CIDR
+// ranges. A single address becomes a range of one.
+func parseNetworks(value string) ([]*net.IPNet, bool) {
+	parts := splitAndTrimCSV(value)
+	networks := make([]*net.IPNet, 0, len(parts))
+	for _, part := range parts {
+		if !strings.Contains(part, "/") {
+			ip := net.ParseIP(part)
+			if ip == nil {
+				return nil, false
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			part = fmt.Sprintf("%s/%d", ip, bits)
+		}
+		_, network, err := net.ParseCIDR(part)
+		if err != nil {
+			return nil, false
+		}
+		networks = append(networks, network)
+	}
+	return networks, true
+}
+
 func parseBool(value string, fallback bool) bool {
 	trimmed := strings.ToLower(strings.TrimSpace(value))
 	if trimmed == "" {
@@ -87,13 +197,86 @@ func Load() (Env, error) {
 		PresenceTTLSeconds:         parseNumber(os.Getenv("PRESENCE_TTL_SECONDS"), 60),
 		PresenceCapacity:           parseNumber(os.Getenv("PRESENCE_CAPACITY"), 1),
 		PresenceMinIntervalSeconds: parseNumber(os.Getenv("PRESENCE_MIN_INTERVAL_SECONDS"), 2),
+		AppBaseURL:                 strings.TrimRight(strings.TrimSpace(os.Getenv("APP_BASE_URL")), "/"),
+		MailDriver:                 strings.ToLower(defaultString(os.Getenv("MAIL_DRIVER"), "log")),
+		MailFrom:                   defaultString(os.Getenv("MAIL_FROM"), "Easy Booking <no-reply@easybooking.local>"),
+		MailFileDir:                defaultString(os.Getenv("MAIL_FILE_DIR"), "tmp/mail"),
+		SMTPHost:                   strings.TrimSpace(os.Getenv("SMTP_HOST")),
+		SMTPPort:                   parseNumber(os.Getenv("SMTP_PORT"), 587),
+		SMTPUsername:               strings.TrimSpace(os.Getenv("SMTP_USERNAME")),
+		SMTPPassword:               os.Getenv("SMTP_PASSWORD"),
+		PasswordResetTTLMinutes:    parseNumber(os.Getenv("PASSWORD_RESET_TTL_MINUTES"), 60),
+		EmailVerificationTTLHours:  parseNumber(os.Getenv("EMAIL_VERIFICATION_TTL_HOURS"), 48),
+		LoginMaxFailures:           parseNumber(os.Getenv("LOGIN_MAX_FAILURES"), 5),
+		LoginIPMaxFailures:         parseNumber(os.Getenv("LOGIN_IP_MAX_FAILURES"), 50),
+		LoginFailureWindowMinutes:  parseNumber(os.Getenv("LOGIN_FAILURE_WINDOW_MINUTES"), 15),
+		LoginLockoutMinutes:        parseNumber(os.Getenv("LOGIN_LOCKOUT_MINUTES"), 15),
+		TOTPIssuer:                 strings.TrimSpace(defaultString(os.Getenv("TOTP_ISSUER"), "Easy Booking")),
+		TOTPRequiredRoles:          splitAndTrimCSV(os.Getenv("TOTP_REQUIRED_ROLES")),
+		SessionIdleMinutes:         parseNumber(os.Getenv("SESSION_IDLE_MINUTES"), 120),
+		SessionAbsoluteHours:       parseNumber(os.Getenv("SESSION_ABSOLUTE_HOURS"), 24),
+		SessionRememberIdleDays:    parseNumber(os.Getenv("SESSION_REMEMBER_IDLE_DAYS"), 7),
+		SessionRememberDays:        parseNumber(os.Getenv("SESSION_REMEMBER_DAYS"), 30),
+		CSPReportOnly:              parseBool(os.Getenv("CSP_REPORT_ONLY"), false),
+		HSTSMaxAgeDays:             parseNumber(os.Getenv("HSTS_MAX_AGE_DAYS"), 180),
+		RateLimitBackend:           strings.ToLower(defaultString(os.Getenv("RATE_LIMIT_BACKEND"), "memory")),
+		DataExportSyncLimit:        parseNumber(os.Getenv("DATA_EXPORT_SYNC_LIMIT"), 500),
+		DataExportTTLHours:         parseNumber(os.Getenv("DATA_EXPORT_TTL_HOURS"), 24),
+		AntiSpamMinFillSeconds:     parseNumber(os.Getenv("ANTISPAM_MIN_FILL_SECONDS"), 3),
+		AntiSpamScoreThreshold:     parseNumber(os.Getenv("ANTISPAM_SCORE_THRESHOLD"), 50),
+		AntiSpamMaxLinks:           parseNumber(os.Getenv("ANTISPAM_MAX_LINKS"), 2),
+		AntiSpamBlockedWords:       splitAndTrimCSV(os.Getenv("ANTISPAM_BLOCKED_WORDS")),
+		NotificationStreamPingSecs: parseNumber(os.Getenv("NOTIFICATION_STREAM_PING_SECONDS"), 25),
+		NotificationEmailTypes:     splitAndTrimCSV(defaultString(os.Getenv("NOTIFICATION_EMAIL_TYPES"), "waitlist_available,booking_reminder")),
+		JobWorkers:                 parseNumber(os.Getenv("JOB_WORKERS"), 2),
+		JobMaxAttempts:             parseNumber(os.Getenv("JOB_MAX_ATTEMPTS"), 5),
+		JobRetrySeconds:            parseNumber(os.Getenv("JOB_RETRY_SECONDS"), 30),
+		JobPollSeconds:             parseNumber(os.Getenv("JOB_POLL_SECONDS"), 5),
+		JobRetentionDays:           parseNumber(os.Getenv("JOB_RETENTION_DAYS"), 7),
+		ReviewRequestAfterDays:     parseNumber(os.Getenv("REVIEW_REQUEST_AFTER_DAYS"), 1),
+		SchedulerIntervalMinutes:   parseNumber(os.Getenv("SCHEDULER_INTERVAL_MINUTES"), 15),
+		WaitlistHoldMinutes:        parseNumber(os.Getenv("WAITLIST_HOLD_MINUTES"), 60),
+		BroadcastBatchSize:         parseNumber(os.Getenv("BROADCAST_BATCH_SIZE"), 500),
+		NotificationRetentionDays:  parseNumber(os.Getenv("NOTIFICATION_RETENTION_DAYS"), 90),
+	}
+
+	var validationErrors []string
+	rateLimits := []struct {
+		name     string
+		fallback string
+		target   *RateLimit
+	}{
+		{"RATE_LIMIT_DEFAULT", "600/1m", &env.RateLimitDefault},
+		{"RATE_LIMIT_API", "300/1m", &env.RateLimitAPI},
+		{"RATE_LIMIT_AUTH", "20/10m", &env.RateLimitAuth},
+		{"ANTISPAM_CONTACT_QUOTA", "5/1h", &env.AntiSpamContactQuota},
+		{"ANTISPAM_REGISTER_QUOTA", "10/1h", &env.AntiSpamRegisterQuota},
+	}
+	for _, item := range rateLimits {
+		limit, ok := parseRateLimit(defaultString(os.Getenv(item.name), item.fallback))
+		if !ok {
+			validationErrors = append(validationErrors, item.name+" must look like 120/1m, or be off.")
+		}
+		*item.target = limit
+	}
+
+	trustedProxies, ok := parseNetworks(os.Getenv("TRUSTED_PROXIES"))
+	if !ok {
+		validationErrors = append(validationErrors, "TRUSTED_PROXIES must be a comma-separated list of IP addresses or CIDR ranges.")
+	}
+	env.TrustedProxies = trustedProxies
+
+	for _, id := range splitAndTrimCSV(os.Getenv("OIDC_PROVIDERS")) {
+		env.OIDCProviders = append(env.OIDCProviders, loadOIDCProvider(strings.ToLower(id)))
 	}
 
 	if env.DBName == "" {
 		env.DBName = "easybook_final"
 	}
+	if env.AppBaseURL == "" {
+		env.AppBaseURL = fmt.Sprintf("http://127.0.0.1:%d", env.Port)
+	}
 
-	var validationErrors []string
 	if env.MongoURI == "" {
 		validationErrors = append(validationErrors, "MONGO_URI is required.")
 	}
@@ -109,6 +292,84 @@ func Load() (Env, error) {
 	if env.PresenceMinIntervalSeconds <= 0 {
 		validationErrors = append(validationErrors, "PRESENCE_MIN_INTERVAL_SECONDS must be greater than 0.")
 	}
+	if env.MailDriver != "log" && env.MailDriver != "file" && env.MailDriver != "smtp" {
+		validationErrors = append(validationErrors, "MAIL_DRIVER must be one of: log, file, smtp.")
+	}
+	if env.MailDriver == "smtp" && env.SMTPHost == "" {
+		validationErrors = append(validationErrors, "SMTP_HOST is required when MAIL_DRIVER=smtp.")
+	}
+	if env.PasswordResetTTLMinutes <= 0 {
+		validationErrors = append(validationErrors, "PASSWORD_RESET_TTL_MINUTES must be greater than 0.")
+	}
+	if env.AntiSpamMinFillSeconds < 0 || env.AntiSpamScoreThreshold < 0 || env.AntiSpamMaxLinks < 0 {
+		validationErrors = append(validationErrors, "ANTISPAM_MIN_FILL_SECONDS, ANTISPAM_SCORE_THRESHOLD and ANTISPAM_MAX_LINKS must not be negative.")
+	}
+	if env.NotificationStreamPingSecs <= 0 {
+		validationErrors = append(validationErrors, "NOTIFICATION_STREAM_PING_SECONDS must be greater than 0.")
+	}
+	if env.JobWorkers <= 0 || env.JobMaxAttempts <= 0 || env.JobRetrySeconds <= 0 || env.JobPollSeconds <= 0 {
+		validationErrors = append(validationErrors, "JOB_WORKERS, JOB_MAX_ATTEMPTS, JOB_RETRY_SECONDS and JOB_POLL_SECONDS must be greater than 0.")
+	}
+	if env.JobRetentionDays <= 0 {
+		validationErrors = append(validationErrors, "JOB_RETENTION_DAYS must be greater than 0.")
+	}
+	reminderDays, ok := parseNumberList(defaultString(os.Getenv("BOOKING_REMINDER_DAYS"), "3,1"))
+	if !ok {
+		validationErrors = append(validationErrors, "BOOKING_REMINDER_DAYS must be a comma-separated list of positive day counts.")
+	}
+	env.BookingReminderDays = reminderDays
+	if env.ReviewRequestAfterDays < 0 {
+		validationErrors = append(validationErrors, "REVIEW_REQUEST_AFTER_DAYS must not be negative.")
+	}
+	if env.SchedulerIntervalMinutes <= 0 {
+		validationErrors = append(validationErrors, "SCHEDULER_INTERVAL_MINUTES must be greater than 0.")
+	}
+	if env.WaitlistHoldMinutes < 0 {
+		validationErrors = append(validationErrors, "WAITLIST_HOLD_MINUTES must not be negative.")
+	}
+	if env.BroadcastBatchSize <= 0 {
+		validationErrors = append(validationErrors, "BROADCAST_BATCH_SIZE must be greater than 0.")
+	}
+	if env.NotificationRetentionDays < 0 {
+		validationErrors = append(validationErrors, "NOTIFICATION_RETENTION_DAYS must not be negative.")
+	}
+	if env.DataExportSyncLimit < 0 {
+		validationErrors = append(validationErrors, "DATA_EXPORT_SYNC_LIMIT must not be negative.")
+	}
+	if env.DataExportTTLHours <= 0 {
+		validationErrors = append(validationErrors, "DATA_EXPORT_TTL_HOURS must be greater than 0.")
+	}
+	if env.EmailVerificationTTLHours <= 0 {
+		validationErrors = append(validationErrors, "EMAIL_VERIFICATION_TTL_HOURS must be greater than 0.")
+	}
+	if env.LoginMaxFailures <= 0 || env.LoginIPMaxFailures <= 0 {
+		validationErrors = append(validationErrors, "LOGIN_MAX_FAILURES and LOGIN_IP_MAX_FAILURES must be greater than 0.")
+	}
+	if env.LoginFailureWindowMinutes <= 0 || env.LoginLockoutMinutes <= 0 {
+		validationErrors = append(validationErrors, "LOGIN_FAILURE_WINDOW_MINUTES and LOGIN_LOCKOUT_MINUTES must be greater than 0.")
+	}
+	if env.SessionIdleMinutes <= 0 || env.SessionAbsoluteHours <= 0 {
+		validationErrors = append(validationErrors, "SESSION_IDLE_MINUTES and SESSION_ABSOLUTE_HOURS must be greater than 0.")
+	}
+	if env.SessionRememberIdleDays <= 0 || env.SessionRememberDays <= 0 {
+		validationErrors = append(validationErrors, "SESSION_REMEMBER_IDLE_DAYS and SESSION_REMEMBER_DAYS must be greater than 0.")
+	}
+	if env.RateLimitBackend != "memory" && env.RateLimitBackend != "mongo" {
+		validationErrors = append(validationErrors, "RATE_LIMIT_BACKEND must be one of: memory, mongo.")
+	}
+	if env.HSTSMaxAgeDays < 0 {
+		validationErrors = append(validationErrors, "HSTS_MAX_AGE_DAYS must not be negative.")
+	}
+	for _, provider := range env.OIDCProviders {
+		prefix := oidcEnvPrefix(provider.ID)
+		if strings.Trim(provider.ID, "abcdefghijklmnopqrstuvwxyz0123456789-_") != "" {
+			validationErrors = append(validationErrors, "OIDC_PROVIDERS entries may only contain letters, digits, '-' and '_'.")
+			continue
+		}
+		if provider.Issuer == "" || provider.ClientID == "" {
+			validationErrors = append(validationErrors, prefix+"ISSUER and "+prefix+"CLIENT_ID are required for OIDC provider "+provider.ID+".")
+		}
+	}
 	if len(validationErrors) > 0 {
 		return Env{}, fmt.Errorf("environment validation failed: %s", strings.Join(validationErrors, " "))
 	}
@@ -116,6 +377,44 @@ func Load() (Env, error) {
 	return env, nil
 }
 
+func parseRateLimit(value string) (RateLimit, bool) {
+	trimmed := strings.ToLower(strings.TrimSpace(value))
+	if trimmed == "off" || trimmed == "0" {
+		return RateLimit{}, true
+	}
+
+	requestsText, periodText, found := strings.Cut(trimmed, "/")
+	if !found {
+		return RateLimit{}, false
+	}
+	requests, err := strconv.Atoi(strings.TrimSpace(requestsText))
+	if err != nil || requests < 0 {
+		return RateLimit{}, false
+	}
+	period, err := time.ParseDuration(strings.TrimSpace(periodText))
+	if err != nil || period <= 0 {
+		return RateLimit{}, false
+	}
+	return RateLimit{Requests: requests, Period: period}, true
+}
+
+func loadOIDCProvider(id string) OIDCProvider {
+	prefix := oidcEnvPrefix(id)
+	return OIDCProvider{
+		ID:           id,
+		Name:         strings.TrimSpace(defaultString(os.Getenv(prefix+"NAME"), id)),
+		Issuer:       strings.TrimSpace(os.Getenv(prefix + "ISSUER")),
+		ClientID:     strings.TrimSpace(os.Getenv(prefix + "CLIENT_ID")),
+		ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+		Scopes:       strings.Fields(defaultString(os.Getenv(prefix+"SCOPES"), "openid email profile")),
+		AllowSignup:  parseBool(os.Getenv(prefix+"ALLOW_SIGNUP"), false),
+	}
+}
+
+func oidcEnvPrefix(id string) string {
+	return "OIDC_" + strings.ToUpper(strings.ReplaceAll(id, "-", "_")) + "_"
+}
+
 func defaultString(value, fallback string) string {
 	if strings.TrimSpace(value) == "" {
 		return fallback
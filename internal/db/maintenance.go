@@ -19,6 +19,9 @@ func EnsureStartupMaintenance(ctx context.Context, database *mongo.Database) err
 	if err := backfillBookingRoomIDs(ctx, database); err != nil {
 		return err
 	}
+	if err := backfillUserEmailVerification(ctx, database); err != nil {
+		return err
+	}
 
 	indexTasks := []struct {
 		collection string
@@ -34,8 +37,12 @@ func EnsureStartupMaintenance(ctx context.Context, database *mongo.Database) err
 		{collection: "hotels", model: mongo.IndexModel{Keys: bson.D{{Key: "location", Value: 1}}}},
 		{collection: "hotels", model: mongo.IndexModel{Keys: bson.D{{Key: "price_per_night", Value: 1}}}},
 		{collection: "contact_requests", model: mongo.IndexModel{Keys: bson.D{{Key: "createdAt", Value: -1}}}},
+		{collection: "contact_requests", model: mongo.IndexModel{Keys: bson.D{{Key: "status", Value: 1}, {Key: "createdAt", Value: -1}}}},
+		{collection: "contact_requests", model: mongo.IndexModel{Keys: bson.D{{Key: "assignedTo", Value: 1}, {Key: "createdAt", Value: -1}}}},
 		{collection: "bookings", model: mongo.IndexModel{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "createdAt", Value: -1}}}},
 		{collection: "bookings", model: mongo.IndexModel{Keys: bson.D{{Key: "roomId", Value: 1}, {Key: "checkIn", Value: 1}, {Key: "checkOut", Value: 1}}}},
+		{collection: "bookings", model: mongo.IndexModel{Keys: bson.D{{Key: "checkIn", Value: 1}}}},
+		{collection: "bookings", model: mongo.IndexModel{Keys: bson.D{{Key: "checkOut", Value: 1}}}},
 		{
 			collection: "room_calendar",
 			model: mongo.IndexModel{
@@ -43,6 +50,9 @@ func EnsureStartupMaintenance(ctx context.Context, database *mongo.Database) err
 				Options: options.Index().SetUnique(true),
 			},
 		},
+		{collection: "room_calendar", model: mongo.IndexModel{Keys: bson.D{{Key: "holdUntil", Value: 1}}, Options: options.Index().SetSparse(true)}},
+		{collection: "room_calendar", model: mongo.IndexModel{Keys: bson.D{{Key: "waitlistId", Value: 1}}, Options: options.Index().SetSparse(true)}},
+		{collection: "room_calendar", model: mongo.IndexModel{Keys: bson.D{{Key: "holdFor", Value: 1}}, Options: options.Index().SetSparse(true)}},
 		{
 			collection: "waitlist",
 			model: mongo.IndexModel{
@@ -78,6 +88,8 @@ func EnsureStartupMaintenance(ctx context.Context, database *mongo.Database) err
 					}),
 			},
 		},
+		{collection: "waitlist", model: mongo.IndexModel{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "isActive", Value: 1}}}},
+		{collection: "waitlist", model: mongo.IndexModel{Keys: bson.D{{Key: "isActive", Value: 1}, {Key: "checkIn", Value: 1}}}},
 		{
 			collection: "notifications",
 			model: mongo.IndexModel{
@@ -96,6 +108,26 @@ func EnsureStartupMaintenance(ctx context.Context, database *mongo.Database) err
 				},
 			},
 		},
+		{
+			collection: "notifications",
+			model: mongo.IndexModel{
+				Keys: bson.D{
+					{Key: "broadcastId", Value: 1},
+					{Key: "userId", Value: 1},
+				},
+				Options: options.Index().
+					SetUnique(true).
+					SetPartialFilterExpression(bson.M{"broadcastId": bson.M{"$exists": true}}),
+			},
+		},
+		{
+			collection: "notifications",
+			model: mongo.IndexModel{
+				Keys:    bson.D{{Key: "purgeAt", Value: 1}},
+				Options: options.Index().SetExpireAfterSeconds(0),
+			},
+		},
+		{collection: "broadcasts", model: mongo.IndexModel{Keys: bson.D{{Key: "createdAt", Value: -1}}}},
 		{
 			collection: "hotel_presence",
 			model: mongo.IndexModel{
@@ -131,6 +163,85 @@ func EnsureStartupMaintenance(ctx context.Context, database *mongo.Database) err
 				Options: options.Index().SetExpireAfterSeconds(0),
 			},
 		},
+		{
+			collection: "password_resets",
+			model: mongo.IndexModel{
+				Keys:    bson.D{{Key: "tokenHash", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			},
+		},
+		{collection: "password_resets", model: mongo.IndexModel{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "usedAt", Value: 1}}}},
+		{
+			collection: "password_resets",
+			model: mongo.IndexModel{
+				Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+				Options: options.Index().SetExpireAfterSeconds(0),
+			},
+		},
+		{
+			collection: "email_verifications",
+			model: mongo.IndexModel{
+				Keys:    bson.D{{Key: "tokenHash", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			},
+		},
+		{collection: "email_verifications", model: mongo.IndexModel{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "usedAt", Value: 1}}}},
+		{
+			collection: "email_verifications",
+			model: mongo.IndexModel{
+				Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+				Options: options.Index().SetExpireAfterSeconds(0),
+			},
+		},
+		{
+			collection: "login_attempts",
+			model: mongo.IndexModel{
+				Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+				Options: options.Index().SetExpireAfterSeconds(0),
+			},
+		},
+		{
+			collection: "api_tokens",
+			model: mongo.IndexModel{
+				Keys:    bson.D{{Key: "tokenHash", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			},
+		},
+		{collection: "api_tokens", model: mongo.IndexModel{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "createdAt", Value: -1}}}},
+		{
+			collection: "user_identities",
+			model: mongo.IndexModel{
+				Keys:    bson.D{{Key: "provider", Value: 1}, {Key: "subject", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			},
+		},
+		{collection: "user_identities", model: mongo.IndexModel{Keys: bson.D{{Key: "userId", Value: 1}}}},
+		{
+			collection: "jobs",
+			model: mongo.IndexModel{
+				Keys: bson.D{{Key: "key", Value: 1}},
+				Options: options.Index().
+					SetUnique(true).
+					SetPartialFilterExpression(bson.M{"key": bson.M{"$exists": true}}),
+			},
+		},
+		{collection: "jobs", model: mongo.IndexModel{Keys: bson.D{{Key: "status", Value: 1}, {Key: "runAt", Value: 1}}}},
+		{collection: "jobs", model: mongo.IndexModel{Keys: bson.D{{Key: "userId", Value: 1}}, Options: options.Index().SetSparse(true)}},
+		{collection: "data_exports", model: mongo.IndexModel{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "createdAt", Value: -1}}}},
+		{
+			collection: "data_exports",
+			model: mongo.IndexModel{
+				Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+				Options: options.Index().SetExpireAfterSeconds(0),
+			},
+		},
+		{
+			collection: "rate_limits",
+			model: mongo.IndexModel{
+				Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+				Options: options.Index().SetExpireAfterSeconds(0),
+			},
+		},
 	}
 
 	for _, task := range indexTasks {
@@ -226,6 +337,18 @@ func backfillBookingRoomIDs(ctx context.Context, database *mongo.Database) error
 	return nil
 }
 
+func backfillUserEmailVerification(ctx context.Context, database *mongo.Database) error {
+	_, err := database.Collection("users").UpdateMany(
+		ctx,
+		bson.M{"emailVerified": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"emailVerified": true}},
+	)
+	if err != nil {
+		return fmt.Errorf("backfill users emailVerified: %w", err)
+	}
+	return nil
+}
+
 func syncRoomCalendarFromActiveBookings(ctx context.Context, database *mongo.Database) error {
 	bookingsCollection := database.Collection("bookings")
 	roomCalendar := database.Collection("room_calendar")
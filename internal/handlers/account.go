@@ -0,0 +1,367 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"easybook/internal/mail"
+	"easybook/internal/models"
+	"easybook/internal/session"
+	"easybook/internal/utils"
+	"easybook/internal/view"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	errCurrentPasswordMismatch = fmt.Errorf("%w: Current password is incorrect.", models.ErrInvalidAccountPayload)
+	errDeleteNotConfirmed      = fmt.Errorf("%w: Type your email address to confirm the deletion.", models.ErrInvalidAccountPayload)
+)
+
+var languageNames = map[string]string{
+	"en": "English",
+	"ru": "Русский",
+	"kk": "Қазақша",
+}
+
+func (a *App) renderAccountPage(w http.ResponseWriter, r *http.Request) error {
+	user, err := a.currentAccount(r)
+	if err != nil {
+		return err
+	}
+
+	notice := ""
+	if r.URL.Query().Get("email") == "sent" {
+		notice = "Check your new inbox and open the confirmation link to finish the change."
+	}
+	return a.renderAccountTemplate(w, r, http.StatusOK, user, notice, "")
+}
+
+func (a *App) updateProfileFromPage(w http.ResponseWriter, r *http.Request) error {
+	return a.handleAccountForm(w, r, "Your profile has been saved.", func(user *models.User, payload map[string]any) error {
+		_, err := a.updateProfile(r.Context(), user, payload)
+		return err
+	})
+}
+
+func (a *App) changePasswordFromPage(w http.ResponseWriter, r *http.Request) error {
+	return a.handleAccountForm(w, r, "Your password has been changed. Other devices were signed out.", func(user *models.User, payload map[string]any) error {
+		return a.changePassword(r, user, payload)
+	})
+}
+
+func (a *App) changeEmailFromPage(w http.ResponseWriter, r *http.Request) error {
+	return a.handleAccountForm(w, r, "", func(user *models.User, payload map[string]any) error {
+		return a.requestEmailChange(r.Context(), user, payload)
+	})
+}
+
+func (a *App) deleteAccountFromPage(w http.ResponseWriter, r *http.Request) error {
+	payload, err := a.parsePayload(r)
+	if err != nil {
+		return err
+	}
+	user, err := a.currentAccount(r)
+	if err != nil {
+		return err
+	}
+
+	if err := a.deleteAccount(w, r, user, payload); err != nil {
+		if status, message, ok := accountInputError(err); ok {
+			return a.renderAccountTemplate(w, r, status, user, "", message)
+		}
+		return err
+	}
+
+	http.Redirect(w, r, "/", http.StatusFound)
+	return nil
+}
+
+// handleAccountForm runs one of the account changes for a page form and
+// re-renders the account page with its outcome.
+func (a *App) handleAccountForm(w http.ResponseWriter, r *http.Request, successNotice string, apply func(*models.User, map[string]any) error) error {
+	payload, err := a.parsePayload(r)
+	if err != nil {
+		return err
+	}
+	user, err := a.currentAccount(r)
+	if err != nil {
+		return err
+	}
+
+	if err := apply(user, payload); err != nil {
+		if status, message, ok := accountInputError(err); ok {
+			return a.renderAccountTemplate(w, r, status, user, "", message)
+		}
+		return err
+	}
+
+	if successNotice == "" {
+		http.Redirect(w, r, "/account?email=sent", http.StatusFound)
+		return nil
+	}
+	user, err = a.currentAccount(r)
+	if err != nil {
+		return err
+	}
+	return a.renderAccountTemplate(w, r, http.StatusOK, user, successNotice, "")
+}
+
+func (a *App) getAccountAPI(w http.ResponseWriter, r *http.Request) error {
+	user, err := a.currentAccount(r)
+	if err != nil {
+		return err
+	}
+	a.writeJSON(w, http.StatusOK, map[string]any{"item": accountResponse(user), "languages": utils.SupportedLanguages})
+	return nil
+}
+
+func (a *App) updateAccountAPI(w http.ResponseWriter, r *http.Request) error {
+	return a.handleAccountAPI(w, r, func(user *models.User, payload map[string]any) (any, error) {
+		updated, err := a.updateProfile(r.Context(), user, payload)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"item": accountResponse(updated)}, nil
+	})
+}
+
+func (a *App) changePasswordAPI(w http.ResponseWriter, r *http.Request) error {
+	return a.handleAccountAPI(w, r, func(user *models.User, payload map[string]any) (any, error) {
+		return map[string]string{"message": "Password changed"}, a.changePassword(r, user, payload)
+	})
+}
+
+func (a *App) changeEmailAPI(w http.ResponseWriter, r *http.Request) error {
+	return a.handleAccountAPI(w, r, func(user *models.User, payload map[string]any) (any, error) {
+		return map[string]string{"message": "Confirmation link sent to the new address"}, a.requestEmailChange(r.Context(), user, payload)
+	})
+}
+
+func (a *App) deleteAccountAPI(w http.ResponseWriter, r *http.Request) error {
+	return a.handleAccountAPI(w, r, func(user *models.User, payload map[string]any) (any, error) {
+		return map[string]string{"message": "Account deleted"}, a.deleteAccount(w, r, user, payload)
+	})
+}
+
+func (a *App) handleAccountAPI(w http.ResponseWriter, r *http.Request, apply func(*models.User, map[string]any) (any, error)) error {
+	payload, err := a.parsePayload(r)
+	if err != nil {
+		a.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid payload"})
+		return nil
+	}
+	user, err := a.currentAccount(r)
+	if err != nil {
+		return err
+	}
+
+	response, err := apply(user, payload)
+	if err != nil {
+		if status, message, ok := accountInputError(err); ok {
+			a.writeJSON(w, status, map[string]any{"error": "validation_error", "message": message})
+			return nil
+		}
+		return err
+	}
+	a.writeJSON(w, http.StatusOK, response)
+	return nil
+}
+
+func (a *App) updateProfile(ctx context.Context, user *models.User, payload map[string]any) (*models.User, error) {
+	validationErrors, profile := utils.ValidateProfilePayload(payload)
+	if len(validationErrors) > 0 {
+		return nil, fmt.Errorf("%w: %s", models.ErrInvalidAccountPayload, validationErrors[0])
+	}
+	return a.Store.UpdateUserProfile(ctx, user.ID.Hex(), profile.Name, profile.Phone, profile.PreferredLanguage)
+}
+
+// changePassword keeps the current session and signs out every other one.
+// Accounts created through single sign-on have no password yet and may set
+// one without the current-password check.
+func (a *App) changePassword(r *http.Request, user *models.User, payload map[string]any) error {
+	if err := checkCurrentPassword(user, payload); err != nil {
+		return err
+	}
+	validationErrors, password := utils.ValidateNewPasswordPayload(payload, user.Email)
+	if len(validationErrors) > 0 {
+		return fmt.Errorf("%w: %s", models.ErrInvalidAccountPayload, validationErrors[0])
+	}
+
+	if err := a.Store.UpdateUserPassword(r.Context(), user.ID.Hex(), password); err != nil {
+		return err
+	}
+	if _, err := a.Sessions.DestroyOtherUserSessions(r, user.ID.Hex()); err != nil {
+		return err
+	}
+
+	a.sendAccountMail(r.Context(), user.Email, "Your Easy Booking password was changed",
+		"The password of your Easy Booking account was just changed and your other devices were signed out.\n\nIf this was not you, reset your password: "+a.Env.AppBaseURL+"/forgot-password\n")
+	return nil
+}
+
+// requestEmailChange mails a confirmation link to the new address. The
+// account keeps its current email until the link is opened.
+func (a *App) requestEmailChange(ctx context.Context, user *models.User, payload map[string]any) error {
+	if err := checkCurrentPassword(user, payload); err != nil {
+		return err
+	}
+
+	newEmail := strings.ToLower(utils.ToTrimmedString(payload["email"]))
+	if !utils.ValidateEmail(newEmail) {
+		return fmt.Errorf("%w: Valid email is required.", models.ErrInvalidAccountPayload)
+	}
+	if newEmail == user.Email {
+		return fmt.Errorf("%w: This is already your email address.", models.ErrInvalidAccountPayload)
+	}
+	existing, err := a.Store.FindUserByEmail(ctx, newEmail)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("%w: This email address is already used by another account.", models.ErrInvalidAccountPayload)
+	}
+
+	if err := a.sendEmailChangeConfirmation(ctx, user.ID.Hex(), newEmail); err != nil {
+		return err
+	}
+	a.sendAccountMail(ctx, user.Email, "Email change requested for your Easy Booking account",
+		fmt.Sprintf("A change of your account email to %s was requested. Nothing changes until the new address is confirmed.\n\nIf this was not you, change your password: %s/account\n", newEmail, a.Env.AppBaseURL))
+	return nil
+}
+
+func (a *App) deleteAccount(w http.ResponseWriter, r *http.Request, user *models.User, payload map[string]any) error {
+	if user.PasswordHash != "" {
+		if err := checkCurrentPassword(user, payload); err != nil {
+			return err
+		}
+	} else if !strings.EqualFold(utils.ToTrimmedString(payload["confirmEmail"]), user.Email) {
+		return errDeleteNotConfirmed
+	}
+
+	result, err := a.Store.DeleteUserAccount(r.Context(), user.ID.Hex())
+	if err != nil {
+		return err
+	}
+	a.queueWaitlistProcessing(r.Context(), result.CancelledRoomIDs...)
+
+	if _, err := a.Sessions.DestroyUserSessions(r.Context(), user.ID.Hex()); err != nil {
+		return err
+	}
+	a.Sessions.DestroySession(w, r)
+
+	log.Printf("account %s deleted: %d bookings cancelled, %d anonymized", user.ID.Hex(), result.CancelledBookings, result.AnonymizedBookings)
+	a.sendAccountMail(r.Context(), user.Email, "Your Easy Booking account was deleted",
+		fmt.Sprintf("Your account has been deleted. %d upcoming booking(s) were cancelled.\n", result.CancelledBookings))
+	return nil
+}
+
+// accountResponse leaves out the password hash and two-factor state.
+func accountResponse(user *models.User) map[string]any {
+	return map[string]any{
+		"_id":               user.ID.Hex(),
+		"email":             user.Email,
+		"role":              user.Role,
+		"name":              user.Name,
+		"phone":             user.Phone,
+		"preferredLanguage": user.PreferredLanguage,
+		"emailVerified":     user.EmailVerified,
+		"hasPassword":       user.PasswordHash != "",
+		"totpEnabled":       user.TOTPEnabled,
+		"createdAt":         user.CreatedAt,
+	}
+}
+
+func checkCurrentPassword(user *models.User, payload map[string]any) error {
+	if user.PasswordHash == "" {
+		return nil
+	}
+	current := utils.ToTrimmedString(payload["currentPassword"])
+	if current == "" || bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(current)) != nil {
+		return errCurrentPasswordMismatch
+	}
+	return nil
+}
+
+func accountInputError(err error) (int, string, bool) {
+	if !errors.Is(err, models.ErrInvalidAccountPayload) {
+		return 0, "", false
+	}
+	message := strings.TrimPrefix(err.Error(), models.ErrInvalidAccountPayload.Error()+": ")
+	if errors.Is(err, errCurrentPasswordMismatch) {
+		return http.StatusUnauthorized, message, true
+	}
+	return http.StatusBadRequest, message, true
+}
+
+func (a *App) sendEmailChangeConfirmation(ctx context.Context, userID, email string) error {
+	token, err := a.Store.CreateEmailVerificationToken(ctx, userID, email, a.emailVerificationTTL())
+	if err != nil {
+		return err
+	}
+
+	return a.queueMail(ctx, "", mail.Message{
+		To:      email,
+		Subject: "Confirm your new Easy Booking email",
+		Text: fmt.Sprintf(
+			"Open this link to use this address for your Easy Booking account:\n%s\n\nThe link expires in %d hours.\n",
+			a.Env.AppBaseURL+"/verify-email?token="+url.QueryEscape(token),
+			a.Env.EmailVerificationTTLHours,
+		),
+	})
+}
+
+func (a *App) sendAccountMail(ctx context.Context, to, subject, text string) {
+	if err := a.queueMail(ctx, "", mail.Message{To: to, Subject: subject, Text: text}); err != nil {
+		log.Printf("account mail %q not queued for %s: %v", subject, to, err)
+	}
+}
+
+func (a *App) renderAccountTemplate(w http.ResponseWriter, r *http.Request, statusCode int, user *models.User, noticeMessage, errorMessage string) error {
+	currentUser := session.CurrentUser(r)
+
+	var options strings.Builder
+	for _, language := range utils.SupportedLanguages {
+		selected := ""
+		if language == user.PreferredLanguage || (user.PreferredLanguage == "" && language == utils.SupportedLanguages[0]) {
+			selected = " selected"
+		}
+		options.WriteString(fmt.Sprintf(`<option value="%s"%s>%s</option>`, language, selected, view.EscapeHTML(languageNames[language])))
+	}
+
+	emailStatus := fmt.Sprintf(`<p>Current: <strong>%s</strong> (verified)</p>`, view.EscapeHTML(user.Email))
+	if !user.EmailVerified {
+		emailStatus = fmt.Sprintf(`<p>Current: <strong>%s</strong> (not verified yet)</p>`, view.EscapeHTML(user.Email))
+	}
+
+	currentPasswordField := ""
+	deleteConfirmField := `
+          <div class="form-group">
+            <label for="confirmEmail">Type your email address to confirm</label>
+            <input id="confirmEmail" type="email" name="confirmEmail" required />
+          </div>`
+	if user.PasswordHash != "" {
+		currentPasswordField = `
+          <div class="form-group">
+            <label>Current password</label>
+            <input type="password" name="currentPassword" autocomplete="current-password" required />
+          </div>`
+		deleteConfirmField = currentPasswordField
+	}
+
+	return a.renderHTML(w, r, statusCode, "account-profile.html", map[string]any{
+		"authControls":         view.Safe(renderAuthControls(currentUser, "/account")),
+		"noticeMessage":        renderNotice("success", noticeMessage),
+		"errorMessage":         errorMessage,
+		"nameValue":            user.Name,
+		"phoneValue":           user.Phone,
+		"languageOptions":      view.Safe(options.String()),
+		"emailStatus":          view.Safe(emailStatus),
+		"currentPasswordField": view.Safe(currentPasswordField),
+		"deleteConfirmField":   view.Safe(deleteConfirmField),
+	})
+}
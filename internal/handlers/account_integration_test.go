@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"easybook/internal/config"
+	"easybook/internal/db"
+	"easybook/internal/models"
+	"easybook/internal/session"
+	"easybook/internal/view"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestDeleteAccountAnonymizesPastBookings(t *testing.T) {
+	mongoURI := strings.TrimSpace(os.Getenv("MONGO_URI"))
+	if mongoURI == "" {
+		t.Skip("MONGO_URI is not set; skipping integration test")
+	}
+
+	dbName := "easybook_account_test_" + primitive.NewObjectID().Hex()
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURI))
+	if err != nil {
+		t.Fatalf("connect mongo: %v", err)
+	}
+	defer func() {
+		_ = client.Disconnect(context.Background())
+	}()
+
+	database := client.Database(dbName)
+	defer func() {
+		_ = database.Drop(context.Background())
+	}()
+
+	if err := db.EnsureStartupMaintenance(ctx, database); err != nil {
+		t.Fatalf("ensure indexes: %v", err)
+	}
+
+	sessions, err := session.NewManager(ctx, database, false, "account-integration-secret-123")
+	if err != nil {
+		t.Fatalf("init sessions: %v", err)
+	}
+
+	store := models.NewStore(database)
+	app := NewApp(config.Env{}, store, sessions, view.NewRenderer("../../views"), "../../views")
+	server := httptest.NewServer(app.Router())
+	defer server.Close()
+
+	userIDText, err := store.CreateUser(ctx, "leaving@example.com", "Passw0rd!", "user")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	userID, _ := primitive.ObjectIDFromHex(userIDText)
+
+	roomID := primitive.NewObjectID()
+	pastID := primitive.NewObjectID()
+	futureID := primitive.NewObjectID()
+	_, err = database.Collection("bookings").InsertMany(ctx, []any{
+		bson.M{"_id": pastID, "roomId": roomID, "userId": userID, "checkIn": "2020-01-10", "checkOut": "2020-01-12", "guests": 1, "notes": "late arrival"},
+		bson.M{"_id": futureID, "roomId": roomID, "userId": userID, "checkIn": "2099-01-10", "checkOut": "2099-01-12", "guests": 1, "notes": ""},
+	})
+	if err != nil {
+		t.Fatalf("insert bookings: %v", err)
+	}
+
+	sessionCookie := createSessionCookieForTests(t, sessions, userIDText, "leaving@example.com", "user")
+	csrfToken := fetchCSRFTokenForTests(t, http.DefaultClient, server.URL, sessionCookie)
+
+	deleteAccount := func(body string) int {
+		request, _ := http.NewRequest(http.MethodDelete, server.URL+"/api/account", strings.NewReader(body))
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set(session.CSRFHeaderName, csrfToken)
+		request.AddCookie(sessionCookie)
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatalf("delete account: %v", err)
+		}
+		response.Body.Close()
+		return response.StatusCode
+	}
+
+	if status := deleteAccount(`{"currentPassword":"wrong"}`); status != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for a wrong password, got %d", status)
+	}
+	if status := deleteAccount(`{"currentPassword":"Passw0rd!"}`); status != http.StatusOK {
+		t.Fatalf("expected 200, got %d", status)
+	}
+
+	user, err := store.FindUserByID(ctx, userIDText)
+	if err != nil {
+		t.Fatalf("find user: %v", err)
+	}
+	if user != nil {
+		t.Fatal("expected the user document to be removed")
+	}
+
+	var past bson.M
+	if err := database.Collection("bookings").FindOne(ctx, bson.M{"_id": pastID}).Decode(&past); err != nil {
+		t.Fatalf("find past booking: %v", err)
+	}
+	if _, ok := past["userId"]; ok {
+		t.Fatal("expected the past booking to be detached from the user")
+	}
+	if past["notes"] != "" {
+		t.Fatalf("expected notes to be cleared, got %v", past["notes"])
+	}
+
+	count, err := database.Collection("bookings").CountDocuments(ctx, bson.M{"_id": futureID})
+	if err != nil {
+		t.Fatalf("count future booking: %v", err)
+	}
+	if count != 0 {
+		t.Fatal("expected the upcoming booking to be cancelled")
+	}
+
+	sessionCount, err := database.Collection("sessions").CountDocuments(ctx, bson.M{"userId": userIDText})
+	if err != nil {
+		t.Fatalf("count sessions: %v", err)
+	}
+	if sessionCount != 0 {
+		t.Fatalf("expected all sessions to be destroyed, got %d", sessionCount)
+	}
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"easybook/internal/antispam"
+	"easybook/internal/config"
+	"easybook/internal/ratelimit"
+	"easybook/internal/utils"
+	"easybook/internal/view"
+)
+
+const (
+	contactForm  = "contact"
+	registerForm = "register"
+
+	linkScore    = 40
+	keywordScore = 50
+)
+
+// contentFields are the visible fields people type into; only they are
+// searched for blocked words.
+var contentFields = []string{"name", "message", "city"}
+
+func newAntiSpamGuard(env config.Env) *antispam.Guard {
+	guard := antispam.NewGuard(antispam.Config{
+		Secret:      env.SessionSecret,
+		MinFillTime: time.Duration(env.AntiSpamMinFillSeconds) * time.Second,
+		Threshold:   env.AntiSpamScoreThreshold,
+		Quotas: map[string]ratelimit.Limit{
+			contactForm:  rateLimit(env.AntiSpamContactQuota),
+			registerForm: rateLimit(env.AntiSpamRegisterQuota),
+		},
+	})
+	guard.AddScorer(antispam.LinkScorer("message", env.AntiSpamMaxLinks, linkScore))
+	if len(env.AntiSpamBlockedWords) > 0 {
+		guard.AddScorer(antispam.KeywordScorer(contentFields, env.AntiSpamBlockedWords, keywordScore))
+	}
+	return guard
+}
+
+// antiSpamFields renders the honeypot and the signed render time for a form.
+// The honeypot is moved off-screen rather than hidden so bots that skip
+// hidden inputs still see it.
+func (a *App) antiSpamFields(form string) view.SafeHTML {
+	return view.Safe(fmt.Sprintf(`
+          <div aria-hidden="true" style="position:absolute; left:-10000px; width:1px; height:1px; overflow:hidden;">
+            <label for="%[1]s">Leave this field empty</label>
+            <input id="%[1]s" type="text" name="%[1]s" tabindex="-1" autocomplete="off" />
+          </div>
+          <input type="hidden" name="%[2]s" value="%[3]s" />`,
+		antispam.HoneypotField,
+		antispam.TimestampField,
+		view.EscapeHTML(a.AntiSpam.Token(form)),
+	))
+}
+
+func (a *App) screenSubmission(r *http.Request, form string, payload map[string]any) antispam.Verdict {
+	fields := make(map[string]string, len(payload))
+	for key, value := range payload {
+		fields[key] = utils.ToTrimmedString(value)
+	}
+	delete(fields, "password")
+	delete(fields, "confirmPassword")
+
+	ip := utils.ClientIP(r)
+	verdict := a.AntiSpam.Check(r.Context(), a.RateLimits, antispam.Submission{Form: form, IP: ip, Fields: fields})
+	if verdict.Blocked || verdict.Spam {
+		log.Printf("antispam %s from %s: score=%d blocked=%t reasons=%s", form, ip, verdict.Score, verdict.Blocked, strings.Join(verdict.Reasons, "; "))
+	}
+	return verdict
+}
+
+func setRetryAfter(w http.ResponseWriter, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", fmt.Sprint(int(math.Ceil(retryAfter.Seconds()))))
+}
@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"easybook/internal/models"
+	"easybook/internal/session"
+	"easybook/internal/types"
+	"easybook/internal/utils"
+	"easybook/internal/view"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const maxAPITokenLifetimeDays = 365
+
+func (a *App) authenticateAPIToken(ctx context.Context, raw string) (*types.CurrentUser, error) {
+	token, user, err := a.Store.AuthenticateAPIToken(ctx, raw)
+	if err != nil || token == nil {
+		return nil, err
+	}
+
+	role := user.Role
+	if role == "" {
+		role = "user"
+	}
+
+	return &types.CurrentUser{
+		ID:            user.ID.Hex(),
+		Email:         user.Email,
+		Role:          role,
+		EmailVerified: user.EmailVerified,
+		// Tokens can only be created from a session that already satisfied the
+		// role's two-factor requirement.
+		TwoFactorVerified: true,
+		APITokenID:        token.ID.Hex(),
+		Scopes:            token.Scopes,
+	}, nil
+}
+
+func (a *App) listAPITokensAPI(w http.ResponseWriter, r *http.Request) error {
+	user := session.CurrentUser(r)
+
+	tokens, err := a.Store.ListAPITokens(r.Context(), user.ID)
+	if err != nil {
+		return err
+	}
+
+	a.writeJSON(w, http.StatusOK, map[string]any{
+		"items":  tokens,
+		"scopes": models.APITokenScopes,
+	})
+	return nil
+}
+
+func (a *App) createAPITokenAPI(w http.ResponseWriter, r *http.Request) error {
+	payload, err := a.parsePayload(r)
+	if err != nil {
+		return err
+	}
+
+	user := session.CurrentUser(r)
+	raw, token, err := a.createAPIToken(r.Context(), user.ID, payload)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidAPITokenPayload) {
+			a.writeJSON(w, http.StatusBadRequest, map[string]string{
+				"error":   "validation_error",
+				"message": strings.TrimPrefix(err.Error(), models.ErrInvalidAPITokenPayload.Error()+": "),
+			})
+			return nil
+		}
+		return err
+	}
+
+	a.writeJSON(w, http.StatusCreated, map[string]any{
+		"token": raw,
+		"item":  token,
+	})
+	return nil
+}
+
+func (a *App) revokeAPITokenAPI(w http.ResponseWriter, r *http.Request) error {
+	user := session.CurrentUser(r)
+
+	revoked, err := a.Store.RevokeAPIToken(r.Context(), user.ID, chi.URLParam(r, "id"))
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		a.writeJSON(w, http.StatusNotFound, map[string]string{"error": "Token not found"})
+		return nil
+	}
+
+	a.writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+	return nil
+}
+
+func (a *App) createAPIToken(ctx context.Context, userID string, payload map[string]any) (string, *models.APIToken, error) {
+	var expiresAt *time.Time
+	if rawDays := utils.ToTrimmedString(payload["expires_in_days"]); rawDays != "" {
+		days, err := strconv.Atoi(rawDays)
+		if err != nil || days <= 0 || days > maxAPITokenLifetimeDays {
+			return "", nil, fmt.Errorf("%w: expires_in_days must be between 1 and %d", models.ErrInvalidAPITokenPayload, maxAPITokenLifetimeDays)
+		}
+		expiry := time.Now().UTC().Add(time.Duration(days) * 24 * time.Hour)
+		expiresAt = &expiry
+	}
+
+	return a.Store.CreateAPIToken(ctx, userID, utils.ToTrimmedString(payload["name"]), scopeList(payload["scopes"]), expiresAt)
+}
+
+func scopeList(value any) []string {
+	switch typed := value.(type) {
+	case []string:
+		return typed
+	case []any:
+		scopes := make([]string, 0, len(typed))
+		for _, item := range typed {
+			scopes = append(scopes, fmt.Sprint(item))
+		}
+		return scopes
+	case string:
+		return strings.Split(typed, ",")
+	default:
+		return nil
+	}
+}
+
+func (a *App) renderAPITokensPage(w http.ResponseWriter, r *http.Request) error {
+	return a.renderAPITokensTemplate(w, r, http.StatusOK, "", "", "")
+}
+
+func (a *App) createAPITokenFromPage(w http.ResponseWriter, r *http.Request) error {
+	payload, err := a.parsePayload(r)
+	if err != nil {
+		return err
+	}
+
+	user := session.CurrentUser(r)
+	raw, _, err := a.createAPIToken(r.Context(), user.ID, payload)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidAPITokenPayload) {
+			message := strings.TrimPrefix(err.Error(), models.ErrInvalidAPITokenPayload.Error()+": ")
+			return a.renderAPITokensTemplate(w, r, http.StatusBadRequest, "", message, "")
+		}
+		return err
+	}
+
+	return a.renderAPITokensTemplate(w, r, http.StatusCreated, "Token created.", "", raw)
+}
+
+func (a *App) revokeAPITokenFromPage(w http.ResponseWriter, r *http.Request) error {
+	user := session.CurrentUser(r)
+
+	revoked, err := a.Store.RevokeAPIToken(r.Context(), user.ID, chi.URLParam(r, "id"))
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return a.renderAPITokensTemplate(w, r, http.StatusNotFound, "", "Token not found", "")
+	}
+
+	return a.renderAPITokensTemplate(w, r, http.StatusOK, "Token revoked.", "", "")
+}
+
+func (a *App) renderAPITokensTemplate(w http.ResponseWriter, r *http.Request, statusCode int, noticeMessage, errorMessage, createdToken string) error {
+	user := session.CurrentUser(r)
+
+	tokens, err := a.Store.ListAPITokens(r.Context(), user.ID)
+	if err != nil {
+		return err
+	}
+
+	createdHTML := ""
+	if createdToken != "" {
+		createdHTML = fmt.Sprintf(`
+      <div class="notice notice-warning">
+        <p>Copy your new token now. It will not be shown again.</p>
+        <p><code>%s</code></p>
+      </div>
+    `, view.EscapeHTML(createdToken))
+	}
+
+	var list strings.Builder
+	if len(tokens) == 0 {
+		list.WriteString(`<p>You have no API tokens yet.</p>`)
+	}
+	for _, token := range tokens {
+		expires := "never expires"
+		if token.ExpiresAt != nil {
+			expires = "expires " + token.ExpiresAt.Format("2006-01-02")
+		}
+		lastUsed := "never used"
+		if token.LastUsedAt != nil {
+			lastUsed = "last used " + token.LastUsedAt.Format("2006-01-02 15:04 MST")
+		}
+		list.WriteString(fmt.Sprintf(`
+      <div class="auth-row">
+        <span><strong>%s</strong> <code>%s…</code><br />%s &middot; %s &middot; %s</span>
+        <form method="POST" action="/account/tokens/%s/revoke" style="display:inline;">
+          <button type="submit" class="btn btn-outline btn-small">Revoke</button>
+        </form>
+      </div>
+    `,
+			view.EscapeHTML(token.Name),
+			view.EscapeHTML(token.Prefix),
+			view.EscapeHTML(strings.Join(token.Scopes, ", ")),
+			expires,
+			lastUsed,
+			token.ID.Hex(),
+		))
+	}
+
+	var scopes strings.Builder
+	for _, scope := range models.APITokenScopes {
+		scopes.WriteString(fmt.Sprintf(`
+          <div class="terms-row">
+            <input id="scope-%[1]s" type="checkbox" name="scopes" value="%[1]s" />
+            <label for="scope-%[1]s">%[1]s</label>
+          </div>`, view.EscapeHTML(scope)))
+	}
+
+	formHTML := fmt.Sprintf(`
+      <h3>Create a token</h3>
+      <form method="POST" action="/account/tokens" class="contact-form">
+        <div class="form-group">
+          <label for="tokenName">Name</label>
+          <input id="tokenName" type="text" name="name" maxlength="60" required />
+        </div>
+        %s
+        <div class="form-group">
+          <label for="tokenExpiry">Expires in days (leave empty for no expiry)</label>
+          <input id="tokenExpiry" type="number" name="expires_in_days" min="1" max="%d" />
+        </div>
+        <button type="submit" class="btn">Create token</button>
+      </form>
+    `, scopes.String(), maxAPITokenLifetimeDays)
+
+	return a.renderHTML(w, r, statusCode, "account-tokens.html", map[string]any{
+		"authControls":      view.Safe(renderAuthControls(user, "/account/tokens")),
+		"noticeMessage":     renderNotice("success", noticeMessage),
+		"errorMessage":      errorMessage,
+		"createdTokenBlock": view.Safe(createdHTML),
+		"tokensBlock":       view.Safe(list.String()),
+		"createFormBlock":   view.Safe(formHTML),
+	})
+}
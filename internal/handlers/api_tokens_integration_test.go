@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"easybook/internal/config"
+	"easybook/internal/db"
+	"easybook/internal/models"
+	"easybook/internal/session"
+	"easybook/internal/view"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestAPITokenBearerAuth(t *testing.T) {
+	mongoURI := strings.TrimSpace(os.Getenv("MONGO_URI"))
+	if mongoURI == "" {
+		t.Skip("MONGO_URI is not set; skipping integration test")
+	}
+
+	dbName := "easybook_api_tokens_test_" + primitive.NewObjectID().Hex()
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURI))
+	if err != nil {
+		t.Fatalf("connect mongo: %v", err)
+	}
+	defer func() {
+		_ = client.Disconnect(context.Background())
+	}()
+
+	database := client.Database(dbName)
+	defer func() {
+		_ = database.Drop(context.Background())
+	}()
+
+	if err := db.EnsureStartupMaintenance(ctx, database); err != nil {
+		t.Fatalf("ensure indexes: %v", err)
+	}
+
+	sessions, err := session.NewManager(ctx, database, false, "api-tokens-integration-secret-123")
+	if err != nil {
+		t.Fatalf("init sessions: %v", err)
+	}
+
+	store := models.NewStore(database)
+	app := NewApp(config.Env{}, store, sessions, view.NewRenderer("../../views"), "../../views")
+	server := httptest.NewServer(app.Router())
+	defer server.Close()
+
+	userID, err := store.CreateUser(ctx, "tokens@example.com", "Passw0rd!", "user")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	raw, token, err := store.CreateAPIToken(ctx, userID, "CI", []string{models.ScopeBookingsRead}, nil)
+	if err != nil {
+		t.Fatalf("create token: %v", err)
+	}
+
+	call := func(method, path string) int {
+		request, _ := http.NewRequest(method, server.URL+path, strings.NewReader("{}"))
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set("Authorization", "Bearer "+raw)
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		response.Body.Close()
+		return response.StatusCode
+	}
+
+	if status := call(http.MethodGet, "/api/bookings"); status != http.StatusOK {
+		t.Fatalf("expected bookings:read token to list bookings, got %d", status)
+	}
+	if status := call(http.MethodPost, "/api/bookings"); status != http.StatusForbidden {
+		t.Fatalf("expected missing bookings:write scope to be rejected, got %d", status)
+	}
+	if status := call(http.MethodGet, "/api/auth/sessions"); status != http.StatusForbidden {
+		t.Fatalf("expected token to be refused on session management, got %d", status)
+	}
+
+	if revoked, err := store.RevokeAPIToken(ctx, userID, token.ID.Hex()); err != nil || !revoked {
+		t.Fatalf("revoke token: revoked=%v err=%v", revoked, err)
+	}
+	if status := call(http.MethodGet, "/api/bookings"); status != http.StatusUnauthorized {
+		t.Fatalf("expected revoked token to be rejected, got %d", status)
+	}
+}
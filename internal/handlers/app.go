@@ -10,9 +10,18 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"easybook/internal/antispam"
 	"easybook/internal/config"
+	"easybook/internal/jobs"
+	"easybook/internal/mail"
+	"easybook/internal/middleware"
 	"easybook/internal/models"
+	"easybook/internal/notify"
+	"easybook/internal/oidc"
+	"easybook/internal/ratelimit"
+	"easybook/internal/scheduler"
 	"easybook/internal/session"
 	"easybook/internal/view"
 )
@@ -22,17 +31,64 @@ type App struct {
 	Store    *models.Store
 	Sessions *session.Manager
 	Renderer *view.Renderer
+	Mailer   mail.Sender
 	ViewsDir string
+
+	SSOProviders []*oidc.Provider
+	// RateLimits holds the request buckets; cmd/server swaps in the Mongo
+	// store when RATE_LIMIT_BACKEND=mongo.
+	RateLimits ratelimit.Store
+	AntiSpam   *antispam.Guard
+	// Notifications wakes open notification streams; see WatchNotifications.
+	Notifications *notify.Hub
+	// Jobs runs side effects such as emails and waitlist processing in the
+	// background; cmd/server starts its workers.
+	Jobs *jobs.Queue
+	// Dispatcher sends notifications on channels other than the in-app
+	// list through Jobs.
+	Dispatcher *notify.Dispatcher
+	// Scheduler runs periodic jobs such as booking reminders; cmd/server
+	// starts it.
+	Scheduler *scheduler.Scheduler
 }
 
 func NewApp(env config.Env, store *models.Store, sessions *session.Manager, renderer *view.Renderer, viewsDir string) *App {
-	return &App{
+	app := &App{
 		Env:      env,
 		Store:    store,
 		Sessions: sessions,
 		Renderer: renderer,
+		Mailer:   mail.NewSender(env),
 		ViewsDir: viewsDir,
+
+		SSOProviders: newSSOProviders(env.OIDCProviders),
+		RateLimits:   ratelimit.NewMemoryStore(),
+		AntiSpam:     newAntiSpamGuard(env),
+
+		Notifications: notify.NewHub(),
+		Jobs: jobs.NewQueue(store, jobs.Config{
+			Workers:      env.JobWorkers,
+			MaxAttempts:  env.JobMaxAttempts,
+			RetryDelay:   time.Duration(env.JobRetrySeconds) * time.Second,
+			PollInterval: time.Duration(env.JobPollSeconds) * time.Second,
+		}),
 	}
+	app.Dispatcher = notify.NewDispatcher(store, app.Jobs)
+	app.Dispatcher.AddChannel(&notify.EmailChannel{
+		Store:    store,
+		Sender:   app.Mailer,
+		Renderer: renderer,
+		BaseURL:  env.AppBaseURL,
+		Types:    env.NotificationEmailTypes,
+	})
+	app.registerJobs()
+	app.Scheduler = app.newScheduler()
+	store.SetWaitlistHold(time.Duration(env.WaitlistHoldMinutes) * time.Minute)
+	store.SetNotificationRetention(time.Duration(env.NotificationRetentionDays) * 24 * time.Hour)
+	store.SetNotificationListener(app.Notifications.Publish)
+	store.SetNotificationCreatedHook(app.Dispatcher.Enqueue)
+	sessions.SetBearerAuthenticator(app.authenticateAPIToken)
+	return app
 }
 
 func (a *App) withError(handler func(http.ResponseWriter, *http.Request) error) http.HandlerFunc {
@@ -52,7 +108,13 @@ func (a *App) handleError(w http.ResponseWriter, r *http.Request, err error) {
 	http.Error(w, "Internal server error", http.StatusInternalServerError)
 }
 
-func (a *App) renderHTML(w http.ResponseWriter, status int, fileName string, replacements map[string]any) error {
+func (a *App) renderHTML(w http.ResponseWriter, r *http.Request, status int, fileName string, replacements map[string]any) error {
+	if replacements == nil {
+		replacements = map[string]any{}
+	}
+	replacements[view.CSRFTokenKey] = session.CSRFToken(r)
+	replacements[view.CSPNonceKey] = middleware.CSPNonce(r)
+
 	html, err := a.Renderer.Render(fileName, replacements)
 	if err != nil {
 		return err
@@ -1,11 +1,18 @@
 package handlers
 
 import (
+	"context"
+	"fmt"
+	"log"
+	"math"
 	"net/http"
 	"strings"
+	"time"
 
+	"easybook/internal/mail"
 	"easybook/internal/models"
 	"easybook/internal/session"
+	"easybook/internal/types"
 	"easybook/internal/utils"
 
 	"golang.org/x/crypto/bcrypt"
@@ -18,10 +25,17 @@ func (a *App) renderLoginPage(w http.ResponseWriter, r *http.Request) error {
 		return nil
 	}
 
-	return a.renderHTML(w, http.StatusOK, "login.html", map[string]any{
-		"next":         nextPath,
-		"errorMessage": "",
-		"emailValue":   "",
+	noticeMessage := ""
+	if r.URL.Query().Get("reset") == "1" {
+		noticeMessage = "Your password has been updated. Please sign in with the new password."
+	}
+
+	return a.renderHTML(w, r, http.StatusOK, "login.html", map[string]any{
+		"next":          nextPath,
+		"errorMessage":  "",
+		"noticeMessage": renderNotice("success", noticeMessage),
+		"emailValue":    "",
+		"ssoButtons":    a.renderSSOButtons(),
 	})
 }
 
@@ -34,12 +48,16 @@ func (a *App) login(w http.ResponseWriter, r *http.Request) error {
 	email := strings.ToLower(utils.ToTrimmedString(payload["email"]))
 	password := utils.ToTrimmedString(payload["password"])
 	nextPath := getSafeRedirectPath(utils.ToTrimmedString(payload["next"]), "/hotels")
+	rememberRaw := utils.ToTrimmedString(payload["remember"])
+	rememberMe := rememberRaw == "on" || rememberRaw == "true" || payload["remember"] == true
 
 	sendInvalidCredentials := func() error {
-		return a.renderHTML(w, http.StatusUnauthorized, "login.html", map[string]any{
-			"next":         nextPath,
-			"errorMessage": "Invalid credentials",
-			"emailValue":   email,
+		return a.renderHTML(w, r, http.StatusUnauthorized, "login.html", map[string]any{
+			"next":          nextPath,
+			"errorMessage":  "Invalid credentials",
+			"noticeMessage": renderNotice("success", ""),
+			"emailValue":    email,
+			"ssoButtons":    a.renderSSOButtons(),
 		})
 	}
 
@@ -47,19 +65,65 @@ func (a *App) login(w http.ResponseWriter, r *http.Request) error {
 		return sendInvalidCredentials()
 	}
 
+	accountKey := models.LoginAccountKey(email)
+	clientKey := models.LoginClientKey(utils.ClientIP(r))
+	throttle, err := a.Store.CheckLoginThrottle(r.Context(), accountKey, clientKey)
+	if err != nil {
+		return err
+	}
+	if throttle.Blocked() {
+		return a.renderHTML(w, r, http.StatusTooManyRequests, "login.html", map[string]any{
+			"next":          nextPath,
+			"errorMessage":  loginThrottleMessage(throttle),
+			"noticeMessage": renderNotice("success", ""),
+			"emailValue":    email,
+			"ssoButtons":    a.renderSSOButtons(),
+		})
+	}
+
 	user, err := a.Store.FindUserByEmail(r.Context(), email)
 	if err != nil {
 		return err
 	}
-	if user == nil || user.PasswordHash == "" {
+	if user == nil || user.PasswordHash == "" || bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		if err := a.recordFailedLogin(r.Context(), user, accountKey, clientKey); err != nil {
+			return err
+		}
 		return sendInvalidCredentials()
 	}
 
-	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
-		return sendInvalidCredentials()
+	if !user.TOTPEnabled {
+		if err := a.Store.ClearLoginFailures(r.Context(), accountKey); err != nil {
+			return err
+		}
 	}
 
-	if err := a.Sessions.StartSession(w, r, user.ID.Hex(), user.Email, user.Role); err != nil {
+	return a.completeLogin(w, r, user, nextPath, rememberMe)
+}
+
+// completeLogin finishes a sign-in whose first factor (password or single
+// sign-on) succeeded: it either asks for the second factor or starts the
+// session right away.
+func (a *App) completeLogin(w http.ResponseWriter, r *http.Request, user *models.User, nextPath string, rememberMe bool) error {
+	if user.TOTPEnabled {
+		if err := a.Sessions.StartLoginChallenge(w, r, session.LoginChallenge{
+			UserID:     user.ID.Hex(),
+			Email:      user.Email,
+			Next:       nextPath,
+			RememberMe: rememberMe,
+		}); err != nil {
+			return err
+		}
+		http.Redirect(w, r, "/login/2fa", http.StatusFound)
+		return nil
+	}
+
+	if err := a.Sessions.StartSession(w, r, types.CurrentUser{
+		ID:            user.ID.Hex(),
+		Email:         user.Email,
+		Role:          user.Role,
+		EmailVerified: user.EmailVerified,
+	}, rememberMe); err != nil {
 		return err
 	}
 
@@ -67,6 +131,59 @@ func (a *App) login(w http.ResponseWriter, r *http.Request) error {
 	return nil
 }
 
+func (a *App) recordFailedLogin(ctx context.Context, user *models.User, accountKey, clientKey string) error {
+	window := time.Duration(a.Env.LoginFailureWindowMinutes) * time.Minute
+	lockout := time.Duration(a.Env.LoginLockoutMinutes) * time.Minute
+
+	if _, err := a.Store.RecordLoginFailure(ctx, clientKey, models.LoginThrottlePolicy{
+		MaxFailures: a.Env.LoginIPMaxFailures,
+		Window:      window,
+		Lockout:     lockout,
+	}); err != nil {
+		return err
+	}
+
+	result, err := a.Store.RecordLoginFailure(ctx, accountKey, models.LoginThrottlePolicy{
+		MaxFailures: a.Env.LoginMaxFailures,
+		Window:      window,
+		Lockout:     lockout,
+	})
+	if err != nil {
+		return err
+	}
+	if !result.LockedNow || user == nil {
+		return nil
+	}
+
+	text := fmt.Sprintf(
+		"Sign-in to your account was locked until %s after %d failed attempts. If this was not you, consider resetting your password.",
+		result.LockedUntil.Format("2006-01-02 15:04 MST"),
+		a.Env.LoginMaxFailures,
+	)
+	if _, err := a.Store.CreateNotification(ctx, user.ID.Hex(), models.NotificationAccountLocked, "Account temporarily locked", text, "/forgot-password"); err != nil {
+		log.Printf("lockout notification failed for %s: %v", user.Email, err)
+	}
+	lockoutKey := fmt.Sprintf("account-locked:%s:%d", user.ID.Hex(), result.LockedUntil.Unix())
+	if err := a.queueMail(ctx, lockoutKey, mail.Message{
+		To:      user.Email,
+		Subject: "Your Easy Booking account was temporarily locked",
+		Text:    text + "\n\nReset your password: " + a.Env.AppBaseURL + "/forgot-password\n",
+	}); err != nil {
+		log.Printf("lockout mail not queued for %s: %v", user.Email, err)
+	}
+
+	return nil
+}
+
+func loginThrottleMessage(status models.LoginThrottleStatus) string {
+	if status.Locked {
+		minutes := int(math.Ceil(status.RetryAfter.Minutes()))
+		return fmt.Sprintf("Too many failed sign-in attempts. Try again in %d minute(s).", minutes)
+	}
+	seconds := int(math.Ceil(status.RetryAfter.Seconds()))
+	return fmt.Sprintf("Please wait %d second(s) before trying again.", seconds)
+}
+
 func (a *App) renderRegisterPage(w http.ResponseWriter, r *http.Request) error {
 	nextPath := getSafeRedirectPath(r.URL.Query().Get("next"), "/bookings")
 	if session.CurrentUser(r) != nil {
@@ -74,10 +191,11 @@ func (a *App) renderRegisterPage(w http.ResponseWriter, r *http.Request) error {
 		return nil
 	}
 
-	return a.renderHTML(w, http.StatusOK, "register.html", map[string]any{
-		"next":         nextPath,
-		"errorMessage": "",
-		"emailValue":   "",
+	return a.renderHTML(w, r, http.StatusOK, "register.html", map[string]any{
+		"next":           nextPath,
+		"antiSpamFields": a.antiSpamFields(registerForm),
+		"errorMessage":   "",
+		"emailValue":     "",
 	})
 }
 
@@ -92,10 +210,26 @@ func (a *App) register(w http.ResponseWriter, r *http.Request) error {
 	emailValue := strings.ToLower(utils.ToTrimmedString(payload["email"]))
 
 	if len(errors) > 0 {
-		return a.renderHTML(w, http.StatusBadRequest, "register.html", map[string]any{
-			"next":         nextPath,
-			"errorMessage": errors[0],
-			"emailValue":   emailValue,
+		return a.renderHTML(w, r, http.StatusBadRequest, "register.html", map[string]any{
+			"next":           nextPath,
+			"antiSpamFields": a.antiSpamFields(registerForm),
+			"errorMessage":   errors[0],
+			"emailValue":     emailValue,
+		})
+	}
+
+	verdict := a.screenSubmission(r, registerForm, payload)
+	if verdict.Blocked || verdict.Spam {
+		status, message := http.StatusBadRequest, "We could not create your account. Please reload the page and try again."
+		if verdict.Blocked {
+			setRetryAfter(w, verdict.RetryAfter)
+			status, message = http.StatusTooManyRequests, "Too many accounts were created from your network. Please try again later."
+		}
+		return a.renderHTML(w, r, status, "register.html", map[string]any{
+			"next":           nextPath,
+			"antiSpamFields": a.antiSpamFields(registerForm),
+			"errorMessage":   message,
+			"emailValue":     emailValue,
 		})
 	}
 
@@ -104,31 +238,42 @@ func (a *App) register(w http.ResponseWriter, r *http.Request) error {
 		return err
 	}
 	if existingUser != nil {
-		return a.renderHTML(w, http.StatusConflict, "register.html", map[string]any{
-			"next":         nextPath,
-			"errorMessage": "Email is already used",
-			"emailValue":   emailValue,
+		return a.renderHTML(w, r, http.StatusConflict, "register.html", map[string]any{
+			"next":           nextPath,
+			"antiSpamFields": a.antiSpamFields(registerForm),
+			"errorMessage":   "Email is already used",
+			"emailValue":     emailValue,
 		})
 	}
 
 	insertedID, err := a.Store.CreateUser(r.Context(), userPayload.Email, userPayload.Password, "user")
 	if err != nil {
 		if models.IsDuplicateKeyError(err, "email") {
-			return a.renderHTML(w, http.StatusConflict, "register.html", map[string]any{
-				"next":         nextPath,
-				"errorMessage": "Email is already used",
-				"emailValue":   emailValue,
+			return a.renderHTML(w, r, http.StatusConflict, "register.html", map[string]any{
+				"next":           nextPath,
+				"antiSpamFields": a.antiSpamFields(registerForm),
+				"errorMessage":   "Email is already used",
+				"emailValue":     emailValue,
 			})
 		}
 
-		return a.renderHTML(w, http.StatusInternalServerError, "register.html", map[string]any{
-			"next":         nextPath,
-			"errorMessage": "Registration is temporarily unavailable. Please try again.",
-			"emailValue":   emailValue,
+		return a.renderHTML(w, r, http.StatusInternalServerError, "register.html", map[string]any{
+			"next":           nextPath,
+			"antiSpamFields": a.antiSpamFields(registerForm),
+			"errorMessage":   "Registration is temporarily unavailable. Please try again.",
+			"emailValue":     emailValue,
 		})
 	}
 
-	if err := a.Sessions.StartSession(w, r, insertedID, userPayload.Email, "user"); err != nil {
+	if err := a.sendVerificationEmail(r.Context(), insertedID, userPayload.Email); err != nil {
+		log.Printf("verification mail failed for %s: %v", userPayload.Email, err)
+	}
+
+	if err := a.Sessions.StartSession(w, r, types.CurrentUser{
+		ID:    insertedID,
+		Email: userPayload.Email,
+		Role:  "user",
+	}, false); err != nil {
 		return err
 	}
 
@@ -151,16 +296,21 @@ func (a *App) logout(w http.ResponseWriter, r *http.Request) error {
 func (a *App) getSessionStatusAPI(w http.ResponseWriter, r *http.Request) error {
 	user := session.CurrentUser(r)
 	if user == nil {
-		a.writeJSON(w, http.StatusUnauthorized, map[string]any{"authenticated": false})
+		a.writeJSON(w, http.StatusUnauthorized, map[string]any{
+			"authenticated": false,
+			"csrfToken":     session.CSRFToken(r),
+		})
 		return nil
 	}
 
 	a.writeJSON(w, http.StatusOK, map[string]any{
 		"authenticated": true,
+		"csrfToken":     session.CSRFToken(r),
 		"user": map[string]any{
-			"id":    user.ID,
-			"email": user.Email,
-			"role":  user.Role,
+			"id":            user.ID,
+			"email":         user.Email,
+			"role":          user.Role,
+			"emailVerified": user.EmailVerified,
 		},
 	})
 	return nil
@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
 	"net/http"
 	"strconv"
 	"strings"
@@ -82,8 +81,8 @@ func (a *App) renderBookingsPage(w http.ResponseWriter, r *http.Request) error {
 			if canManage {
 				actions = append(actions, fmt.Sprintf(`<a class="btn btn-outline" href="/bookings/%s/edit">Edit</a>`, bookingID))
 				actions = append(actions, fmt.Sprintf(`
-          <form method="POST" action="/bookings/%s/delete" style="display:inline;">
-            <button class="btn btn-outline" type="submit" onclick="return confirm('Delete this booking?')">Delete</button>
+          <form method="POST" action="/bookings/%s/delete" style="display:inline;" data-confirm="Delete this booking?">
+            <button class="btn btn-outline" type="submit">Delete</button>
           </form>
         `, bookingID))
 			}
@@ -132,7 +131,7 @@ func (a *App) renderBookingsPage(w http.ResponseWriter, r *http.Request) error {
 		roleNote = `<span class="chip">Extended access is enabled for this account.</span>`
 	}
 
-	return a.renderHTML(w, http.StatusOK, "bookings.html", map[string]any{
+	return a.renderHTML(w, r, http.StatusOK, "bookings.html", map[string]any{
 		"authControls":  view.Safe(renderAuthControls(user, "/bookings")),
 		"roleNote":      view.Safe(roleNote),
 		"scopeOptions":  view.Safe(scopeOptions),
@@ -148,7 +147,7 @@ func (a *App) renderNewBookingPage(w http.ResponseWriter, r *http.Request) error
 		return err
 	}
 
-	return a.renderHTML(w, http.StatusOK, "bookings-new.html", map[string]any{
+	return a.renderHTML(w, r, http.StatusOK, "bookings-new.html", map[string]any{
 		"authControls": view.Safe(renderAuthControls(session.CurrentUser(r), "/bookings/new")),
 		"errorMessage": "",
 		"hotelOptions": view.Safe(hotelOptions),
@@ -179,7 +178,7 @@ func (a *App) createBookingFromPage(w http.ResponseWriter, r *http.Request) erro
 	}
 
 	if len(validationErrors) > 0 {
-		return a.renderHTML(w, http.StatusBadRequest, "bookings-new.html", map[string]any{
+		return a.renderHTML(w, r, http.StatusBadRequest, "bookings-new.html", map[string]any{
 			"authControls": view.Safe(renderAuthControls(session.CurrentUser(r), "/bookings/new")),
 			"errorMessage": validationErrors[0],
 			"hotelOptions": view.Safe(hotelOptions),
@@ -197,7 +196,7 @@ func (a *App) createBookingFromPage(w http.ResponseWriter, r *http.Request) erro
 		return err
 	}
 	if hotel == nil {
-		return a.renderHTML(w, http.StatusBadRequest, "bookings-new.html", map[string]any{
+		return a.renderHTML(w, r, http.StatusBadRequest, "bookings-new.html", map[string]any{
 			"authControls": view.Safe(renderAuthControls(session.CurrentUser(r), "/bookings/new")),
 			"errorMessage": "Selected room does not exist",
 			"hotelOptions": view.Safe(hotelOptions),
@@ -213,7 +212,7 @@ func (a *App) createBookingFromPage(w http.ResponseWriter, r *http.Request) erro
 	insertedID, err := a.Store.CreateBooking(r.Context(), booking, user.ID)
 	if err != nil {
 		if errors.Is(err, models.ErrBookingConflict) {
-			return a.renderHTML(w, http.StatusConflict, "bookings-new.html", map[string]any{
+			return a.renderHTML(w, r, http.StatusConflict, "bookings-new.html", map[string]any{
 				"authControls": view.Safe(renderAuthControls(session.CurrentUser(r), "/bookings/new")),
 				"errorMessage": "Selected room is occupied for these dates. Choose different dates or subscribe for notifications.",
 				"hotelOptions": view.Safe(hotelOptions),
@@ -225,7 +224,7 @@ func (a *App) createBookingFromPage(w http.ResponseWriter, r *http.Request) erro
 			})
 		}
 		if errors.Is(err, models.ErrInvalidBookingPayload) {
-			return a.renderHTML(w, http.StatusBadRequest, "bookings-new.html", map[string]any{
+			return a.renderHTML(w, r, http.StatusBadRequest, "bookings-new.html", map[string]any{
 				"authControls": view.Safe(renderAuthControls(session.CurrentUser(r), "/bookings/new")),
 				"errorMessage": "Invalid booking data. Check dates and room.",
 				"hotelOptions": view.Safe(hotelOptions),
@@ -265,12 +264,12 @@ func (a *App) renderBookingDetailsPage(w http.ResponseWriter, r *http.Request) e
 	bookingID := objectIDHex(booking["_id"])
 	actionButtons := fmt.Sprintf(`
     <a class="btn btn-outline" href="/bookings/%s/edit">Edit</a>
-    <form method="POST" action="/bookings/%s/delete" style="display:inline;">
-      <button class="btn btn-outline" type="submit" onclick="return confirm('Delete this booking?')">Delete</button>
+    <form method="POST" action="/bookings/%s/delete" style="display:inline;" data-confirm="Delete this booking?">
+      <button class="btn btn-outline" type="submit">Delete</button>
     </form>
   `, bookingID, bookingID)
 
-	return a.renderHTML(w, http.StatusOK, "bookings-item.html", map[string]any{
+	return a.renderHTML(w, r, http.StatusOK, "bookings-item.html", map[string]any{
 		"authControls":  view.Safe(renderAuthControls(session.CurrentUser(r), "/bookings/"+bookingID)),
 		"id":            bookingID,
 		"hotelTitle":    defaultIfEmpty(stringValue(booking, "hotelTitle"), "Unknown hotel"),
@@ -309,7 +308,7 @@ func (a *App) renderEditBookingPage(w http.ResponseWriter, r *http.Request) erro
 	}
 
 	bookingID := objectIDHex(booking["_id"])
-	return a.renderHTML(w, http.StatusOK, "bookings-edit.html", map[string]any{
+	return a.renderHTML(w, r, http.StatusOK, "bookings-edit.html", map[string]any{
 		"authControls": view.Safe(renderAuthControls(session.CurrentUser(r), "/bookings/"+bookingID+"/edit")),
 		"id":           bookingID,
 		"errorMessage": "",
@@ -360,7 +359,7 @@ func (a *App) updateBookingFromPage(w http.ResponseWriter, r *http.Request) erro
 	}
 
 	if len(validationErrors) > 0 {
-		return a.renderHTML(w, http.StatusBadRequest, "bookings-edit.html", map[string]any{
+		return a.renderHTML(w, r, http.StatusBadRequest, "bookings-edit.html", map[string]any{
 			"authControls": view.Safe(renderAuthControls(session.CurrentUser(r), "/bookings/"+id+"/edit")),
 			"id":           id,
 			"errorMessage": validationErrors[0],
@@ -379,7 +378,7 @@ func (a *App) updateBookingFromPage(w http.ResponseWriter, r *http.Request) erro
 		return err
 	}
 	if hotel == nil {
-		return a.renderHTML(w, http.StatusBadRequest, "bookings-edit.html", map[string]any{
+		return a.renderHTML(w, r, http.StatusBadRequest, "bookings-edit.html", map[string]any{
 			"authControls": view.Safe(renderAuthControls(session.CurrentUser(r), "/bookings/"+id+"/edit")),
 			"id":           id,
 			"errorMessage": "Selected room does not exist",
@@ -395,7 +394,7 @@ func (a *App) updateBookingFromPage(w http.ResponseWriter, r *http.Request) erro
 	matched, err := a.Store.UpdateBookingByID(r.Context(), id, booking)
 	if err != nil {
 		if errors.Is(err, models.ErrBookingConflict) {
-			return a.renderHTML(w, http.StatusConflict, "bookings-edit.html", map[string]any{
+			return a.renderHTML(w, r, http.StatusConflict, "bookings-edit.html", map[string]any{
 				"authControls": view.Safe(renderAuthControls(session.CurrentUser(r), "/bookings/"+id+"/edit")),
 				"id":           id,
 				"errorMessage": "Selected room is occupied for these dates. Choose different dates or subscribe for notifications.",
@@ -408,7 +407,7 @@ func (a *App) updateBookingFromPage(w http.ResponseWriter, r *http.Request) erro
 			})
 		}
 		if errors.Is(err, models.ErrInvalidBookingPayload) {
-			return a.renderHTML(w, http.StatusBadRequest, "bookings-edit.html", map[string]any{
+			return a.renderHTML(w, r, http.StatusBadRequest, "bookings-edit.html", map[string]any{
 				"authControls": view.Safe(renderAuthControls(session.CurrentUser(r), "/bookings/"+id+"/edit")),
 				"id":           id,
 				"errorMessage": "Invalid booking data. Check dates and room.",
@@ -426,7 +425,7 @@ func (a *App) updateBookingFromPage(w http.ResponseWriter, r *http.Request) erro
 		return sendBookingNotFoundPage(a, w, r, http.StatusNotFound)
 	}
 
-	a.triggerWaitlistProcessing(r.Context(), roomIDFromBookingData(existing), roomIDHex)
+	a.queueWaitlistProcessing(r.Context(), roomIDFromBookingData(existing), roomIDHex)
 
 	http.Redirect(w, r, "/bookings/"+id, http.StatusFound)
 	return nil
@@ -458,7 +457,7 @@ func (a *App) deleteBookingFromPage(w http.ResponseWriter, r *http.Request) erro
 		return sendBookingNotFoundPage(a, w, r, http.StatusNotFound)
 	}
 
-	a.triggerWaitlistProcessing(r.Context(), roomIDFromBookingData(existing))
+	a.queueWaitlistProcessing(r.Context(), roomIDFromBookingData(existing))
 
 	http.Redirect(w, r, "/bookings", http.StatusFound)
 	return nil
@@ -626,7 +625,7 @@ func (a *App) updateBookingAPI(w http.ResponseWriter, r *http.Request) error {
 	if updatedRoomID == "" {
 		updatedRoomID = roomIDFromBookingData(existing)
 	}
-	a.triggerWaitlistProcessing(r.Context(), roomIDFromBookingData(existing), updatedRoomID)
+	a.queueWaitlistProcessing(r.Context(), roomIDFromBookingData(existing), updatedRoomID)
 
 	a.writeJSON(w, http.StatusOK, map[string]string{"message": "Updated"})
 	return nil
@@ -661,7 +660,7 @@ func (a *App) deleteBookingAPI(w http.ResponseWriter, r *http.Request) error {
 		return nil
 	}
 
-	a.triggerWaitlistProcessing(r.Context(), roomIDFromBookingData(existing))
+	a.queueWaitlistProcessing(r.Context(), roomIDFromBookingData(existing))
 
 	a.writeJSON(w, http.StatusOK, map[string]string{"message": "Deleted"})
 	return nil
@@ -678,7 +677,8 @@ func (a *App) getBookingAvailabilityAPI(w http.ResponseWriter, r *http.Request)
 	checkOut := firstNonEmpty(strings.TrimSpace(query.Get("check_out")), strings.TrimSpace(query.Get("checkOut")))
 	excludeBookingID := firstNonEmpty(strings.TrimSpace(query.Get("exclude_booking_id")), strings.TrimSpace(query.Get("excludeBookingId")))
 
-	available, err := a.Store.CheckRoomAvailability(r.Context(), roomID, checkIn, checkOut, excludeBookingID)
+	user := session.CurrentUser(r)
+	available, err := a.Store.CheckRoomAvailability(r.Context(), roomID, checkIn, checkOut, excludeBookingID, user.ID)
 	if err != nil {
 		if errors.Is(err, models.ErrInvalidBookingPayload) {
 			a.writeJSON(w, http.StatusBadRequest, map[string]string{
@@ -705,24 +705,6 @@ func (a *App) getBookingAvailabilityAPI(w http.ResponseWriter, r *http.Request)
 	return nil
 }
 
-func (a *App) triggerWaitlistProcessing(ctx context.Context, roomIDs ...string) {
-	seen := map[string]struct{}{}
-	for _, roomID := range roomIDs {
-		roomID = strings.TrimSpace(roomID)
-		if roomID == "" {
-			continue
-		}
-		if _, exists := seen[roomID]; exists {
-			continue
-		}
-		seen[roomID] = struct{}{}
-
-		if _, err := a.Store.ProcessWaitlistForRoom(ctx, roomID); err != nil {
-			log.Printf("waitlist processing failed for room %s: %v", roomID, err)
-		}
-	}
-}
-
 func roomIDFromBookingData(booking map[string]any) string {
 	if booking == nil {
 		return ""
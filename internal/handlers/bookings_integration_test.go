@@ -17,6 +17,7 @@ import (
 	"easybook/internal/db"
 	"easybook/internal/models"
 	"easybook/internal/session"
+	"easybook/internal/types"
 	"easybook/internal/view"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -85,6 +86,7 @@ func TestCreateBookingAPIConcurrentConflict(t *testing.T) {
 	defer server.Close()
 
 	sessionCookie := createSessionCookieForTests(t, sessions, userID.Hex(), "race@example.com", "user")
+	csrfToken := fetchCSRFTokenForTests(t, http.DefaultClient, server.URL, sessionCookie)
 
 	payload := map[string]any{
 		"room_id":   roomID.Hex(),
@@ -125,6 +127,7 @@ func TestCreateBookingAPIConcurrentConflict(t *testing.T) {
 				return
 			}
 			request.Header.Set("Content-Type", "application/json")
+			request.Header.Set(session.CSRFHeaderName, csrfToken)
 			request.AddCookie(sessionCookie)
 
 			response, responseErr := http.DefaultClient.Do(request)
@@ -203,7 +206,12 @@ func createSessionCookieForTests(t *testing.T, sessions *session.Manager, userID
 
 	request := httptest.NewRequest(http.MethodGet, "/", nil)
 	recorder := httptest.NewRecorder()
-	if err := sessions.StartSession(recorder, request, userID, email, role); err != nil {
+	if err := sessions.StartSession(recorder, request, types.CurrentUser{
+		ID:            userID,
+		Email:         email,
+		Role:          role,
+		EmailVerified: true,
+	}, false); err != nil {
 		t.Fatalf("start session: %v", err)
 	}
 
@@ -219,6 +227,32 @@ func createSessionCookieForTests(t *testing.T, sessions *session.Manager, userID
 	return nil
 }
 
+func fetchCSRFTokenForTests(t *testing.T, client *http.Client, serverURL string, cookies ...*http.Cookie) string {
+	t.Helper()
+
+	request, err := http.NewRequest(http.MethodGet, serverURL+"/api/auth/session", nil)
+	if err != nil {
+		t.Fatalf("build session request: %v", err)
+	}
+	for _, cookie := range cookies {
+		request.AddCookie(cookie)
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		t.Fatalf("fetch csrf token: %v", err)
+	}
+	defer response.Body.Close()
+
+	payload := map[string]any{}
+	_ = json.NewDecoder(response.Body).Decode(&payload)
+	token, _ := payload["csrfToken"].(string)
+	if token == "" {
+		t.Fatalf("session status did not include a csrf token: %v", payload)
+	}
+	return token
+}
+
 func ensureTransactionsSupportedForHandlers(ctx context.Context, client *mongo.Client) error {
 	sessionHandle, err := client.StartSession()
 	if err != nil {
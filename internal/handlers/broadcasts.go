@@ -0,0 +1,290 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"easybook/internal/models"
+	"easybook/internal/session"
+	"easybook/internal/utils"
+	"easybook/internal/view"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	broadcastListPageSize = 20
+	broadcastListPageMax  = 100
+)
+
+var broadcastAudienceLabels = map[string]string{
+	models.BroadcastAll:      "All users",
+	models.BroadcastBookings: "Users with bookings",
+	models.BroadcastRole:     "Users with a role",
+}
+
+func (a *App) listBroadcastsAPI(w http.ResponseWriter, r *http.Request) error {
+	query := r.URL.Query()
+	pagination := utils.GetPagination(query.Get("page"), query.Get("limit"), broadcastListPageSize, broadcastListPageMax)
+
+	items, total, err := a.Store.ListBroadcasts(r.Context(), pagination.Skip, int64(pagination.Limit))
+	if err != nil {
+		return err
+	}
+
+	a.writeJSON(w, http.StatusOK, map[string]any{
+		"items": items,
+		"meta":  utils.GetPaginationMeta(total, pagination.Page, pagination.Limit),
+	})
+	return nil
+}
+
+func (a *App) getBroadcastAPI(w http.ResponseWriter, r *http.Request) error {
+	broadcast, err := a.Store.FindBroadcast(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		return err
+	}
+	if broadcast == nil {
+		a.writeJSON(w, http.StatusNotFound, map[string]string{"error": "Broadcast not found"})
+		return nil
+	}
+
+	a.writeJSON(w, http.StatusOK, broadcast)
+	return nil
+}
+
+func (a *App) createBroadcastAPI(w http.ResponseWriter, r *http.Request) error {
+	payload, err := a.parsePayload(r)
+	if err != nil {
+		return err
+	}
+
+	broadcast, err := a.createBroadcast(r, payload)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidBroadcast) {
+			a.writeJSON(w, http.StatusBadRequest, map[string]string{
+				"error":   "validation_error",
+				"message": strings.TrimPrefix(err.Error(), models.ErrInvalidBroadcast.Error()+": "),
+			})
+			return nil
+		}
+		return err
+	}
+
+	a.writeJSON(w, http.StatusAccepted, broadcast)
+	return nil
+}
+
+// createBroadcast stores the announcement described by payload and queues
+// its first delivery batch.
+func (a *App) createBroadcast(r *http.Request, payload map[string]any) (*models.Broadcast, error) {
+	user := session.CurrentUser(r)
+	authorID, _ := primitive.ObjectIDFromHex(user.ID)
+
+	broadcast, err := a.Store.CreateBroadcast(r.Context(), models.Broadcast{
+		Title: utils.ToTrimmedString(payload["title"]),
+		Text:  utils.ToTrimmedString(payload["text"]),
+		Link:  utils.ToTrimmedString(payload["link"]),
+		Target: models.BroadcastTarget{
+			Audience: utils.ToTrimmedString(payload["audience"]),
+			HotelID: firstNonEmpty(
+				utils.ToTrimmedString(payload["hotel_id"]),
+				utils.ToTrimmedString(payload["hotelId"]),
+			),
+			From: utils.ToTrimmedString(payload["from"]),
+			To:   utils.ToTrimmedString(payload["to"]),
+			Role: utils.ToTrimmedString(payload["role"]),
+		},
+		CreatedBy:      authorID,
+		CreatedByEmail: user.Email,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.queueBroadcastBatch(r.Context(), broadcast); err != nil {
+		return nil, err
+	}
+	return broadcast, nil
+}
+
+// queueBroadcastBatch queues the delivery batch that starts after the
+// broadcast's last recipient. The key keeps each batch from being queued
+// twice.
+func (a *App) queueBroadcastBatch(ctx context.Context, broadcast *models.Broadcast) error {
+	after := "start"
+	if !broadcast.LastUserID.IsZero() {
+		after = broadcast.LastUserID.Hex()
+	}
+	return a.Jobs.Enqueue(ctx, models.Job{
+		Kind:    jobDeliverBroadcast,
+		Key:     fmt.Sprintf("%s:%s:%s", jobDeliverBroadcast, broadcast.ID.Hex(), after),
+		Payload: map[string]string{"broadcastId": broadcast.ID.Hex()},
+	})
+}
+
+func (a *App) runBroadcastJob(ctx context.Context, job models.Job) error {
+	broadcast, err := a.Store.DeliverBroadcastBatch(ctx, job.Payload["broadcastId"], a.Env.BroadcastBatchSize)
+	if err != nil || broadcast == nil || broadcast.Status != models.BroadcastSending {
+		return err
+	}
+	return a.queueBroadcastBatch(ctx, broadcast)
+}
+
+func (a *App) renderBroadcastsPage(w http.ResponseWriter, r *http.Request) error {
+	return a.renderBroadcastsTemplate(w, r, http.StatusOK, "", "", nil)
+}
+
+func (a *App) createBroadcastFromPage(w http.ResponseWriter, r *http.Request) error {
+	payload, err := a.parsePayload(r)
+	if err != nil {
+		return err
+	}
+
+	broadcast, err := a.createBroadcast(r, payload)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidBroadcast) {
+			message := strings.TrimPrefix(err.Error(), models.ErrInvalidBroadcast.Error()+": ")
+			return a.renderBroadcastsTemplate(w, r, http.StatusBadRequest, "", message, payload)
+		}
+		return err
+	}
+
+	return a.renderBroadcastsTemplate(w, r, http.StatusCreated, fmt.Sprintf("Sending %q.", broadcast.Title), "", nil)
+}
+
+// renderBroadcastsTemplate shows the compose form, refilled from form after
+// a validation error, and the sent broadcasts with their read counts.
+func (a *App) renderBroadcastsTemplate(w http.ResponseWriter, r *http.Request, statusCode int, noticeMessage, errorMessage string, form map[string]any) error {
+	user := session.CurrentUser(r)
+	query := r.URL.Query()
+	pagination := utils.GetPagination(query.Get("page"), query.Get("limit"), broadcastListPageSize, broadcastListPageMax)
+
+	items, total, err := a.Store.ListBroadcasts(r.Context(), pagination.Skip, int64(pagination.Limit))
+	if err != nil {
+		return err
+	}
+	hotels, _, err := a.Store.FindHotels(r.Context(), bson.M{}, bson.D{{Key: "title", Value: 1}}, bson.M{"title": 1}, 0, 500)
+	if err != nil {
+		return err
+	}
+	meta := utils.GetPaginationMeta(total, pagination.Page, pagination.Limit)
+
+	var list strings.Builder
+	if len(items) == 0 {
+		list.WriteString(`<p>No broadcasts sent yet.</p>`)
+	}
+	for _, item := range items {
+		status := "sending"
+		if item.Status == models.BroadcastSent {
+			status = "sent " + item.FinishedAt.Format("2006-01-02 15:04")
+		}
+		list.WriteString(fmt.Sprintf(`
+      <div class="auth-row">
+        <span><strong>%s</strong><br />%s &middot; %s &middot; by %s<br />%d recipient(s), %d read</span>
+      </div>
+    `,
+			view.EscapeHTML(item.Title),
+			view.EscapeHTML(describeBroadcastTarget(item.Target)),
+			view.EscapeHTML(status),
+			view.EscapeHTML(item.CreatedByEmail),
+			item.Recipients,
+			item.Read,
+		))
+	}
+
+	value := func(key string) string {
+		return view.EscapeHTML(utils.ToTrimmedString(form[key]))
+	}
+	audience := utils.ToTrimmedString(form["audience"])
+	audienceOptions := make([]string, 0, len(models.BroadcastAudiences))
+	for _, option := range models.BroadcastAudiences {
+		audienceOptions = append(audienceOptions, renderOption(option, broadcastAudienceLabels[option], audience == option))
+	}
+	hotelID := firstNonEmpty(utils.ToTrimmedString(form["hotel_id"]), utils.ToTrimmedString(form["hotelId"]))
+	hotelOptions := []string{renderOption("", "Any hotel", hotelID == "")}
+	for _, hotel := range hotels {
+		id := objectIDHex(hotel["_id"])
+		hotelOptions = append(hotelOptions, renderOption(id, utils.ToTrimmedString(hotel["title"]), hotelID == id))
+	}
+
+	formHTML := fmt.Sprintf(`
+      <h3>New broadcast</h3>
+      <form method="POST" action="/admin/broadcasts" class="contact-form">
+        <div class="form-group">
+          <label for="broadcastTitle">Title</label>
+          <input id="broadcastTitle" type="text" name="title" maxlength="120" value="%s" required />
+        </div>
+        <div class="form-group">
+          <label for="broadcastText">Message</label>
+          <textarea id="broadcastText" name="text" rows="5" maxlength="2000" required>%s</textarea>
+        </div>
+        <div class="form-group">
+          <label for="broadcastLink">Link (optional path, e.g. /hotels)</label>
+          <input id="broadcastLink" type="text" name="link" value="%s" />
+        </div>
+        <div class="form-group">
+          <label for="broadcastAudience">Send to</label>
+          <select id="broadcastAudience" name="audience">%s</select>
+        </div>
+        <div class="form-group">
+          <label for="broadcastHotel">Hotel (users with bookings)</label>
+          <select id="broadcastHotel" name="hotel_id">%s</select>
+        </div>
+        <div class="form-group">
+          <label for="broadcastFrom">Stays from / to (users with bookings)</label>
+          <input id="broadcastFrom" type="date" name="from" value="%s" />
+          <input id="broadcastTo" type="date" name="to" value="%s" />
+        </div>
+        <div class="form-group">
+          <label for="broadcastRole">Role (users with a role)</label>
+          <input id="broadcastRole" type="text" name="role" value="%s" placeholder="admin" />
+        </div>
+        <button type="submit" class="btn">Send broadcast</button>
+      </form>
+    `,
+		value("title"),
+		value("text"),
+		value("link"),
+		strings.Join(audienceOptions, ""),
+		strings.Join(hotelOptions, ""),
+		value("from"),
+		value("to"),
+		value("role"),
+	)
+
+	return a.renderHTML(w, r, statusCode, "admin-broadcasts.html", map[string]any{
+		"authControls":    view.Safe(renderAuthControls(user, "/admin/broadcasts")),
+		"noticeMessage":   renderNotice("success", noticeMessage),
+		"errorMessage":    errorMessage,
+		"composeBlock":    view.Safe(formHTML),
+		"broadcastsBlock": view.Safe(list.String()),
+		"paginationBar": view.Safe(renderPaginationBar(meta, "/admin/broadcasts", map[string]string{
+			"limit": strconv.Itoa(pagination.Limit),
+		})),
+	})
+}
+
+func describeBroadcastTarget(target models.BroadcastTarget) string {
+	switch target.Audience {
+	case models.BroadcastRole:
+		return "Role " + target.Role
+	case models.BroadcastBookings:
+		parts := []string{"Bookings"}
+		if target.HotelID != "" {
+			parts = append(parts, "at hotel "+target.HotelID)
+		}
+		if target.From != "" {
+			parts = append(parts, fmt.Sprintf("staying %s to %s", target.From, target.To))
+		}
+		return strings.Join(parts, " ")
+	default:
+		return broadcastAudienceLabels[models.BroadcastAll]
+	}
+}
@@ -0,0 +1,471 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"easybook/internal/mail"
+	"easybook/internal/models"
+	"easybook/internal/session"
+	"easybook/internal/types"
+	"easybook/internal/utils"
+	"easybook/internal/view"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	contactInboxPageSize = 20
+	contactInboxPageMax  = 100
+	maxContactReplyLen   = 5000
+	maxContactNoteLen    = 2000
+)
+
+var contactStatusLabels = map[string]string{
+	models.ContactStatusNew:        "New",
+	models.ContactStatusInProgress: "In progress",
+	models.ContactStatusResolved:   "Resolved",
+	models.ContactStatusSpam:       "Spam",
+}
+
+func (a *App) renderContactInboxPage(w http.ResponseWriter, r *http.Request) error {
+	user := session.CurrentUser(r)
+	query := r.URL.Query()
+	filter := contactFilterFromQuery(query.Get("status"), query.Get("assignee"), query.Get("q"), user)
+	pagination := utils.GetPagination(query.Get("page"), query.Get("limit"), contactInboxPageSize, contactInboxPageMax)
+
+	items, total, err := a.Store.ListContactRequests(r.Context(), filter, pagination.Skip, int64(pagination.Limit))
+	if err != nil {
+		return err
+	}
+	staff, err := a.Store.ListUsersByRole(r.Context(), "admin")
+	if err != nil {
+		return err
+	}
+	meta := utils.GetPaginationMeta(total, pagination.Page, pagination.Limit)
+
+	listHTML := `<div class="feature-card"><h3>No contact requests found</h3></div>`
+	if len(items) > 0 {
+		parts := make([]string, 0, len(items))
+		for _, item := range items {
+			assigned := "Unassigned"
+			if item.AssignedEmail != "" {
+				assigned = item.AssignedEmail
+			}
+			parts = append(parts, fmt.Sprintf(`
+        <div class="feature-card" style="text-align:left;">
+          <h3>%s</h3>
+          <p>
+            <span class="chip">%s</span><br/>
+            <strong>From:</strong> %s &lt;%s&gt;<br/>
+            <strong>Received:</strong> %s<br/>
+            <strong>Assigned:</strong> %s
+          </p>
+          <p>%s</p>
+          <a class="btn" href="/admin/contact-requests/%s">Open</a>
+        </div>
+      `,
+				view.EscapeHTML(truncateText(item.Message, 60)),
+				view.EscapeHTML(contactStatusLabels[item.Status]),
+				view.EscapeHTML(item.Name),
+				view.EscapeHTML(item.Email),
+				view.EscapeHTML(item.CreatedAt.Format("2006-01-02 15:04")),
+				view.EscapeHTML(assigned),
+				view.EscapeHTML(truncateText(item.Message, 240)),
+				item.ID.Hex(),
+			))
+		}
+		listHTML = strings.Join(parts, "")
+	}
+
+	statusOptions := []string{renderOption("", "Any status", filter.Status == "")}
+	for _, status := range models.ContactStatuses {
+		statusOptions = append(statusOptions, renderOption(status, contactStatusLabels[status], filter.Status == status))
+	}
+
+	assignee := strings.TrimSpace(query.Get("assignee"))
+	assigneeOptions := []string{
+		renderOption("", "Anyone", assignee == ""),
+		renderOption("me", "Assigned to me", assignee == "me"),
+		renderOption("unassigned", "Unassigned", assignee == "unassigned"),
+	}
+	for _, member := range staff {
+		assigneeOptions = append(assigneeOptions, renderOption(member.ID.Hex(), member.Email, assignee == member.ID.Hex()))
+	}
+
+	paginationBar := renderPaginationBar(meta, "/admin/contact-requests", map[string]string{
+		"status":   filter.Status,
+		"assignee": assignee,
+		"q":        filter.Query,
+		"limit":    strconv.Itoa(pagination.Limit),
+	})
+
+	return a.renderHTML(w, r, http.StatusOK, "admin-contact-requests.html", map[string]any{
+		"authControls":    view.Safe(renderAuthControls(user, "/admin/contact-requests")),
+		"statusOptions":   view.Safe(strings.Join(statusOptions, "")),
+		"assigneeOptions": view.Safe(strings.Join(assigneeOptions, "")),
+		"queryValue":      filter.Query,
+		"requests":        view.Safe(listHTML),
+		"paginationBar":   view.Safe(paginationBar),
+	})
+}
+
+func (a *App) renderContactRequestPage(w http.ResponseWriter, r *http.Request) error {
+	request, err := a.Store.FindContactRequestByID(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		return err
+	}
+	if request == nil {
+		a.NotFoundHandler(w, r)
+		return nil
+	}
+
+	notice := ""
+	switch r.URL.Query().Get("updated") {
+	case "status":
+		notice = "Status updated."
+	case "assign":
+		notice = "Assignment updated."
+	case "note":
+		notice = "Note added."
+	case "reply":
+		notice = "Reply sent to " + request.Email + "."
+	}
+	return a.renderContactRequestTemplate(w, r, http.StatusOK, request, notice, "")
+}
+
+func (a *App) updateContactStatusFromPage(w http.ResponseWriter, r *http.Request) error {
+	return a.handleContactAdminForm(w, r, "status", a.changeContactStatus)
+}
+
+func (a *App) assignContactRequestFromPage(w http.ResponseWriter, r *http.Request) error {
+	return a.handleContactAdminForm(w, r, "assign", a.assignContactRequest)
+}
+
+func (a *App) addContactNoteFromPage(w http.ResponseWriter, r *http.Request) error {
+	return a.handleContactAdminForm(w, r, "note", a.addContactNote)
+}
+
+func (a *App) replyToContactRequestFromPage(w http.ResponseWriter, r *http.Request) error {
+	return a.handleContactAdminForm(w, r, "reply", a.replyToContactRequest)
+}
+
+type contactAdminAction func(ctx context.Context, id string, payload map[string]any, user *types.CurrentUser) (*models.ContactRequest, error)
+
+func (a *App) handleContactAdminForm(w http.ResponseWriter, r *http.Request, updated string, action contactAdminAction) error {
+	id := chi.URLParam(r, "id")
+	payload, err := a.parsePayload(r)
+	if err != nil {
+		return err
+	}
+
+	if _, err := action(r.Context(), id, payload, session.CurrentUser(r)); err != nil {
+		status, message, ok := contactAdminError(err)
+		if !ok {
+			return err
+		}
+		if status == http.StatusNotFound {
+			a.NotFoundHandler(w, r)
+			return nil
+		}
+		request, findErr := a.Store.FindContactRequestByID(r.Context(), id)
+		if findErr != nil || request == nil {
+			return err
+		}
+		return a.renderContactRequestTemplate(w, r, status, request, "", message)
+	}
+
+	http.Redirect(w, r, "/admin/contact-requests/"+id+"?updated="+updated, http.StatusFound)
+	return nil
+}
+
+func (a *App) listContactRequestsAPI(w http.ResponseWriter, r *http.Request) error {
+	query := r.URL.Query()
+	filter := contactFilterFromQuery(query.Get("status"), query.Get("assignee"), query.Get("q"), session.CurrentUser(r))
+	pagination := utils.GetPagination(query.Get("page"), query.Get("limit"), contactInboxPageSize, contactInboxPageMax)
+
+	items, total, err := a.Store.ListContactRequests(r.Context(), filter, pagination.Skip, int64(pagination.Limit))
+	if err != nil {
+		return err
+	}
+
+	a.writeJSON(w, http.StatusOK, map[string]any{
+		"items":    items,
+		"meta":     utils.GetPaginationMeta(total, pagination.Page, pagination.Limit),
+		"statuses": models.ContactStatuses,
+	})
+	return nil
+}
+
+func (a *App) getContactRequestAPI(w http.ResponseWriter, r *http.Request) error {
+	request, err := a.Store.FindContactRequestByID(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		return err
+	}
+	if request == nil {
+		a.writeJSON(w, http.StatusNotFound, map[string]string{"error": "Contact request not found"})
+		return nil
+	}
+
+	a.writeJSON(w, http.StatusOK, map[string]any{"item": request, "nextStatuses": models.ContactStatusTargets(request.Status)})
+	return nil
+}
+
+func (a *App) updateContactStatusAPI(w http.ResponseWriter, r *http.Request) error {
+	return a.handleContactAdminAPI(w, r, a.changeContactStatus)
+}
+
+func (a *App) assignContactRequestAPI(w http.ResponseWriter, r *http.Request) error {
+	return a.handleContactAdminAPI(w, r, a.assignContactRequest)
+}
+
+func (a *App) addContactNoteAPI(w http.ResponseWriter, r *http.Request) error {
+	return a.handleContactAdminAPI(w, r, a.addContactNote)
+}
+
+func (a *App) replyToContactRequestAPI(w http.ResponseWriter, r *http.Request) error {
+	return a.handleContactAdminAPI(w, r, a.replyToContactRequest)
+}
+
+func (a *App) handleContactAdminAPI(w http.ResponseWriter, r *http.Request, action contactAdminAction) error {
+	payload, err := a.parsePayload(r)
+	if err != nil {
+		a.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid payload"})
+		return nil
+	}
+
+	request, err := action(r.Context(), chi.URLParam(r, "id"), payload, session.CurrentUser(r))
+	if err != nil {
+		status, message, ok := contactAdminError(err)
+		if !ok {
+			return err
+		}
+		a.writeJSON(w, status, map[string]string{"error": message})
+		return nil
+	}
+
+	a.writeJSON(w, http.StatusOK, map[string]any{"item": request, "nextStatuses": models.ContactStatusTargets(request.Status)})
+	return nil
+}
+
+func (a *App) changeContactStatus(ctx context.Context, id string, payload map[string]any, _ *types.CurrentUser) (*models.ContactRequest, error) {
+	return a.Store.UpdateContactRequestStatus(ctx, id, utils.ToTrimmedString(payload["status"]))
+}
+
+// assignContactRequest accepts a staff user id, "me", or an empty value to
+// unassign. Only admins count as staff.
+func (a *App) assignContactRequest(ctx context.Context, id string, payload map[string]any, user *types.CurrentUser) (*models.ContactRequest, error) {
+	assigneeID := utils.ToTrimmedString(payload["assignee"])
+	if assigneeID == "me" {
+		assigneeID = user.ID
+	}
+	if assigneeID == "" {
+		return a.Store.AssignContactRequest(ctx, id, nil)
+	}
+
+	assignee, err := a.Store.FindUserByID(ctx, assigneeID)
+	if err != nil {
+		return nil, err
+	}
+	if assignee == nil || assignee.Role != "admin" {
+		return nil, fmt.Errorf("%w: requests can only be assigned to staff", models.ErrInvalidContactPayload)
+	}
+	return a.Store.AssignContactRequest(ctx, id, assignee)
+}
+
+func (a *App) addContactNote(ctx context.Context, id string, payload map[string]any, user *types.CurrentUser) (*models.ContactRequest, error) {
+	text := utils.ToTrimmedString(payload["note"])
+	if len([]rune(text)) > maxContactNoteLen {
+		return nil, fmt.Errorf("%w: note must be at most %d characters", models.ErrInvalidContactPayload, maxContactNoteLen)
+	}
+
+	authorID, _ := primitive.ObjectIDFromHex(user.ID)
+	return a.Store.AddContactNote(ctx, id, models.ContactNote{AuthorID: authorID, AuthorEmail: user.Email, Text: text})
+}
+
+// replyToContactRequest queues the response to the sender and logs it on
+// the request. With resolve set, the request is closed in the same step.
+func (a *App) replyToContactRequest(ctx context.Context, id string, payload map[string]any, user *types.CurrentUser) (*models.ContactRequest, error) {
+	request, err := a.Store.FindContactRequestByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if request == nil {
+		return nil, models.ErrContactRequestNotFound
+	}
+
+	subject := utils.ToTrimmedString(payload["subject"])
+	if subject == "" {
+		subject = "Re: your message to Easy Booking"
+	}
+	text := utils.ToTrimmedString(payload["message"])
+	if text == "" {
+		return nil, fmt.Errorf("%w: reply text is required", models.ErrInvalidContactPayload)
+	}
+	if len([]rune(text)) > maxContactReplyLen {
+		return nil, fmt.Errorf("%w: reply must be at most %d characters", models.ErrInvalidContactPayload, maxContactReplyLen)
+	}
+	if !utils.ValidateEmail(request.Email) {
+		return nil, fmt.Errorf("%w: the request has no valid email address to reply to", models.ErrInvalidContactPayload)
+	}
+
+	body := fmt.Sprintf("%s\n\n--\nEasy Booking support\n\nYour message from %s:\n> %s\n",
+		text,
+		request.CreatedAt.Format("2006-01-02"),
+		strings.ReplaceAll(request.Message, "\n", "\n> "),
+	)
+	// The key covers the reply's content, so resubmitting a reply whose
+	// logging failed does not mail it twice.
+	digest := sha256.Sum256([]byte(subject + "\x00" + text))
+	key := "contact-reply:" + request.ID.Hex() + ":" + hex.EncodeToString(digest[:8])
+	if err := a.queueMail(ctx, key, mail.Message{To: request.Email, Subject: subject, Text: body}); err != nil {
+		return nil, err
+	}
+
+	authorID, _ := primitive.ObjectIDFromHex(user.ID)
+	updated, err := a.Store.AddContactReply(ctx, id, models.ContactReply{
+		AuthorID:    authorID,
+		AuthorEmail: user.Email,
+		Subject:     subject,
+		Text:        text,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resolve := utils.ToTrimmedString(payload["resolve"])
+	if (resolve == "on" || resolve == "true" || payload["resolve"] == true) && updated.Status != models.ContactStatusResolved {
+		return a.Store.UpdateContactRequestStatus(ctx, id, models.ContactStatusResolved)
+	}
+	return updated, nil
+}
+
+func contactAdminError(err error) (int, string, bool) {
+	switch {
+	case errors.Is(err, models.ErrContactRequestNotFound):
+		return http.StatusNotFound, "Contact request not found", true
+	case errors.Is(err, models.ErrInvalidContactTransition):
+		return http.StatusConflict, strings.TrimPrefix(err.Error(), models.ErrInvalidContactTransition.Error()+": "), true
+	case errors.Is(err, models.ErrInvalidContactPayload):
+		return http.StatusBadRequest, strings.TrimPrefix(err.Error(), models.ErrInvalidContactPayload.Error()+": "), true
+	}
+	return 0, "", false
+}
+
+func contactFilterFromQuery(status, assignee, text string, user *types.CurrentUser) models.ContactRequestFilter {
+	status = strings.ToLower(strings.TrimSpace(status))
+	if _, known := contactStatusLabels[status]; !known {
+		status = ""
+	}
+	assignee = strings.TrimSpace(assignee)
+	if assignee == "me" && user != nil {
+		assignee = user.ID
+	}
+	return models.ContactRequestFilter{Status: status, Assignee: assignee, Query: strings.TrimSpace(text)}
+}
+
+func (a *App) renderContactRequestTemplate(w http.ResponseWriter, r *http.Request, statusCode int, request *models.ContactRequest, noticeMessage, errorMessage string) error {
+	staff, err := a.Store.ListUsersByRole(r.Context(), "admin")
+	if err != nil {
+		return err
+	}
+
+	statusOptions := make([]string, 0, 4)
+	for _, status := range models.ContactStatusTargets(request.Status) {
+		statusOptions = append(statusOptions, renderOption(status, contactStatusLabels[status], false))
+	}
+	statusForm := ""
+	if len(statusOptions) > 0 {
+		statusForm = fmt.Sprintf(`
+        <form method="POST" action="/admin/contact-requests/%s/status" class="contact-form">
+          <div class="form-group">
+            <label for="status">Move to</label>
+            <select id="status" name="status">%s</select>
+          </div>
+          <button type="submit" class="btn btn-outline">Change status</button>
+        </form>`, request.ID.Hex(), strings.Join(statusOptions, ""))
+	}
+
+	assigned := ""
+	if request.AssignedTo != nil {
+		assigned = request.AssignedTo.Hex()
+	}
+	assigneeOptions := []string{renderOption("", "Unassigned", assigned == "")}
+	for _, member := range staff {
+		assigneeOptions = append(assigneeOptions, renderOption(member.ID.Hex(), member.Email, assigned == member.ID.Hex()))
+	}
+
+	notesHTML := `<p>No internal notes yet.</p>`
+	if len(request.Notes) > 0 {
+		parts := make([]string, 0, len(request.Notes))
+		for _, note := range request.Notes {
+			parts = append(parts, fmt.Sprintf(`<div class="feature-card" style="text-align:left;"><p style="white-space:pre-wrap;">%s</p><small>%s, %s</small></div>`,
+				view.EscapeHTML(note.Text),
+				view.EscapeHTML(note.AuthorEmail),
+				view.EscapeHTML(note.CreatedAt.Format("2006-01-02 15:04")),
+			))
+		}
+		notesHTML = strings.Join(parts, "")
+	}
+
+	repliesHTML := `<p>No replies sent yet.</p>`
+	if len(request.Replies) > 0 {
+		parts := make([]string, 0, len(request.Replies))
+		for _, reply := range request.Replies {
+			parts = append(parts, fmt.Sprintf(`<div class="feature-card" style="text-align:left;"><h4>%s</h4><p style="white-space:pre-wrap;">%s</p><small>Sent by %s, %s</small></div>`,
+				view.EscapeHTML(reply.Subject),
+				view.EscapeHTML(reply.Text),
+				view.EscapeHTML(reply.AuthorEmail),
+				view.EscapeHTML(reply.SentAt.Format("2006-01-02 15:04")),
+			))
+		}
+		repliesHTML = strings.Join(parts, "")
+	}
+
+	spamInfo := ""
+	if request.SpamScore > 0 {
+		spamInfo = fmt.Sprintf(`<p class="chip">Spam score %d: %s</p>`, request.SpamScore, view.EscapeHTML(strings.Join(request.SpamReasons, "; ")))
+	}
+
+	return a.renderHTML(w, r, statusCode, "admin-contact-request.html", map[string]any{
+		"authControls":    view.Safe(renderAuthControls(session.CurrentUser(r), "/admin/contact-requests/"+request.ID.Hex())),
+		"noticeMessage":   renderNotice("success", noticeMessage),
+		"errorMessage":    errorMessage,
+		"requestID":       request.ID.Hex(),
+		"statusLabel":     contactStatusLabels[request.Status],
+		"senderName":      request.Name,
+		"senderEmail":     request.Email,
+		"senderPhone":     request.Phone,
+		"senderCity":      request.City,
+		"receivedAt":      request.CreatedAt.Format("2006-01-02 15:04"),
+		"message":         request.Message,
+		"spamInfo":        view.Safe(spamInfo),
+		"statusForm":      view.Safe(statusForm),
+		"assigneeOptions": view.Safe(strings.Join(assigneeOptions, "")),
+		"notes":           view.Safe(notesHTML),
+		"replies":         view.Safe(repliesHTML),
+	})
+}
+
+func renderOption(value, label string, selected bool) string {
+	selectedAttr := ""
+	if selected {
+		selectedAttr = " selected"
+	}
+	return fmt.Sprintf(`<option value="%s"%s>%s</option>`, view.EscapeHTML(value), selectedAttr, view.EscapeHTML(label))
+}
+
+func truncateText(text string, limit int) string {
+	runes := []rune(strings.TrimSpace(text))
+	if len(runes) <= limit {
+		return string(runes)
+	}
+	return strings.TrimSpace(string(runes[:limit])) + "…"
+}
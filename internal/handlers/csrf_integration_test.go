@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"easybook/internal/config"
+	"easybook/internal/db"
+	"easybook/internal/models"
+	"easybook/internal/session"
+	"easybook/internal/view"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestCSRFTokenRequiredForStateChangingRequests(t *testing.T) {
+	mongoURI := strings.TrimSpace(os.Getenv("MONGO_URI"))
+	if mongoURI == "" {
+		t.Skip("MONGO_URI is not set; skipping integration test")
+	}
+
+	dbName := "easybook_csrf_test_" + primitive.NewObjectID().Hex()
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURI))
+	if err != nil {
+		t.Fatalf("connect mongo: %v", err)
+	}
+	defer func() {
+		_ = client.Disconnect(context.Background())
+	}()
+
+	database := client.Database(dbName)
+	defer func() {
+		_ = database.Drop(context.Background())
+	}()
+
+	if err := db.EnsureStartupMaintenance(ctx, database); err != nil {
+		t.Fatalf("ensure indexes: %v", err)
+	}
+
+	sessions, err := session.NewManager(ctx, database, false, "csrf-integration-secret-123")
+	if err != nil {
+		t.Fatalf("init sessions: %v", err)
+	}
+
+	app := NewApp(config.Env{}, models.NewStore(database), sessions, view.NewRenderer("../../views"), "../../views")
+	server := httptest.NewServer(app.Router())
+	defer server.Close()
+
+	sessionCookie := createSessionCookieForTests(t, sessions, primitive.NewObjectID().Hex(), "csrf@example.com", "user")
+
+	logout := func(token string) int {
+		form := url.Values{"next": {"/hotels"}}
+		if token != "" {
+			form.Set("_csrf", token)
+		}
+		request, _ := http.NewRequest(http.MethodPost, server.URL+"/logout", strings.NewReader(form.Encode()))
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		request.AddCookie(sessionCookie)
+		response, err := newPresenceTestHTTPClient().Do(request)
+		if err != nil {
+			t.Fatalf("logout request: %v", err)
+		}
+		response.Body.Close()
+		return response.StatusCode
+	}
+
+	if status := logout(""); status != http.StatusForbidden {
+		t.Fatalf("expected logout without token to be rejected, got %d", status)
+	}
+	if status := logout("forged-token"); status != http.StatusForbidden {
+		t.Fatalf("expected logout with forged token to be rejected, got %d", status)
+	}
+
+	pageRequest, _ := http.NewRequest(http.MethodGet, server.URL+"/bookings", nil)
+	pageRequest.AddCookie(sessionCookie)
+	pageResponse, err := http.DefaultClient.Do(pageRequest)
+	if err != nil {
+		t.Fatalf("load bookings page: %v", err)
+	}
+	body, _ := io.ReadAll(pageResponse.Body)
+	pageResponse.Body.Close()
+
+	token := fetchCSRFTokenForTests(t, http.DefaultClient, server.URL, sessionCookie)
+	if !strings.Contains(string(body), `name="_csrf" value="`+token+`"`) {
+		t.Fatal("expected rendered forms to carry the session csrf token")
+	}
+
+	if status := logout(token); status != http.StatusFound {
+		t.Fatalf("expected logout with valid token to succeed, got %d", status)
+	}
+}
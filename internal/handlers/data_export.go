@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"easybook/internal/jobs"
+	"easybook/internal/models"
+	"easybook/internal/session"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// dataExportTimeout is how long an export may stay pending. Older ones are
+// given up, so a lost job does not block new exports until the TTL.
+const dataExportTimeout = 15 * time.Minute
+
+// dataExportArchive is the document users download. Session metadata comes
+// from the session store, so it is read while the request is still around
+// and passed on to the export job.
+type dataExportArchive struct {
+	GeneratedAt time.Time `json:"generatedAt"`
+	*models.UserData
+	Sessions []session.Info `json:"sessions"`
+}
+
+// exportUserDataAPI returns the archive right away for small accounts. Larger
+// ones (or ?async=1) get a background export whose status can be polled. It
+// is a POST, so the CSRF check applies and link prefetchers cannot start
+// exports.
+func (a *App) exportUserDataAPI(w http.ResponseWriter, r *http.Request) error {
+	user := session.CurrentUser(r)
+	query := r.URL.Query()
+
+	format := strings.ToLower(strings.TrimSpace(query.Get("format")))
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "zip" {
+		a.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "format must be json or zip"})
+		return nil
+	}
+
+	sessions, err := a.Sessions.ListUserSessions(r, user.ID)
+	if err != nil {
+		return err
+	}
+	records, err := a.Store.CountUserData(r.Context(), user.ID, user.Email)
+	if err != nil {
+		return err
+	}
+
+	async, _ := strconv.ParseBool(query.Get("async"))
+	if !async && records <= int64(a.Env.DataExportSyncLimit) {
+		fileName, contentType, data, err := a.buildDataExport(r.Context(), user.ID, format, sessions)
+		if err != nil {
+			return err
+		}
+		writeDownload(w, fileName, contentType, data)
+		return nil
+	}
+
+	export, err := a.Store.PendingDataExport(r.Context(), user.ID, time.Now().Add(-dataExportTimeout))
+	if err != nil {
+		return err
+	}
+	if export == nil {
+		sessionsJSON, err := json.Marshal(sessions)
+		if err != nil {
+			return err
+		}
+		export, err = a.Store.CreateDataExport(r.Context(), user.ID, format, a.dataExportTTL())
+		if err != nil {
+			return err
+		}
+		err = a.Jobs.Enqueue(r.Context(), models.Job{
+			Kind: jobGenerateDataExport,
+			Key:  jobGenerateDataExport + ":" + export.ID.Hex(),
+			Payload: map[string]string{
+				"exportId": export.ID.Hex(),
+				"userId":   user.ID,
+				"sessions": string(sessionsJSON),
+			},
+		})
+		if err != nil {
+			_ = a.Store.FailDataExport(r.Context(), export.ID, "The export could not be started. Please try again.")
+			return err
+		}
+	}
+
+	a.writeJSON(w, http.StatusAccepted, dataExportResponse(export))
+	return nil
+}
+
+func (a *App) getDataExportAPI(w http.ResponseWriter, r *http.Request) error {
+	user := session.CurrentUser(r)
+	export, err := a.Store.FindDataExport(r.Context(), user.ID, chi.URLParam(r, "id"), false)
+	if err != nil {
+		return err
+	}
+	if export == nil {
+		a.writeJSON(w, http.StatusNotFound, map[string]string{"error": "Export not found"})
+		return nil
+	}
+
+	a.writeJSON(w, http.StatusOK, dataExportResponse(export))
+	return nil
+}
+
+func (a *App) downloadDataExportAPI(w http.ResponseWriter, r *http.Request) error {
+	user := session.CurrentUser(r)
+	export, err := a.Store.FindDataExport(r.Context(), user.ID, chi.URLParam(r, "id"), true)
+	if err != nil {
+		return err
+	}
+	if export == nil || export.Status != models.DataExportReady {
+		a.writeJSON(w, http.StatusNotFound, map[string]string{"error": "Export not found or not ready"})
+		return nil
+	}
+
+	writeDownload(w, export.FileName, export.ContentType, export.Data)
+	return nil
+}
+
+// runDataExportJob builds the archive of a pending export and tells the
+// user it is ready. Failures end the export instead of retrying, so the
+// user can start a new one.
+func (a *App) runDataExportJob(ctx context.Context, job models.Job) error {
+	userID := job.Payload["userId"]
+	export, err := a.Store.FindDataExport(ctx, userID, job.Payload["exportId"], false)
+	if err != nil {
+		return err
+	}
+	if export == nil || export.Status != models.DataExportPending {
+		return nil
+	}
+	if time.Since(export.CreatedAt) > dataExportTimeout {
+		return a.Store.FailDataExport(ctx, export.ID, "The export took too long. Please try again.")
+	}
+
+	var sessions []session.Info
+	if err := json.Unmarshal([]byte(job.Payload["sessions"]), &sessions); err != nil {
+		sessions = []session.Info{}
+	}
+
+	fileName, contentType, data, err := a.buildDataExport(ctx, userID, export.Format, sessions)
+	if err != nil {
+		_ = a.Store.FailDataExport(ctx, export.ID, "The export could not be generated. Please try again.")
+		return fmt.Errorf("%w: data export %s: %v", jobs.ErrPermanent, export.ID.Hex(), err)
+	}
+	if err := a.Store.CompleteDataExport(ctx, export.ID, fileName, contentType, data); err != nil {
+		_ = a.Store.FailDataExport(ctx, export.ID, "The export could not be stored. Please try again.")
+		return fmt.Errorf("%w: data export %s: %v", jobs.ErrPermanent, export.ID.Hex(), err)
+	}
+
+	link := "/api/me/export/" + export.ID.Hex() + "/download"
+	text := fmt.Sprintf("Your personal data export is ready and can be downloaded until %s.", export.ExpiresAt.Format("2006-01-02 15:04 MST"))
+	if _, err := a.Store.CreateNotification(ctx, userID, models.NotificationDataExportReady, "Data export ready", text, link); err != nil {
+		log.Printf("data export %s: notify user: %v", export.ID.Hex(), err)
+	}
+	return nil
+}
+
+func (a *App) buildDataExport(ctx context.Context, userID, format string, sessions []session.Info) (string, string, []byte, error) {
+	data, err := a.Store.CollectUserData(ctx, userID)
+	if err != nil {
+		return "", "", nil, err
+	}
+	generatedAt := time.Now().UTC()
+	baseName := "easybook-export-" + generatedAt.Format("20060102-150405")
+
+	if format != "zip" {
+		body, err := json.MarshalIndent(dataExportArchive{GeneratedAt: generatedAt, UserData: data, Sessions: sessions}, "", "  ")
+		if err != nil {
+			return "", "", nil, err
+		}
+		return baseName + ".json", "application/json", body, nil
+	}
+
+	files := []struct {
+		name  string
+		value any
+	}{
+		{"profile.json", data.Profile},
+		{"bookings.json", data.Bookings},
+		{"waitlist.json", data.Waitlist},
+		{"notifications.json", data.Notifications},
+		{"reviews.json", data.Reviews},
+		{"contact-requests.json", data.ContactRequests},
+		{"identities.json", data.Identities},
+		{"api-tokens.json", data.APITokens},
+		{"sessions.json", sessions},
+	}
+
+	var buffer bytes.Buffer
+	archive := zip.NewWriter(&buffer)
+	for _, file := range files {
+		body, err := json.MarshalIndent(file.value, "", "  ")
+		if err != nil {
+			return "", "", nil, err
+		}
+		writer, err := archive.CreateHeader(&zip.FileHeader{Name: baseName + "/" + file.name, Method: zip.Deflate, Modified: generatedAt})
+		if err != nil {
+			return "", "", nil, err
+		}
+		if _, err := writer.Write(body); err != nil {
+			return "", "", nil, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return "", "", nil, err
+	}
+	return baseName + ".zip", "application/zip", buffer.Bytes(), nil
+}
+
+func (a *App) dataExportTTL() time.Duration {
+	return time.Duration(a.Env.DataExportTTLHours) * time.Hour
+}
+
+func dataExportResponse(export *models.DataExport) map[string]any {
+	response := map[string]any{
+		"item":      export,
+		"statusUrl": "/api/me/export/" + export.ID.Hex(),
+	}
+	if export.Status == models.DataExportReady {
+		response["downloadUrl"] = "/api/me/export/" + export.ID.Hex() + "/download"
+	}
+	return response
+}
+
+func writeDownload(w http.ResponseWriter, fileName, contentType string, data []byte) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+fileName+`"`)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(data)
+}
@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"easybook/internal/config"
+	"easybook/internal/db"
+	"easybook/internal/models"
+	"easybook/internal/session"
+	"easybook/internal/view"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestDataExport(t *testing.T) {
+	mongoURI := strings.TrimSpace(os.Getenv("MONGO_URI"))
+	if mongoURI == "" {
+		t.Skip("MONGO_URI is not set; skipping integration test")
+	}
+
+	dbName := "easybook_export_test_" + primitive.NewObjectID().Hex()
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURI))
+	if err != nil {
+		t.Fatalf("connect mongo: %v", err)
+	}
+	defer func() {
+		_ = client.Disconnect(context.Background())
+	}()
+
+	database := client.Database(dbName)
+	defer func() {
+		_ = database.Drop(context.Background())
+	}()
+
+	if err := db.EnsureStartupMaintenance(ctx, database); err != nil {
+		t.Fatalf("ensure indexes: %v", err)
+	}
+
+	sessions, err := session.NewManager(ctx, database, false, "export-integration-secret-123")
+	if err != nil {
+		t.Fatalf("init sessions: %v", err)
+	}
+
+	store := models.NewStore(database)
+	env := config.Env{DataExportSyncLimit: 10, DataExportTTLHours: 1}
+	app := NewApp(env, store, sessions, view.NewRenderer("../../views"), "../../views")
+	server := httptest.NewServer(app.Router())
+	defer server.Close()
+
+	userIDText, err := store.CreateUser(ctx, "export@example.com", "Passw0rd!", "user")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	userID, _ := primitive.ObjectIDFromHex(userIDText)
+
+	_, err = database.Collection("bookings").InsertOne(ctx, bson.M{
+		"roomId": primitive.NewObjectID(), "userId": userID, "checkIn": "2030-01-10", "checkOut": "2030-01-12", "createdAt": time.Now().UTC(),
+	})
+	if err != nil {
+		t.Fatalf("insert booking: %v", err)
+	}
+	contactID, err := store.CreateContactRequest(ctx, map[string]string{"name": "Export", "email": "Export@Example.com", "message": "Hello"}, models.ContactScreening{})
+	if err != nil {
+		t.Fatalf("insert contact request: %v", err)
+	}
+	if _, err := store.AddContactNote(ctx, contactID, models.ContactNote{AuthorEmail: "staff@example.com", Text: "Internal remark"}); err != nil {
+		t.Fatalf("add contact note: %v", err)
+	}
+
+	sessionCookie := createSessionCookieForTests(t, sessions, userIDText, "export@example.com", "user")
+	csrfToken := fetchCSRFTokenForTests(t, http.DefaultClient, server.URL, sessionCookie)
+	call := func(method, path string) (*http.Response, []byte) {
+		request, _ := http.NewRequest(method, server.URL+path, nil)
+		request.AddCookie(sessionCookie)
+		if method == http.MethodPost {
+			request.Header.Set(session.CSRFHeaderName, csrfToken)
+		}
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		defer response.Body.Close()
+		body, _ := io.ReadAll(response.Body)
+		return response, body
+	}
+	get := func(path string) (*http.Response, []byte) {
+		return call(http.MethodGet, path)
+	}
+
+	if response, _ := get("/api/me/export"); response.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("expected exports to need a POST, got %d", response.StatusCode)
+	}
+	response, body := call(http.MethodPost, "/api/me/export")
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", response.StatusCode, body)
+	}
+	if !strings.Contains(response.Header.Get("Content-Disposition"), "attachment") {
+		t.Fatalf("expected an attachment, got %q", response.Header.Get("Content-Disposition"))
+	}
+	if bytes.Contains(body, []byte("passwordHash")) {
+		t.Fatal("export must not contain the password hash")
+	}
+	if bytes.Contains(body, []byte("Internal remark")) || bytes.Contains(body, []byte("staff@example.com")) {
+		t.Fatal("export must not contain staff notes on contact requests")
+	}
+	var archive struct {
+		Profile         map[string]any   `json:"profile"`
+		Bookings        []map[string]any `json:"bookings"`
+		ContactRequests []map[string]any `json:"contactRequests"`
+		Sessions        []map[string]any `json:"sessions"`
+	}
+	if err := json.Unmarshal(body, &archive); err != nil {
+		t.Fatalf("decode export: %v", err)
+	}
+	if archive.Profile["email"] != "export@example.com" || len(archive.Bookings) != 1 || len(archive.ContactRequests) != 1 || len(archive.Sessions) != 1 {
+		t.Fatalf("unexpected export content: %s", body)
+	}
+
+	response, body = call(http.MethodPost, "/api/me/export?format=zip&async=1")
+	if response.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", response.StatusCode, body)
+	}
+	var accepted struct {
+		StatusURL string `json:"statusUrl"`
+	}
+	_ = json.Unmarshal(body, &accepted)
+	app.Jobs.Drain(ctx)
+
+	var status struct {
+		Item        models.DataExport `json:"item"`
+		DownloadURL string            `json:"downloadUrl"`
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for status.DownloadURL == "" {
+		if time.Now().After(deadline) {
+			t.Fatalf("export not ready in time: %+v", status.Item)
+		}
+		time.Sleep(100 * time.Millisecond)
+		_, body = get(accepted.StatusURL)
+		if err := json.Unmarshal(body, &status); err != nil {
+			t.Fatalf("decode status: %v", err)
+		}
+	}
+
+	response, body = get(status.DownloadURL)
+	if response.StatusCode != http.StatusOK || response.Header.Get("Content-Type") != "application/zip" {
+		t.Fatalf("expected a zip download, got %d %q", response.StatusCode, response.Header.Get("Content-Type"))
+	}
+	reader, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	names := map[string]bool{}
+	for _, file := range reader.File {
+		names[file.Name[strings.Index(file.Name, "/")+1:]] = true
+	}
+	for _, name := range []string{"profile.json", "bookings.json", "sessions.json"} {
+		if !names[name] {
+			t.Fatalf("zip is missing %s: %v", name, names)
+		}
+	}
+
+	otherCookie := createSessionCookieForTests(t, sessions, primitive.NewObjectID().Hex(), "other@example.com", "user")
+	request, _ := http.NewRequest(http.MethodGet, server.URL+status.DownloadURL, nil)
+	request.AddCookie(otherCookie)
+	otherResponse, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("download as other user: %v", err)
+	}
+	otherResponse.Body.Close()
+	if otherResponse.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 for another user, got %d", otherResponse.StatusCode)
+	}
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"easybook/internal/mail"
+	"easybook/internal/models"
+	"easybook/internal/session"
+	"easybook/internal/view"
+)
+
+func (a *App) sendVerificationEmail(ctx context.Context, userID, email string) error {
+	token, err := a.Store.CreateEmailVerificationToken(ctx, userID, email, a.emailVerificationTTL())
+	if err != nil {
+		return err
+	}
+
+	link := a.Env.AppBaseURL + "/verify-email?token=" + url.QueryEscape(token)
+	return a.queueMail(ctx, "", mail.Message{
+		To:      email,
+		Subject: "Confirm your Easy Booking email",
+		Text: fmt.Sprintf(
+			"Please confirm your email address to start booking.\n\nOpen this link to verify:\n%s\n\nThe link expires in %d hours.\n",
+			link,
+			a.Env.EmailVerificationTTLHours,
+		),
+	})
+}
+
+func (a *App) renderVerifyEmailPage(w http.ResponseWriter, r *http.Request) error {
+	token := strings.TrimSpace(r.URL.Query().Get("token"))
+	if token == "" {
+		notice := ""
+		if r.URL.Query().Get("required") == "1" {
+			notice = "Please verify your email address before creating bookings or waitlist subscriptions."
+		}
+		return a.renderVerifyEmailTemplate(w, r, http.StatusOK, notice, "")
+	}
+
+	user, err := a.Store.ConsumeEmailVerificationToken(r.Context(), token)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidVerificationToken) {
+			return a.renderVerifyEmailTemplate(w, r, http.StatusBadRequest, "", "This verification link is invalid or has expired.")
+		}
+		if models.IsDuplicateKeyError(err, "email") {
+			return a.renderVerifyEmailTemplate(w, r, http.StatusConflict, "", "This email address is already used by another account.")
+		}
+		return err
+	}
+
+	if err := a.Sessions.MarkEmailVerified(r.Context(), user.ID.Hex(), user.Email); err != nil {
+		return err
+	}
+
+	currentUser := session.CurrentUser(r)
+	if currentUser != nil && currentUser.ID == user.ID.Hex() {
+		currentUser.EmailVerified = true
+		currentUser.Email = user.Email
+	}
+
+	return a.renderVerifyEmailTemplate(w, r, http.StatusOK, "Your email address has been verified.", "")
+}
+
+func (a *App) resendVerificationEmail(w http.ResponseWriter, r *http.Request) error {
+	user := session.CurrentUser(r)
+	if user.EmailVerified {
+		return a.renderVerifyEmailTemplate(w, r, http.StatusOK, "Your email address is already verified.", "")
+	}
+
+	if err := a.sendVerificationEmail(r.Context(), user.ID, user.Email); err != nil {
+		return err
+	}
+
+	return a.renderVerifyEmailTemplate(w, r, http.StatusOK, "A new verification link has been sent to "+user.Email+".", "")
+}
+
+func (a *App) renderVerifyEmailTemplate(w http.ResponseWriter, r *http.Request, statusCode int, noticeMessage, errorMessage string) error {
+	user := session.CurrentUser(r)
+
+	statusHTML := `<p>Please <a href="/login?next=%2Fverify-email">login</a> to check the verification state of your account.</p>`
+	if user != nil && user.EmailVerified {
+		statusHTML = fmt.Sprintf(`<p><strong>%s</strong> is verified. You can create bookings and waitlist subscriptions.</p>`, view.EscapeHTML(user.Email))
+	} else if user != nil {
+		statusHTML = fmt.Sprintf(`
+      <p>We sent a verification link to <strong>%s</strong>. Open it to activate booking features.</p>
+      <form method="POST" action="/verify-email/resend">
+        <button type="submit" class="btn btn-outline">Resend verification email</button>
+      </form>
+    `, view.EscapeHTML(user.Email))
+	}
+
+	return a.renderHTML(w, r, statusCode, "verify-email.html", map[string]any{
+		"authControls":  view.Safe(renderAuthControls(user, "/verify-email")),
+		"noticeMessage": renderNotice("success", noticeMessage),
+		"errorMessage":  errorMessage,
+		"statusBlock":   view.Safe(statusHTML),
+	})
+}
+
+func (a *App) emailVerificationTTL() time.Duration {
+	return time.Duration(a.Env.EmailVerificationTTLHours) * time.Hour
+}
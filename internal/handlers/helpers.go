@@ -24,11 +24,18 @@ func getSafeRedirectPath(value string, fallback string) string {
 
 func renderAuthControls(user *types.CurrentUser, nextPath string) string {
 	if user != nil {
+		inboxLink := ""
+		if user.Role == "admin" {
+			inboxLink = `<a class="btn btn-outline btn-small" href="/admin/contact-requests">Inbox</a>`
+		}
 		return fmt.Sprintf(`
       <div class="auth-row">
         <span>
           Signed in as <strong>%s</strong>
         </span>
+        %s
+        <a class="btn btn-outline btn-small" href="/account">Account</a>
+        <a class="btn btn-outline btn-small" href="/account/security">Security</a>
         <form method="POST" action="/logout" style="display:inline;">
           <input type="hidden" name="next" value="%s" />
           <button type="submit" class="btn btn-outline btn-small">Logout</button>
@@ -36,6 +43,7 @@ func renderAuthControls(user *types.CurrentUser, nextPath string) string {
       </div>
     `,
 			view.EscapeHTML(user.Email),
+			inboxLink,
 			view.EscapeHTML(nextPath),
 		)
 	}
@@ -51,6 +59,13 @@ func renderAuthControls(user *types.CurrentUser, nextPath string) string {
   `, url.QueryEscape(nextPath), url.QueryEscape(nextPath))
 }
 
+func renderNotice(kind, message string) view.SafeHTML {
+	if strings.TrimSpace(message) == "" {
+		return view.Safe("")
+	}
+	return view.Safe(fmt.Sprintf(`<div class="notice notice-%s">%s</div>`, kind, view.EscapeHTML(message)))
+}
+
 func renderPaginationBar(meta utils.PaginationMeta, basePath string, query map[string]string) string {
 	if meta.TotalPages <= 1 {
 		return ""
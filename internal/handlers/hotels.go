@@ -121,8 +121,8 @@ func buildHotelCardHTML(r *http.Request, hotel map[string]any) string {
 	if user != nil && user.Role == "admin" {
 		actions = append(actions, fmt.Sprintf(`<a class="btn btn-outline" href="/hotels/%s/edit">Edit</a>`, hotelID))
 		actions = append(actions, fmt.Sprintf(`
-      <form method="POST" action="/hotels/%s/delete" style="display:inline;">
-        <button class="btn btn-outline" type="submit" onclick="return confirm('Delete this hotel?')">Delete</button>
+      <form method="POST" action="/hotels/%s/delete" style="display:inline;" data-confirm="Delete this hotel?">
+        <button class="btn btn-outline" type="submit">Delete</button>
       </form>
     `, hotelID))
 	}
@@ -278,7 +278,7 @@ func (a *App) renderHotelsPage(w http.ResponseWriter, r *http.Request) error {
 		bookingNotice = `<div class="notice notice-warning">You need an account to book. Redirecting to login...</div>`
 	}
 
-	return a.renderHTML(w, http.StatusOK, "hotels.html", map[string]any{
+	return a.renderHTML(w, r, http.StatusOK, "hotels.html", map[string]any{
 		"q":             q,
 		"cityOptions":   view.Safe(strings.Join(cityOptions, "")),
 		"minPrice":      minPrice,
@@ -295,7 +295,7 @@ func (a *App) renderHotelsPage(w http.ResponseWriter, r *http.Request) error {
 }
 
 func (a *App) renderNewHotelPage(w http.ResponseWriter, r *http.Request) error {
-	return a.renderHTML(w, http.StatusOK, "hotels-new.html", map[string]any{
+	return a.renderHTML(w, r, http.StatusOK, "hotels-new.html", map[string]any{
 		"authControls":    view.Safe(renderAuthControls(session.CurrentUser(r), "/hotels/new")),
 		"errorMessage":    "",
 		"title":           "",
@@ -318,7 +318,7 @@ func (a *App) createHotelFromPage(w http.ResponseWriter, r *http.Request) error
 
 	validationErrors, hotel := utils.ValidateHotelPayload(payload, false)
 	if len(validationErrors) > 0 {
-		return a.renderHTML(w, http.StatusBadRequest, "hotels-new.html", map[string]any{
+		return a.renderHTML(w, r, http.StatusBadRequest, "hotels-new.html", map[string]any{
 			"authControls":    view.Safe(renderAuthControls(session.CurrentUser(r), "/hotels/new")),
 			"errorMessage":    validationErrors[0],
 			"title":           utils.ToTrimmedString(payload["title"]),
@@ -385,8 +385,8 @@ func (a *App) renderHotelDetailsPage(w http.ResponseWriter, r *http.Request) err
 	if user != nil && user.Role == "admin" {
 		manageButtons = fmt.Sprintf(`
       <a href="/hotels/%s/edit" class="btn btn-outline">Edit</a>
-      <form method="POST" action="/hotels/%s/delete" style="display:inline;">
-        <button type="submit" class="btn btn-outline" onclick="return confirm('Delete this hotel?')">Delete</button>
+      <form method="POST" action="/hotels/%s/delete" style="display:inline;" data-confirm="Delete this hotel?">
+        <button type="submit" class="btn btn-outline">Delete</button>
       </form>
     `, hotelID, hotelID)
 	}
@@ -433,7 +433,7 @@ func (a *App) renderHotelDetailsPage(w http.ResponseWriter, r *http.Request) err
 		presenceEnabled = "true"
 	}
 
-	return a.renderHTML(w, http.StatusOK, "hotels-item.html", map[string]any{
+	return a.renderHTML(w, r, http.StatusOK, "hotels-item.html", map[string]any{
 		"id":              hotelID,
 		"title":           stringValue(hotel, "title"),
 		"description":     stringValue(hotel, "description"),
@@ -471,7 +471,7 @@ func (a *App) renderEditHotelPage(w http.ResponseWriter, r *http.Request) error
 	}
 
 	hotelID := objectIDHex(hotel["_id"])
-	return a.renderHTML(w, http.StatusOK, "hotels-edit.html", map[string]any{
+	return a.renderHTML(w, r, http.StatusOK, "hotels-edit.html", map[string]any{
 		"id":              hotelID,
 		"title":           stringValue(hotel, "title"),
 		"description":     stringValue(hotel, "description"),
@@ -500,7 +500,7 @@ func (a *App) updateHotelFromPage(w http.ResponseWriter, r *http.Request) error
 
 	validationErrors, hotel := utils.ValidateHotelPayload(payload, false)
 	if len(validationErrors) > 0 {
-		return a.renderHTML(w, http.StatusBadRequest, "hotels-edit.html", map[string]any{
+		return a.renderHTML(w, r, http.StatusBadRequest, "hotels-edit.html", map[string]any{
 			"id":              id,
 			"title":           utils.ToTrimmedString(payload["title"]),
 			"description":     utils.ToTrimmedString(payload["description"]),
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"easybook/internal/jobs"
+	"easybook/internal/mail"
+	"easybook/internal/models"
+	"easybook/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Job kinds handled by App. Notification deliveries are registered by
+// notify.Dispatcher.
+const (
+	jobProcessWaitlist    = "waitlist.process"
+	jobSendMail           = "mail.send"
+	jobPurgeJobs          = "jobs.purge"
+	jobDeliverBroadcast   = "broadcast.deliver"
+	jobGenerateDataExport = "data_export.generate"
+)
+
+const (
+	jobListPageSize = 25
+	jobListPageMax  = 100
+)
+
+func (a *App) registerJobs() {
+	a.Jobs.Register(jobProcessWaitlist, a.runWaitlistJob)
+	a.Jobs.Register(jobSendMail, a.runMailJob)
+	a.Jobs.Register(jobPurgeJobs, a.runPurgeJobsJob)
+	a.Jobs.Register(jobDeliverBroadcast, a.runBroadcastJob)
+	a.Jobs.Register(jobGenerateDataExport, a.runDataExportJob)
+}
+
+// queueWaitlistProcessing offers the freed rooms to their waitlists in the
+// background. The booking change has already been saved, so a failure to
+// queue is only logged.
+func (a *App) queueWaitlistProcessing(ctx context.Context, roomIDs ...string) {
+	seen := map[string]struct{}{}
+	for _, roomID := range roomIDs {
+		roomID = strings.TrimSpace(roomID)
+		if roomID == "" {
+			continue
+		}
+		if _, exists := seen[roomID]; exists {
+			continue
+		}
+		seen[roomID] = struct{}{}
+
+		err := a.Jobs.Enqueue(ctx, models.Job{Kind: jobProcessWaitlist, Payload: map[string]string{"roomId": roomID}})
+		if err != nil {
+			log.Printf("waitlist processing for room %s not queued: %v", roomID, err)
+		}
+	}
+}
+
+// runWaitlistJob ends the room's expired holds, offers the free nights and
+// queues another run for when the next hold runs out, so an unused hold
+// passes on without waiting for the scheduler.
+func (a *App) runWaitlistJob(ctx context.Context, job models.Job) error {
+	roomID := job.Payload["roomId"]
+	if _, err := a.Store.ReleaseExpiredWaitlistHolds(ctx, roomID); err != nil {
+		return err
+	}
+	if _, err := a.Store.ProcessWaitlistForRoom(ctx, roomID); err != nil {
+		return err
+	}
+
+	until, held, err := a.Store.NextWaitlistHoldExpiry(ctx, roomID)
+	if err != nil || !held {
+		return err
+	}
+	return a.Jobs.Enqueue(ctx, models.Job{
+		Kind:    jobProcessWaitlist,
+		Key:     fmt.Sprintf("%s:%s:%d", jobProcessWaitlist, roomID, until.Unix()),
+		Payload: map[string]string{"roomId": roomID},
+		RunAt:   until,
+	})
+}
+
+// queueMail sends message in the background with retries. key, when not
+// empty, keeps the same mail from being queued twice.
+func (a *App) queueMail(ctx context.Context, key string, message mail.Message) error {
+	job := models.Job{
+		Kind: jobSendMail,
+		Payload: map[string]string{
+			"to":      message.To,
+			"subject": message.Subject,
+			"text":    message.Text,
+			"html":    message.HTML,
+		},
+	}
+	if key != "" {
+		job.Key = jobSendMail + ":" + key
+	}
+	return a.Jobs.Enqueue(ctx, job)
+}
+
+func (a *App) runMailJob(ctx context.Context, job models.Job) error {
+	err := a.Mailer.Send(ctx, mail.Message{
+		To:      job.Payload["to"],
+		Subject: job.Payload["subject"],
+		Text:    job.Payload["text"],
+		HTML:    job.Payload["html"],
+	})
+	if err != nil && !mail.IsTransient(err) {
+		return fmt.Errorf("%w: %v", jobs.ErrPermanent, err)
+	}
+	return err
+}
+
+func (a *App) runPurgeJobsJob(ctx context.Context, job models.Job) error {
+	purged, err := a.Store.PurgeFinishedJobs(ctx, time.Now().AddDate(0, 0, -max(a.Env.JobRetentionDays, 1)))
+	if purged > 0 {
+		log.Printf("Purged %d finished jobs", purged)
+	}
+	return err
+}
+
+func (a *App) listJobsAPI(w http.ResponseWriter, r *http.Request) error {
+	query := r.URL.Query()
+	pagination := utils.GetPagination(query.Get("page"), query.Get("limit"), jobListPageSize, jobListPageMax)
+
+	items, total, err := a.Store.ListJobs(r.Context(), query.Get("status"), query.Get("kind"), pagination.Skip, int64(pagination.Limit))
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidJobQuery) {
+			a.writeJSON(w, http.StatusBadRequest, map[string]string{
+				"error":   "validation_error",
+				"message": strings.TrimPrefix(err.Error(), models.ErrInvalidJobQuery.Error()+": "),
+			})
+			return nil
+		}
+		return err
+	}
+
+	a.writeJSON(w, http.StatusOK, map[string]any{
+		"items": items,
+		"meta":  utils.GetPaginationMeta(total, pagination.Page, pagination.Limit),
+	})
+	return nil
+}
+
+// retryJobAPI moves a dead-lettered job back to the queue.
+func (a *App) retryJobAPI(w http.ResponseWriter, r *http.Request) error {
+	requeued, err := a.Store.RequeueJob(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		return err
+	}
+	if !requeued {
+		a.writeJSON(w, http.StatusNotFound, map[string]string{"error": "Dead job not found"})
+		return nil
+	}
+
+	a.writeJSON(w, http.StatusOK, map[string]bool{"requeued": true})
+	return nil
+}
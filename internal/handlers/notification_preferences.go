@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"easybook/internal/models"
+	"easybook/internal/session"
+	"easybook/internal/view"
+)
+
+var channelLabels = map[string]string{
+	models.ChannelInApp:   "In-app",
+	models.ChannelEmail:   "Email",
+	models.ChannelWebhook: "Webhook",
+	models.ChannelDigest:  "Digest",
+}
+
+type notificationPreferenceRow struct {
+	models.NotificationTypeInfo
+	Channels map[string]bool `json:"channels"`
+}
+
+type notificationChannelInfo struct {
+	Channel   string `json:"channel"`
+	Label     string `json:"label"`
+	Available bool   `json:"available"`
+}
+
+func (a *App) getNotificationPreferencesAPI(w http.ResponseWriter, r *http.Request) error {
+	user := session.CurrentUser(r)
+	rows, err := a.notificationPreferenceRows(r, user.ID)
+	if err != nil {
+		return err
+	}
+
+	a.writeJSON(w, http.StatusOK, map[string]any{
+		"items":    rows,
+		"channels": a.notificationChannelInfo(),
+	})
+	return nil
+}
+
+func (a *App) updateNotificationPreferencesAPI(w http.ResponseWriter, r *http.Request) error {
+	payload, err := a.parsePayload(r)
+	if err != nil {
+		return err
+	}
+
+	user := session.CurrentUser(r)
+	settings, err := parsePreferenceSettings(payload["settings"])
+	if err == nil {
+		err = a.Store.UpdateNotificationPreferences(r.Context(), user.ID, settings)
+	}
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidNotificationPreferences) {
+			a.writeJSON(w, http.StatusBadRequest, map[string]string{
+				"error":   "validation_error",
+				"message": strings.TrimPrefix(err.Error(), models.ErrInvalidNotificationPreferences.Error()+": "),
+			})
+			return nil
+		}
+		return err
+	}
+
+	return a.getNotificationPreferencesAPI(w, r)
+}
+
+func parsePreferenceSettings(value any) (map[string]map[string]bool, error) {
+	raw, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: settings must map notification types to channels", models.ErrInvalidNotificationPreferences)
+	}
+
+	settings := make(map[string]map[string]bool, len(raw))
+	for notificationType, rawChannels := range raw {
+		channels, ok := rawChannels.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%w: channels for %q must be an object", models.ErrInvalidNotificationPreferences, notificationType)
+		}
+		settings[notificationType] = make(map[string]bool, len(channels))
+		for channel, rawEnabled := range channels {
+			enabled, ok := rawEnabled.(bool)
+			if !ok {
+				return nil, fmt.Errorf("%w: %s.%s must be true or false", models.ErrInvalidNotificationPreferences, notificationType, channel)
+			}
+			settings[notificationType][channel] = enabled
+		}
+	}
+	return settings, nil
+}
+
+func (a *App) renderNotificationPreferencesPage(w http.ResponseWriter, r *http.Request) error {
+	notice := ""
+	if r.URL.Query().Get("saved") == "1" {
+		notice = "Your notification settings have been saved."
+	}
+	return a.renderNotificationPreferencesTemplate(w, r, http.StatusOK, notice, "")
+}
+
+// updateNotificationPreferencesFromPage saves every optional checkbox on the
+// page; unchecked boxes are not submitted, so absence means "off".
+func (a *App) updateNotificationPreferencesFromPage(w http.ResponseWriter, r *http.Request) error {
+	payload, err := a.parsePayload(r)
+	if err != nil {
+		return err
+	}
+	checked := scopeList(payload["enabled"])
+
+	user := session.CurrentUser(r)
+	settings := map[string]map[string]bool{}
+	for _, info := range models.NotificationTypes {
+		settings[info.Type] = map[string]bool{}
+		for _, channel := range a.notificationChannelInfo() {
+			if !channel.Available || models.IsMandatoryChannel(info.Type, channel.Channel) {
+				continue
+			}
+			settings[info.Type][channel.Channel] = slices.Contains(checked, info.Type+":"+channel.Channel)
+		}
+	}
+
+	if err := a.Store.UpdateNotificationPreferences(r.Context(), user.ID, settings); err != nil {
+		if errors.Is(err, models.ErrInvalidNotificationPreferences) {
+			message := strings.TrimPrefix(err.Error(), models.ErrInvalidNotificationPreferences.Error()+": ")
+			return a.renderNotificationPreferencesTemplate(w, r, http.StatusBadRequest, "", message)
+		}
+		return err
+	}
+
+	http.Redirect(w, r, "/account/notifications?saved=1", http.StatusFound)
+	return nil
+}
+
+func (a *App) renderNotificationPreferencesTemplate(w http.ResponseWriter, r *http.Request, statusCode int, noticeMessage, errorMessage string) error {
+	user := session.CurrentUser(r)
+	rows, err := a.notificationPreferenceRows(r, user.ID)
+	if err != nil {
+		return err
+	}
+	channels := a.notificationChannelInfo()
+
+	var table strings.Builder
+	table.WriteString(`<table class="preferences-table"><thead><tr><th>Notification</th>`)
+	for _, channel := range channels {
+		label := view.EscapeHTML(channel.Label)
+		if !channel.Available {
+			label += `<br /><small>not available yet</small>`
+		}
+		table.WriteString(`<th>` + label + `</th>`)
+	}
+	table.WriteString(`</tr></thead><tbody>`)
+	for _, row := range rows {
+		label := view.EscapeHTML(row.Label)
+		if row.Transactional {
+			label += `<br /><small>Always sent in-app and by email</small>`
+		}
+		table.WriteString(`<tr><td>` + label + `</td>`)
+		for _, channel := range channels {
+			value := row.Type + ":" + channel.Channel
+			attributes := ""
+			if row.Channels[channel.Channel] {
+				attributes += " checked"
+			}
+			if !channel.Available || models.IsMandatoryChannel(row.Type, channel.Channel) {
+				attributes += " disabled"
+			}
+			table.WriteString(fmt.Sprintf(
+				`<td><input type="checkbox" name="enabled" value="%s" aria-label="%s: %s"%s /></td>`,
+				view.EscapeHTML(value),
+				view.EscapeHTML(row.Label),
+				view.EscapeHTML(channel.Label),
+				attributes,
+			))
+		}
+		table.WriteString(`</tr>`)
+	}
+	table.WriteString(`</tbody></table>`)
+
+	return a.renderHTML(w, r, statusCode, "account-notifications.html", map[string]any{
+		"authControls":     view.Safe(renderAuthControls(user, "/account/notifications")),
+		"noticeMessage":    renderNotice("success", noticeMessage),
+		"errorMessage":     errorMessage,
+		"preferencesTable": view.Safe(table.String()),
+	})
+}
+
+// notificationPreferenceRows resolves the effective setting of every type on
+// every channel, including defaults and mandatory channels.
+func (a *App) notificationPreferenceRows(r *http.Request, userID string) ([]notificationPreferenceRow, error) {
+	preferences, err := a.Store.FindNotificationPreferences(r.Context(), userID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]notificationPreferenceRow, 0, len(models.NotificationTypes))
+	for _, info := range models.NotificationTypes {
+		row := notificationPreferenceRow{NotificationTypeInfo: info, Channels: map[string]bool{}}
+		for _, channel := range models.NotificationChannels {
+			fallback := channel == models.ChannelInApp || a.Dispatcher.EnabledByDefault(info.Type, channel)
+			row.Channels[channel] = preferences.Allows(info.Type, channel, fallback)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func (a *App) notificationChannelInfo() []notificationChannelInfo {
+	channels := make([]notificationChannelInfo, 0, len(models.NotificationChannels))
+	for _, channel := range models.NotificationChannels {
+		channels = append(channels, notificationChannelInfo{
+			Channel:   channel,
+			Label:     channelLabels[channel],
+			Available: channel == models.ChannelInApp || a.Dispatcher.HasChannel(channel),
+		})
+	}
+	return channels
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"easybook/internal/config"
+	"easybook/internal/db"
+	"easybook/internal/models"
+	"easybook/internal/notify"
+	"easybook/internal/session"
+	"easybook/internal/view"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestNotificationPreferences(t *testing.T) {
+	mongoURI := strings.TrimSpace(os.Getenv("MONGO_URI"))
+	if mongoURI == "" {
+		t.Skip("MONGO_URI is not set; skipping integration test")
+	}
+
+	dbName := "easybook_preferences_test_" + primitive.NewObjectID().Hex()
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURI))
+	if err != nil {
+		t.Fatalf("connect mongo: %v", err)
+	}
+	defer func() {
+		_ = client.Disconnect(context.Background())
+	}()
+
+	database := client.Database(dbName)
+	defer func() {
+		_ = database.Drop(context.Background())
+	}()
+
+	if err := db.EnsureStartupMaintenance(ctx, database); err != nil {
+		t.Fatalf("ensure indexes: %v", err)
+	}
+
+	sessions, err := session.NewManager(ctx, database, false, "preferences-integration-secret-123")
+	if err != nil {
+		t.Fatalf("init sessions: %v", err)
+	}
+
+	store := models.NewStore(database)
+	env := config.Env{NotificationEmailTypes: []string{models.NotificationWaitlistAvailable}}
+	app := NewApp(env, store, sessions, view.NewRenderer("../../views"), "../../views")
+	server := httptest.NewServer(app.Router())
+	defer server.Close()
+
+	userIDText, err := store.CreateUser(ctx, "prefs@example.com", "Passw0rd!", "user")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	sessionCookie := createSessionCookieForTests(t, sessions, userIDText, "prefs@example.com", "user")
+	csrfToken := fetchCSRFTokenForTests(t, http.DefaultClient, server.URL, sessionCookie)
+
+	put := func(body string) (int, []byte) {
+		request, _ := http.NewRequest(http.MethodPut, server.URL+"/api/notifications/preferences", bytes.NewBufferString(body))
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set(session.CSRFHeaderName, csrfToken)
+		request.AddCookie(sessionCookie)
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatalf("PUT preferences: %v", err)
+		}
+		defer response.Body.Close()
+		payload, _ := io.ReadAll(response.Body)
+		return response.StatusCode, payload
+	}
+
+	status, body := put(`{"settings":{"booking_confirmed":{"email":false}}}`)
+	if status != http.StatusBadRequest {
+		t.Fatalf("expected transactional email to be mandatory, got %d: %s", status, body)
+	}
+
+	status, body = put(`{"settings":{"waitlist_available":{"email":false,"webhook":true},"general":{"in_app":false}}}`)
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", status, body)
+	}
+	var saved struct {
+		Items []struct {
+			Type     string          `json:"type"`
+			Channels map[string]bool `json:"channels"`
+		} `json:"items"`
+		Channels []notificationChannelInfo `json:"channels"`
+	}
+	if err := json.Unmarshal(body, &saved); err != nil {
+		t.Fatalf("decode preferences: %v", err)
+	}
+	for _, item := range saved.Items {
+		if item.Type == models.NotificationWaitlistAvailable && (item.Channels["email"] || !item.Channels["in_app"] || !item.Channels["webhook"]) {
+			t.Fatalf("unexpected waitlist settings: %+v", item.Channels)
+		}
+		if item.Type == models.NotificationBookingConfirmed && !item.Channels["email"] {
+			t.Fatalf("expected booking confirmations to stay on: %+v", item.Channels)
+		}
+	}
+
+	for _, channel := range saved.Channels {
+		if want := channel.Channel == models.ChannelInApp || channel.Channel == models.ChannelEmail; channel.Available != want {
+			t.Fatalf("expected %s to be available=%v", channel.Channel, want)
+		}
+	}
+
+	if _, err := store.CreateNotification(ctx, userIDText, models.NotificationGeneral, "Muted", "Hidden in-app", ""); err != nil {
+		t.Fatalf("create notification: %v", err)
+	}
+	waitlistID, err := store.CreateNotification(ctx, userIDText, models.NotificationWaitlistAvailable, "Room is available now", "Shown in-app only", "")
+	if err != nil {
+		t.Fatalf("create notification: %v", err)
+	}
+
+	items, _, err := store.ListNotifications(ctx, userIDText, models.NotificationQuery{}, 0, 10)
+	if err != nil {
+		t.Fatalf("list notifications: %v", err)
+	}
+	unread, err := store.CountUnreadNotifications(ctx, userIDText)
+	if err != nil {
+		t.Fatalf("count unread notifications: %v", err)
+	}
+	if len(items) != 1 || unread != 1 || objectIDHex(items[0]["_id"]) != waitlistID {
+		t.Fatalf("expected only the waitlist notification in-app, got %d items, %d unread", len(items), unread)
+	}
+	deliveries, err := database.Collection("jobs").CountDocuments(ctx, bson.M{"kind": notify.DeliverJob})
+	if err != nil {
+		t.Fatalf("count deliveries: %v", err)
+	}
+	if deliveries != 0 {
+		t.Fatalf("expected the email opt-out to skip delivery, got %d deliveries", deliveries)
+	}
+}
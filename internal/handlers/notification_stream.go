@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"easybook/internal/models"
+	"easybook/internal/notify"
+	"easybook/internal/session"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	notificationStreamBatch = 100
+	notificationStreamRetry = 5 * time.Second
+	// notificationStreamOverlap is how far back each sync looks again, for
+	// notifications created on instances with a slightly different clock
+	// or inserted while a sync was running.
+	notificationStreamOverlap = 10 * time.Second
+)
+
+// WatchNotifications switches the stream feed to a Mongo change stream so
+// writes from other instances are delivered too. Without replica set
+// support it keeps the in-process feed set up by NewApp. Call it before the
+// server starts accepting requests.
+func (a *App) WatchNotifications(ctx context.Context) {
+	if err := notify.Watch(ctx, a.Store.NotificationsCollection(), a.Notifications); err != nil {
+		log.Printf("Notification change stream unavailable, using in-process delivery: %v", err)
+		return
+	}
+	a.Store.SetNotificationListener(nil)
+}
+
+// streamNotificationsAPI pushes "notification" events for new notifications,
+// "unread" events when the unread count changes and "changed" events when
+// notifications were read, archived or deleted. Clients resume with the
+// Last-Event-ID header, or the lastEventId query parameter to pick up after
+// the newest item they already loaded; notifications around that one may be
+// sent again.
+func (a *App) streamNotificationsAPI(w http.ResponseWriter, r *http.Request) error {
+	user := session.CurrentUser(r)
+	if user == nil {
+		a.writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Authentication required"})
+		return nil
+	}
+
+	controller := http.NewResponseController(w)
+	// Streams outlive the server's write timeout.
+	_ = controller.SetWriteDeadline(time.Time{})
+
+	// Subscribe before reading the backlog so nothing inserted in between
+	// is missed.
+	changes, unsubscribe := a.Notifications.Subscribe(user.ID)
+	defer unsubscribe()
+
+	stream := &notificationStream{
+		app:         a,
+		w:           w,
+		controller:  controller,
+		userID:      user.ID,
+		sent:        map[string]time.Time{},
+		unreadCount: -1,
+	}
+	lastID := firstNonEmpty(
+		strings.TrimSpace(r.Header.Get("Last-Event-ID")),
+		strings.TrimSpace(r.URL.Query().Get("lastEventId")),
+	)
+	if err := stream.start(r.Context(), lastID); err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", notificationStreamRetry.Milliseconds())
+
+	if err := stream.sync(r.Context(), false); err != nil {
+		return stream.fail(r.Context(), err)
+	}
+
+	ping := time.NewTicker(time.Duration(max(a.Env.NotificationStreamPingSecs, 1)) * time.Second)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return nil
+		case _, ok := <-changes:
+			if !ok {
+				return nil
+			}
+			if err := stream.sync(r.Context(), true); err != nil {
+				return stream.fail(r.Context(), err)
+			}
+		case <-ping.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return nil
+			}
+			if err := controller.Flush(); err != nil {
+				return nil
+			}
+		}
+	}
+}
+
+type notificationStream struct {
+	app        *App
+	w          http.ResponseWriter
+	controller *http.ResponseController
+	userID     string
+	// since is the newest creation time sent so far; sent holds the ids
+	// sent within the overlap before it.
+	since       time.Time
+	sent        map[string]time.Time
+	unreadCount int64
+}
+
+// start sets the point to resume from: the creation time of lastID, or now
+// when the client has nothing yet. In the latter case notifications from
+// just before now count as sent.
+func (s *notificationStream) start(ctx context.Context, lastID string) error {
+	if objectID, err := primitive.ObjectIDFromHex(lastID); err == nil {
+		s.since = objectID.Timestamp()
+		notification, err := s.app.Store.FindNotificationByID(ctx, objectID)
+		if err != nil && !errors.Is(err, models.ErrNotificationNotFound) {
+			return err
+		}
+		if notification != nil && notification.UserID.Hex() == s.userID {
+			s.since = notification.CreatedAt
+		}
+		s.sent[lastID] = s.since
+		return nil
+	}
+
+	s.since = time.Now().UTC()
+	items, err := s.app.Store.ListNotificationsSince(ctx, s.userID, s.since.Add(-notificationStreamOverlap), notificationStreamBatch)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		s.sent[objectIDHex(item["_id"])] = notificationCreatedAt(item)
+	}
+	return nil
+}
+
+// sync sends the notifications not sent yet and the unread count if it
+// changed since the last event. After a change signal that brought no new
+// notification it also sends "changed", so clients reload their list.
+func (s *notificationStream) sync(ctx context.Context, signalled bool) error {
+	from := s.since.Add(-notificationStreamOverlap)
+	sentNew := false
+	for {
+		items, err := s.app.Store.ListNotificationsSince(ctx, s.userID, from, notificationStreamBatch)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			id := objectIDHex(item["_id"])
+			createdAt := notificationCreatedAt(item)
+			if _, done := s.sent[id]; done {
+				continue
+			}
+			if err := s.send(id, "notification", notificationResponse(item)); err != nil {
+				return err
+			}
+			s.sent[id] = createdAt
+			sentNew = true
+			if createdAt.After(s.since) {
+				s.since = createdAt
+			}
+		}
+		if len(items) < notificationStreamBatch {
+			break
+		}
+		next := notificationCreatedAt(items[len(items)-1])
+		if !next.After(from) {
+			break
+		}
+		from = next
+	}
+	for id, createdAt := range s.sent {
+		if createdAt.Before(s.since.Add(-notificationStreamOverlap)) {
+			delete(s.sent, id)
+		}
+	}
+
+	unreadCount, err := s.app.Store.CountUnreadNotifications(ctx, s.userID)
+	if err != nil {
+		return err
+	}
+	if unreadCount != s.unreadCount {
+		if err := s.send("", "unread", map[string]int64{"unreadCount": unreadCount}); err != nil {
+			return err
+		}
+		s.unreadCount = unreadCount
+	}
+	if signalled && !sentNew {
+		if err := s.send("", "changed", map[string]string{}); err != nil {
+			return err
+		}
+	}
+	return s.controller.Flush()
+}
+
+func (s *notificationStream) send(id, event string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	if id != "" {
+		if _, err := fmt.Fprintf(s.w, "id: %s\n", id); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event, data)
+	return err
+}
+
+// fail ends a stream whose headers are already sent; the client reconnects
+// and resumes from the last id it received.
+func (s *notificationStream) fail(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return nil
+	}
+	log.Printf("Notification stream for %s: %v", s.userID, err)
+	return nil
+}
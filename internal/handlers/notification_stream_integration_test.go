@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"easybook/internal/config"
+	"easybook/internal/db"
+	"easybook/internal/models"
+	"easybook/internal/session"
+	"easybook/internal/view"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type sseEvent struct {
+	id    string
+	event string
+	data  string
+}
+
+func readSSEEvent(t *testing.T, reader *bufio.Reader) sseEvent {
+	t.Helper()
+	var event sseEvent
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read stream: %v", err)
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "":
+			if event.event != "" {
+				return event
+			}
+		case strings.HasPrefix(line, "id: "):
+			event.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			event.event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			event.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func TestNotificationStream(t *testing.T) {
+	mongoURI := strings.TrimSpace(os.Getenv("MONGO_URI"))
+	if mongoURI == "" {
+		t.Skip("MONGO_URI is not set; skipping integration test")
+	}
+
+	dbName := "easybook_stream_test_" + primitive.NewObjectID().Hex()
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURI))
+	if err != nil {
+		t.Fatalf("connect mongo: %v", err)
+	}
+	defer func() {
+		_ = client.Disconnect(context.Background())
+	}()
+
+	database := client.Database(dbName)
+	defer func() {
+		_ = database.Drop(context.Background())
+	}()
+
+	if err := db.EnsureStartupMaintenance(ctx, database); err != nil {
+		t.Fatalf("ensure indexes: %v", err)
+	}
+
+	sessions, err := session.NewManager(ctx, database, false, "stream-integration-secret-123")
+	if err != nil {
+		t.Fatalf("init sessions: %v", err)
+	}
+
+	store := models.NewStore(database)
+	app := NewApp(config.Env{NotificationStreamPingSecs: 30}, store, sessions, view.NewRenderer("../../views"), "../../views")
+	server := httptest.NewServer(app.Router())
+	defer server.Close()
+	defer app.Notifications.Close()
+
+	userIDText, err := store.CreateUser(ctx, "stream@example.com", "Passw0rd!", "user")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	firstID, err := store.CreateNotification(ctx, userIDText, models.NotificationGeneral, "Earlier", "Already loaded", "")
+	if err != nil {
+		t.Fatalf("create notification: %v", err)
+	}
+	sessionCookie := createSessionCookieForTests(t, sessions, userIDText, "stream@example.com", "user")
+
+	open := func(lastEventID string) (*http.Response, *bufio.Reader) {
+		request, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/api/notifications/stream", nil)
+		request.AddCookie(sessionCookie)
+		if lastEventID != "" {
+			request.Header.Set("Last-Event-ID", lastEventID)
+		}
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatalf("open stream: %v", err)
+		}
+		if response.StatusCode != http.StatusOK || response.Header.Get("Content-Type") != "text/event-stream" {
+			t.Fatalf("expected an event stream, got %d %q", response.StatusCode, response.Header.Get("Content-Type"))
+		}
+		return response, bufio.NewReader(response.Body)
+	}
+
+	response, reader := open("")
+	if event := readSSEEvent(t, reader); event.event != "unread" || event.data != `{"unreadCount":1}` {
+		t.Fatalf("expected the initial unread count, got %+v", event)
+	}
+
+	secondID, err := store.CreateNotification(ctx, userIDText, models.NotificationGeneral, "Room is available now", "Hello", "/hotels")
+	if err != nil {
+		t.Fatalf("create notification: %v", err)
+	}
+	event := readSSEEvent(t, reader)
+	if event.event != "notification" || event.id != secondID || !strings.Contains(event.data, "Room is available now") {
+		t.Fatalf("expected the new notification, got %+v", event)
+	}
+	if event := readSSEEvent(t, reader); event.event != "unread" || event.data != `{"unreadCount":2}` {
+		t.Fatalf("expected the unread count to grow, got %+v", event)
+	}
+
+	if _, err := store.MarkAllNotificationsRead(ctx, userIDText); err != nil {
+		t.Fatalf("mark read: %v", err)
+	}
+	if event := readSSEEvent(t, reader); event.event != "unread" || event.data != `{"unreadCount":0}` {
+		t.Fatalf("expected the unread count to drop, got %+v", event)
+	}
+	if event := readSSEEvent(t, reader); event.event != "changed" {
+		t.Fatalf("expected a change event after marking read, got %+v", event)
+	}
+	response.Body.Close()
+
+	response, reader = open(firstID)
+	defer response.Body.Close()
+	if event := readSSEEvent(t, reader); event.event != "notification" || event.id != secondID {
+		t.Fatalf("expected the missed notification on resume, got %+v", event)
+	}
+}
@@ -14,12 +14,24 @@ import (
 	"easybook/internal/view"
 
 	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+const (
+	notificationListPageSize = 50
+	notificationListPageMax  = 200
+)
+
 func (a *App) renderNotificationsPage(w http.ResponseWriter, r *http.Request) error {
-	return a.renderHTML(w, http.StatusOK, "notifications.html", map[string]any{
+	typeOptions := []string{renderOption("", "All types", true)}
+	for _, info := range models.NotificationTypes {
+		typeOptions = append(typeOptions, renderOption(info.Type, info.Label, false))
+	}
+
+	return a.renderHTML(w, r, http.StatusOK, "notifications.html", map[string]any{
 		"authControls": view.Safe(renderAuthControls(session.CurrentUser(r), "/notifications")),
+		"typeOptions":  view.Safe(strings.Join(typeOptions, "")),
 	})
 }
 
@@ -63,6 +75,15 @@ func (a *App) subscribeNotificationsAPI(w http.ResponseWriter, r *http.Request)
 		return nil
 	}
 
+	flexibility, flexErr := waitlistFlexibility(payload)
+	if flexErr != nil {
+		a.writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error":   "validation_error",
+			"message": flexErr.Error(),
+		})
+		return nil
+	}
+
 	waitlistID, groupID, subscribeErr := a.Store.SubscribeToWaitlist(
 		r.Context(),
 		user.ID,
@@ -70,6 +91,7 @@ func (a *App) subscribeNotificationsAPI(w http.ResponseWriter, r *http.Request)
 		checkIn,
 		checkOut,
 		waitlistType,
+		flexibility,
 	)
 
 	if subscribeErr != nil {
@@ -87,7 +109,8 @@ func (a *App) subscribeNotificationsAPI(w http.ResponseWriter, r *http.Request)
 		}
 		if errors.Is(subscribeErr, models.ErrInvalidWaitlistPayload) {
 			a.writeJSON(w, http.StatusBadRequest, map[string]string{
-				"error": "validation_error",
+				"error":   "validation_error",
+				"message": strings.TrimPrefix(subscribeErr.Error(), models.ErrInvalidWaitlistPayload.Error()+": "),
 			})
 			return nil
 		}
@@ -115,52 +138,75 @@ func (a *App) getNotificationsAPI(w http.ResponseWriter, r *http.Request) error
 		return nil
 	}
 
-	limit := int64(50)
-	if queryLimit := strings.TrimSpace(r.URL.Query().Get("limit")); queryLimit != "" {
-		if parsed, err := strconv.ParseInt(queryLimit, 10, 64); err == nil && parsed > 0 {
-			limit = parsed
+	query := r.URL.Query()
+	pagination := utils.GetPagination(query.Get("page"), query.Get("limit"), notificationListPageSize, notificationListPageMax)
+
+	items, total, err := a.Store.ListNotifications(r.Context(), user.ID, models.NotificationQuery{
+		Status: query.Get("status"),
+		Type:   query.Get("type"),
+	}, pagination.Skip, int64(pagination.Limit))
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidNotificationQuery) {
+			a.writeJSON(w, http.StatusBadRequest, map[string]string{
+				"error":   "validation_error",
+				"message": strings.TrimPrefix(err.Error(), models.ErrInvalidNotificationQuery.Error()+": "),
+			})
+			return nil
 		}
+		return err
 	}
-
-	items, unreadCount, err := a.Store.ListNotifications(r.Context(), user.ID, limit)
+	unreadCount, err := a.Store.CountUnreadNotifications(r.Context(), user.ID)
 	if err != nil {
 		return err
 	}
 
 	responseItems := make([]map[string]any, 0, len(items))
 	for _, item := range items {
-		createdAt := ""
-		switch typed := item["createdAt"].(type) {
-		case time.Time:
-			createdAt = typed.UTC().Format(time.RFC3339)
-		case primitive.DateTime:
-			createdAt = typed.Time().UTC().Format(time.RFC3339)
-		default:
-			createdAt = strings.TrimSpace(fmt.Sprint(item["createdAt"]))
-		}
-
-		isRead := false
-		if value, ok := item["isRead"].(bool); ok {
-			isRead = value
-		}
-
-		responseItems = append(responseItems, map[string]any{
-			"id":        objectIDHex(item["_id"]),
-			"title":     strings.TrimSpace(utils.ToTrimmedString(item["title"])),
-			"text":      strings.TrimSpace(utils.ToTrimmedString(item["text"])),
-			"link":      strings.TrimSpace(utils.ToTrimmedString(item["link"])),
-			"createdAt": createdAt,
-			"isRead":    isRead,
-		})
+		responseItems = append(responseItems, notificationResponse(item))
 	}
 
 	a.writeJSON(w, http.StatusOK, map[string]any{
 		"items":       responseItems,
+		"meta":        utils.GetPaginationMeta(total, pagination.Page, pagination.Limit),
 		"unreadCount": unreadCount,
 	})
 	return nil
 }
 
+func notificationResponse(item bson.M) map[string]any {
+	createdAt := strings.TrimSpace(fmt.Sprint(item["createdAt"]))
+	if at := notificationCreatedAt(item); !at.IsZero() {
+		createdAt = at.Format(time.RFC3339)
+	}
+
+	isRead := false
+	if value, ok := item["isRead"].(bool); ok {
+		isRead = value
+	}
+	_, archived := item["archivedAt"]
+
+	return map[string]any{
+		"id":        objectIDHex(item["_id"]),
+		"type":      utils.ToTrimmedString(item["type"]),
+		"title":     strings.TrimSpace(utils.ToTrimmedString(item["title"])),
+		"text":      strings.TrimSpace(utils.ToTrimmedString(item["text"])),
+		"link":      strings.TrimSpace(utils.ToTrimmedString(item["link"])),
+		"createdAt": createdAt,
+		"isRead":    isRead,
+		"archived":  archived,
+	}
+}
+
+func notificationCreatedAt(item bson.M) time.Time {
+	switch typed := item["createdAt"].(type) {
+	case time.Time:
+		return typed.UTC()
+	case primitive.DateTime:
+		return typed.Time().UTC()
+	}
+	return time.Time{}
+}
+
 func (a *App) markNotificationReadAPI(w http.ResponseWriter, r *http.Request) error {
 	user := session.CurrentUser(r)
 	if user == nil {
@@ -203,3 +249,75 @@ func (a *App) markAllNotificationsReadAPI(w http.ResponseWriter, r *http.Request
 	})
 	return nil
 }
+
+func (a *App) archiveNotificationAPI(w http.ResponseWriter, r *http.Request) error {
+	user := session.CurrentUser(r)
+	if user == nil {
+		a.writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Authentication required"})
+		return nil
+	}
+
+	if err := a.Store.ArchiveNotification(r.Context(), user.ID, chi.URLParam(r, "id")); err != nil {
+		if errors.Is(err, models.ErrNotificationNotFound) {
+			a.writeJSON(w, http.StatusNotFound, map[string]string{
+				"error":   "not_found",
+				"message": "Notification not found",
+			})
+			return nil
+		}
+		return err
+	}
+
+	a.writeJSON(w, http.StatusOK, map[string]string{"message": "Notification archived"})
+	return nil
+}
+
+func (a *App) deleteNotificationAPI(w http.ResponseWriter, r *http.Request) error {
+	user := session.CurrentUser(r)
+	if user == nil {
+		a.writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Authentication required"})
+		return nil
+	}
+
+	if err := a.Store.DeleteNotification(r.Context(), user.ID, chi.URLParam(r, "id")); err != nil {
+		if errors.Is(err, models.ErrNotificationNotFound) {
+			a.writeJSON(w, http.StatusNotFound, map[string]string{
+				"error":   "not_found",
+				"message": "Notification not found",
+			})
+			return nil
+		}
+		return err
+	}
+
+	a.writeJSON(w, http.StatusOK, map[string]string{"message": "Notification deleted"})
+	return nil
+}
+
+// waitlistFlexibility reads the optional stay lengths of a waitlist request.
+// Leaving both out subscribes to the exact dates.
+func waitlistFlexibility(payload map[string]any) (models.WaitlistFlexibility, error) {
+	var flexibility models.WaitlistFlexibility
+	fields := []struct {
+		name   string
+		target *int
+		raw    string
+	}{
+		{"nights", &flexibility.Nights, utils.ToTrimmedString(payload["nights"])},
+		{"min_nights", &flexibility.MinNights, firstNonEmpty(
+			utils.ToTrimmedString(payload["min_nights"]),
+			utils.ToTrimmedString(payload["minNights"]),
+		)},
+	}
+	for _, field := range fields {
+		if field.raw == "" {
+			continue
+		}
+		value, err := strconv.Atoi(field.raw)
+		if err != nil || value < 1 {
+			return models.WaitlistFlexibility{}, fmt.Errorf("%s must be a positive whole number", field.name)
+		}
+		*field.target = value
+	}
+	return flexibility, nil
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 
+	"easybook/internal/models"
 	"easybook/internal/utils"
 	"easybook/internal/view"
 )
@@ -29,7 +30,7 @@ func (a *App) renderContactPage(w http.ResponseWriter, r *http.Request) error {
 	if r.URL.Query().Get("sent") == "1" {
 		successMessage = "Thanks for reaching out. Our team will contact you shortly."
 	}
-	return a.renderContactTemplate(w, http.StatusOK, successMessage, "", map[string]string{})
+	return a.renderContactTemplate(w, r, http.StatusOK, successMessage, "", map[string]string{})
 }
 
 func (a *App) handleContactForm(w http.ResponseWriter, r *http.Request) error {
@@ -40,10 +41,22 @@ func (a *App) handleContactForm(w http.ResponseWriter, r *http.Request) error {
 
 	cleanPayload, validationErrors := utils.ValidateContactPayload(payload)
 	if len(validationErrors) > 0 {
-		return a.renderContactTemplate(w, http.StatusBadRequest, "", validationErrors[0], cleanPayload)
+		return a.renderContactTemplate(w, r, http.StatusBadRequest, "", validationErrors[0], cleanPayload)
 	}
 
-	_, err = a.Store.CreateContactRequest(r.Context(), cleanPayload)
+	verdict := a.screenSubmission(r, contactForm, payload)
+	if verdict.Blocked {
+		setRetryAfter(w, verdict.RetryAfter)
+		return a.renderContactTemplate(w, r, http.StatusTooManyRequests, "", "You have sent several messages already. Please try again later.", cleanPayload)
+	}
+
+	// Suspicious messages are stored as spam for review instead of being
+	// rejected, so senders get no hint about what tripped the filter.
+	_, err = a.Store.CreateContactRequest(r.Context(), cleanPayload, models.ContactScreening{
+		Spam:    verdict.Spam,
+		Score:   verdict.Score,
+		Reasons: verdict.Reasons,
+	})
 	if err != nil {
 		return err
 	}
@@ -52,7 +65,7 @@ func (a *App) handleContactForm(w http.ResponseWriter, r *http.Request) error {
 	return nil
 }
 
-func (a *App) renderContactTemplate(w http.ResponseWriter, statusCode int, successMessage, errorMessage string, values map[string]string) error {
+func (a *App) renderContactTemplate(w http.ResponseWriter, r *http.Request, statusCode int, successMessage, errorMessage string, values map[string]string) error {
 	successHTML := ""
 	if successMessage != "" {
 		successHTML = fmt.Sprintf(`<div class="notice notice-success">%s</div>`, view.EscapeHTML(successMessage))
@@ -66,7 +79,8 @@ func (a *App) renderContactTemplate(w http.ResponseWriter, statusCode int, succe
 		"cityValue":      values["city"],
 		"emailValue":     values["email"],
 		"messageValue":   values["message"],
+		"antiSpamFields": a.antiSpamFields(contactForm),
 	}
 
-	return a.renderHTML(w, statusCode, "contact.html", replacements)
+	return a.renderHTML(w, r, statusCode, "contact.html", replacements)
 }
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"easybook/internal/mail"
+	"easybook/internal/models"
+	"easybook/internal/session"
+	"easybook/internal/utils"
+)
+
+const passwordResetRequestedMessage = "If an account exists for that email, a reset link has been sent."
+
+func (a *App) renderForgotPasswordPage(w http.ResponseWriter, r *http.Request) error {
+	if session.CurrentUser(r) != nil {
+		http.Redirect(w, r, "/hotels", http.StatusFound)
+		return nil
+	}
+
+	return a.renderHTML(w, r, http.StatusOK, "forgot-password.html", map[string]any{
+		"errorMessage":   "",
+		"successMessage": renderNotice("success", ""),
+		"emailValue":     "",
+	})
+}
+
+func (a *App) requestPasswordReset(w http.ResponseWriter, r *http.Request) error {
+	payload, err := a.parsePayload(r)
+	if err != nil {
+		return err
+	}
+
+	email := strings.ToLower(utils.ToTrimmedString(payload["email"]))
+	if !utils.ValidateEmail(email) {
+		return a.renderHTML(w, r, http.StatusBadRequest, "forgot-password.html", map[string]any{
+			"errorMessage":   "Valid email is required.",
+			"successMessage": renderNotice("success", ""),
+			"emailValue":     email,
+		})
+	}
+
+	user, err := a.Store.FindUserByEmail(r.Context(), email)
+	if err != nil {
+		return err
+	}
+	if user != nil {
+		token, tokenErr := a.Store.CreatePasswordResetToken(r.Context(), user.ID.Hex(), a.passwordResetTTL())
+		if tokenErr != nil {
+			return tokenErr
+		}
+
+		link := a.Env.AppBaseURL + "/reset-password?token=" + url.QueryEscape(token)
+		sendErr := a.queueMail(r.Context(), "", mail.Message{
+			To:      user.Email,
+			Subject: "Reset your Easy Booking password",
+			Text: fmt.Sprintf(
+				"We received a request to reset your password.\n\nOpen this link to choose a new password:\n%s\n\nThe link expires in %d minutes and can be used once. If you did not request a reset, you can ignore this email.\n",
+				link,
+				a.Env.PasswordResetTTLMinutes,
+			),
+		})
+		if sendErr != nil {
+			log.Printf("password reset mail not queued for %s: %v", user.Email, sendErr)
+		}
+	}
+
+	return a.renderHTML(w, r, http.StatusOK, "forgot-password.html", map[string]any{
+		"errorMessage":   "",
+		"successMessage": renderNotice("success", passwordResetRequestedMessage),
+		"emailValue":     "",
+	})
+}
+
+func (a *App) renderResetPasswordPage(w http.ResponseWriter, r *http.Request) error {
+	token := strings.TrimSpace(r.URL.Query().Get("token"))
+	user, err := a.Store.FindPasswordResetUser(r.Context(), token)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidPasswordResetToken) {
+			return a.renderInvalidResetToken(w, r)
+		}
+		return err
+	}
+
+	return a.renderHTML(w, r, http.StatusOK, "reset-password.html", map[string]any{
+		"token":        token,
+		"emailValue":   user.Email,
+		"errorMessage": "",
+	})
+}
+
+func (a *App) resetPassword(w http.ResponseWriter, r *http.Request) error {
+	payload, err := a.parsePayload(r)
+	if err != nil {
+		return err
+	}
+
+	token := utils.ToTrimmedString(payload["token"])
+	user, err := a.Store.FindPasswordResetUser(r.Context(), token)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidPasswordResetToken) {
+			return a.renderInvalidResetToken(w, r)
+		}
+		return err
+	}
+
+	validationErrors, password := utils.ValidateNewPasswordPayload(payload, user.Email)
+	if len(validationErrors) > 0 {
+		return a.renderHTML(w, r, http.StatusBadRequest, "reset-password.html", map[string]any{
+			"token":        token,
+			"emailValue":   user.Email,
+			"errorMessage": validationErrors[0],
+		})
+	}
+
+	user, err = a.Store.ConsumePasswordResetToken(r.Context(), token)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidPasswordResetToken) {
+			return a.renderInvalidResetToken(w, r)
+		}
+		return err
+	}
+
+	if err := a.Store.UpdateUserPassword(r.Context(), user.ID.Hex(), password); err != nil {
+		// Let the user retry with the same link.
+		if releaseErr := a.Store.ReleasePasswordResetToken(r.Context(), token); releaseErr != nil {
+			log.Printf("Release password reset token for %s: %v", user.ID.Hex(), releaseErr)
+		}
+		return err
+	}
+	if _, err := a.Sessions.DestroyUserSessions(r.Context(), user.ID.Hex()); err != nil {
+		return err
+	}
+	if err := a.Store.ClearLoginFailures(r.Context(), models.LoginAccountKey(user.Email)); err != nil {
+		return err
+	}
+	a.Sessions.DestroySession(w, r)
+
+	http.Redirect(w, r, "/login?reset=1", http.StatusFound)
+	return nil
+}
+
+func (a *App) renderInvalidResetToken(w http.ResponseWriter, r *http.Request) error {
+	return a.renderHTML(w, r, http.StatusBadRequest, "forgot-password.html", map[string]any{
+		"errorMessage":   "This reset link is invalid or has expired. Request a new one below.",
+		"successMessage": renderNotice("success", ""),
+		"emailValue":     "",
+	})
+}
+
+func (a *App) passwordResetTTL() time.Duration {
+	return time.Duration(a.Env.PasswordResetTTLMinutes) * time.Minute
+}
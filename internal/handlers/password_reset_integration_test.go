@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"easybook/internal/config"
+	"easybook/internal/db"
+	"easybook/internal/mail"
+	"easybook/internal/models"
+	"easybook/internal/session"
+	"easybook/internal/view"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type recordingMailSender struct {
+	mu       sync.Mutex
+	messages []mail.Message
+}
+
+func (s *recordingMailSender) Send(ctx context.Context, message mail.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append(s.messages, message)
+	return nil
+}
+
+func (s *recordingMailSender) last() (mail.Message, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.messages) == 0 {
+		return mail.Message{}, false
+	}
+	return s.messages[len(s.messages)-1], true
+}
+
+func TestPasswordResetFlowRevokesSessions(t *testing.T) {
+	mongoURI := strings.TrimSpace(os.Getenv("MONGO_URI"))
+	if mongoURI == "" {
+		t.Skip("MONGO_URI is not set; skipping integration test")
+	}
+
+	dbName := "easybook_reset_test_" + primitive.NewObjectID().Hex()
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURI))
+	if err != nil {
+		t.Fatalf("connect mongo: %v", err)
+	}
+	defer func() {
+		_ = client.Disconnect(context.Background())
+	}()
+
+	database := client.Database(dbName)
+	defer func() {
+		_ = database.Drop(context.Background())
+	}()
+
+	if err := db.EnsureStartupMaintenance(ctx, database); err != nil {
+		t.Fatalf("ensure indexes: %v", err)
+	}
+
+	sessions, err := session.NewManager(ctx, database, false, "reset-integration-secret-123")
+	if err != nil {
+		t.Fatalf("init sessions: %v", err)
+	}
+
+	store := models.NewStore(database)
+	userID, err := store.CreateUser(ctx, "reset@example.com", "OldPassw0rd!", "user")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	env := config.Env{AppBaseURL: "http://easybook.test", PasswordResetTTLMinutes: 30}
+	app := NewApp(env, store, sessions, view.NewRenderer("../../views"), "../../views")
+	mailer := &recordingMailSender{}
+	app.Mailer = mailer
+	server := httptest.NewServer(app.Router())
+	defer server.Close()
+
+	existingSession := createSessionCookieForTests(t, sessions, userID, "reset@example.com", "user")
+	httpClient := newPresenceTestHTTPClient()
+	csrfToken := fetchCSRFTokenForTests(t, httpClient, server.URL)
+
+	response, err := httpClient.PostForm(server.URL+"/forgot-password", url.Values{"email": {"reset@example.com"}, "_csrf": {csrfToken}})
+	if err != nil {
+		t.Fatalf("request reset: %v", err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("expected forgot-password status 200, got %d", response.StatusCode)
+	}
+
+	app.Jobs.Drain(ctx)
+	message, ok := mailer.last()
+	if !ok {
+		t.Fatal("expected a reset email to be sent")
+	}
+	tokenStart := strings.Index(message.Text, "token=")
+	if tokenStart < 0 {
+		t.Fatalf("reset email does not contain a token: %q", message.Text)
+	}
+	token := strings.Fields(message.Text[tokenStart+len("token="):])[0]
+	token, _ = url.QueryUnescape(token)
+
+	resetForm := url.Values{
+		"token":           {token},
+		"password":        {"NewPassw0rd!"},
+		"confirmPassword": {"NewPassw0rd!"},
+		"_csrf":           {csrfToken},
+	}
+	response, err = httpClient.PostForm(server.URL+"/reset-password", resetForm)
+	if err != nil {
+		t.Fatalf("reset password: %v", err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusFound || !strings.HasPrefix(response.Header.Get("Location"), "/login") {
+		t.Fatalf("expected redirect to login, got %d %s", response.StatusCode, response.Header.Get("Location"))
+	}
+
+	remaining, err := database.Collection("sessions").CountDocuments(ctx, bson.M{"userId": userID})
+	if err != nil {
+		t.Fatalf("count sessions: %v", err)
+	}
+	if remaining != 0 {
+		t.Fatalf("expected all sessions to be revoked, got %d", remaining)
+	}
+
+	request, _ := http.NewRequest(http.MethodGet, server.URL+"/api/auth/session", nil)
+	request.AddCookie(existingSession)
+	sessionResponse, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("session status: %v", err)
+	}
+	sessionResponse.Body.Close()
+	if sessionResponse.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected old session to be rejected, got %d", sessionResponse.StatusCode)
+	}
+
+	response, err = httpClient.PostForm(server.URL+"/reset-password", resetForm)
+	if err != nil {
+		t.Fatalf("reuse reset token: %v", err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected reused token to be rejected, got %d", response.StatusCode)
+	}
+}
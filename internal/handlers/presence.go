@@ -4,17 +4,18 @@ import (
 	"crypto/rand"
 	"errors"
 	"fmt"
-	"net"
+	"log"
 	"net/http"
 	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"easybook/internal/models"
+	"easybook/internal/ratelimit"
 	"easybook/internal/session"
+	"easybook/internal/utils"
 	"easybook/internal/view"
 
 	"github.com/go-chi/chi/v5"
@@ -25,46 +26,6 @@ const presenceCookieName = "presence_token"
 
 var presenceTokenPattern = regexp.MustCompile(`^[a-f0-9]{8}-[a-f0-9]{4}-[a-f0-9]{4}-[a-f0-9]{4}-[a-f0-9]{12}$`)
 
-var globalPresenceRequestLimiter = newPresenceRequestLimiter()
-
-type presenceRequestLimiter struct {
-	mu   sync.Mutex
-	last map[string]time.Time
-}
-
-func newPresenceRequestLimiter() *presenceRequestLimiter {
-	return &presenceRequestLimiter{
-		last: map[string]time.Time{},
-	}
-}
-
-func (l *presenceRequestLimiter) Allow(key string, minInterval time.Duration) bool {
-	key = strings.TrimSpace(key)
-	if key == "" || minInterval <= 0 {
-		return true
-	}
-
-	now := time.Now().UTC()
-	l.mu.Lock()
-	defer l.mu.Unlock()
-
-	if previous, exists := l.last[key]; exists && now.Sub(previous) < minInterval {
-		return false
-	}
-	l.last[key] = now
-
-	if len(l.last) > 10000 {
-		cutoff := now.Add(-10 * minInterval)
-		for itemKey, itemTime := range l.last {
-			if itemTime.Before(cutoff) {
-				delete(l.last, itemKey)
-			}
-		}
-	}
-
-	return true
-}
-
 func (a *App) renderHotelWaitPage(w http.ResponseWriter, r *http.Request) error {
 	hotelID := strings.TrimSpace(r.URL.Query().Get("hotelId"))
 	if _, err := primitive.ObjectIDFromHex(hotelID); err != nil {
@@ -92,7 +53,7 @@ func (a *App) renderHotelWaitPage(w http.ResponseWriter, r *http.Request) error
 		return err
 	}
 
-	return a.renderHTML(w, http.StatusOK, "hotel-wait.html", map[string]any{
+	return a.renderHTML(w, r, http.StatusOK, "hotel-wait.html", map[string]any{
 		"authControls": view.Safe(renderAuthControls(session.CurrentUser(r), "/hotel-wait?hotelId="+url.QueryEscape(hotelID))),
 		"hotelId":      hotelID,
 		"hotelTitle":   defaultIfEmpty(stringValue(hotel, "title"), "Selected hotel"),
@@ -115,7 +76,7 @@ func (a *App) getHotelPresenceStatusAPI(w http.ResponseWriter, r *http.Request)
 	}
 
 	rateKey := "status:" + hotelID + ":" + presenceClientKey(r)
-	if !globalPresenceRequestLimiter.Allow(rateKey, a.presenceMinInterval()) {
+	if !a.allowPresenceRequest(r, rateKey) {
 		a.writeJSON(w, http.StatusTooManyRequests, map[string]any{
 			"error":   "rate_limited",
 			"message": "Too many status requests. Please wait a moment.",
@@ -160,7 +121,7 @@ func (a *App) heartbeatHotelPresenceAPI(w http.ResponseWriter, r *http.Request)
 	}
 
 	rateKey := "heartbeat:" + hotelID + ":" + token
-	if !globalPresenceRequestLimiter.Allow(rateKey, a.presenceMinInterval()) {
+	if !a.allowPresenceRequest(r, rateKey) {
 		a.writeJSON(w, http.StatusTooManyRequests, map[string]any{
 			"ok":      false,
 			"reason":  "rate_limited",
@@ -304,34 +265,17 @@ func (a *App) presenceMinInterval() time.Duration {
 	return time.Duration(a.Env.PresenceMinIntervalSeconds) * time.Second
 }
 
-func presenceClientKey(r *http.Request) string {
-	if r == nil {
-		return "unknown"
-	}
-
-	if forwarded := strings.TrimSpace(r.Header.Get("X-Forwarded-For")); forwarded != "" {
-		parts := strings.Split(forwarded, ",")
-		if len(parts) > 0 {
-			value := strings.TrimSpace(parts[0])
-			if value != "" {
-				return value
-			}
-		}
-	}
-	if realIP := strings.TrimSpace(r.Header.Get("X-Real-Ip")); realIP != "" {
-		return realIP
-	}
-
-	remoteAddr := strings.TrimSpace(r.RemoteAddr)
-	if remoteAddr == "" {
-		return "unknown"
-	}
-	host, _, err := net.SplitHostPort(remoteAddr)
+// allowPresenceRequest enforces the minimum interval between presence calls
+// for one key as a bucket of size one.
+func (a *App) allowPresenceRequest(r *http.Request, key string) bool {
+	result, err := a.RateLimits.Take(r.Context(), "presence:"+key, ratelimit.Limit{Requests: 1, Period: a.presenceMinInterval()})
 	if err != nil {
-		return remoteAddr
-	}
-	if strings.TrimSpace(host) == "" {
-		return remoteAddr
+		log.Printf("presence rate limit unavailable: %v", err)
+		return true
 	}
-	return host
+	return result.Allowed
+}
+
+func presenceClientKey(r *http.Request) string {
+	return utils.ClientIP(r)
 }
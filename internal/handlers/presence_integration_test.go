@@ -157,7 +157,10 @@ func TestHotelPresenceHeartbeatExtendsTTL(t *testing.T) {
 
 	time.Sleep(1100 * time.Millisecond)
 
-	heartbeatResponse, err := client1.Post(server.URL+"/api/hotels/"+hotelID+"/presence/heartbeat", "application/json", strings.NewReader("{}"))
+	heartbeatRequest, _ := http.NewRequest(http.MethodPost, server.URL+"/api/hotels/"+hotelID+"/presence/heartbeat", strings.NewReader("{}"))
+	heartbeatRequest.Header.Set("Content-Type", "application/json")
+	heartbeatRequest.Header.Set(session.CSRFHeaderName, fetchCSRFTokenForTests(t, client1, server.URL))
+	heartbeatResponse, err := client1.Do(heartbeatRequest)
 	if err != nil {
 		t.Fatalf("heartbeat request failed: %v", err)
 	}
@@ -4,7 +4,10 @@ import (
 	"net/http"
 	"net/url"
 
+	"easybook/internal/config"
 	"easybook/internal/middleware"
+	"easybook/internal/models"
+	"easybook/internal/ratelimit"
 
 	"github.com/go-chi/chi/v5"
 )
@@ -12,9 +15,16 @@ import (
 func (a *App) Router() http.Handler {
 	r := chi.NewRouter()
 	r.Use(middleware.Recoverer)
+	r.Use(middleware.ClientAddress(a.Env.TrustedProxies))
+	r.Use(middleware.SecurityHeaders(a.securityPolicy(middleware.PageSecurityPolicy())))
 	r.Use(middleware.RequestLogger)
 	r.Use(middleware.StaticMiddleware("public"))
 	r.Use(a.Sessions.Middleware)
+	r.Use(middleware.RateLimit(a.RateLimits, middleware.RateLimitRule{Name: "default", Limit: rateLimit(a.Env.RateLimitDefault)}))
+	r.Use(middleware.CSRFProtect)
+	r.Use(middleware.RequireTwoFactorForRoles(a.Env.TOTPRequiredRoles))
+
+	authLimit := middleware.RateLimit(a.RateLimits, middleware.RateLimitRule{Name: "auth", Limit: rateLimit(a.Env.RateLimitAuth), ByIP: true})
 
 	r.Get("/", a.withError(a.renderHomePage))
 	r.Get("/about", a.withError(a.renderAboutPage))
@@ -30,10 +40,42 @@ func (a *App) Router() http.Handler {
 	})
 
 	r.Get("/login", a.withError(a.renderLoginPage))
-	r.Post("/login", a.withError(a.login))
+	r.With(authLimit).Post("/login", a.withError(a.login))
+	r.Get("/login/2fa", a.withError(a.renderLoginTwoFactorPage))
+	r.With(authLimit).Post("/login/2fa", a.withError(a.loginTwoFactor))
+	r.With(authLimit).Post("/login/sso/{provider}", a.withError(a.startSSOLogin))
+	r.Get("/login/sso/{provider}/callback", a.withError(a.ssoCallback))
 	r.Get("/register", a.withError(a.renderRegisterPage))
-	r.Post("/register", a.withError(a.register))
+	r.With(authLimit).Post("/register", a.withError(a.register))
 	r.Post("/logout", a.withError(a.logout))
+	r.Get("/forgot-password", a.withError(a.renderForgotPasswordPage))
+	r.With(authLimit).Post("/forgot-password", a.withError(a.requestPasswordReset))
+	r.Get("/reset-password", a.withError(a.renderResetPasswordPage))
+	r.With(authLimit).Post("/reset-password", a.withError(a.resetPassword))
+	r.Get("/verify-email", a.withError(a.renderVerifyEmailPage))
+	r.With(middleware.RequireAuth).Post("/verify-email/resend", a.withError(a.resendVerificationEmail))
+
+	r.Group(func(account chi.Router) {
+		account.Use(middleware.RequireAuth)
+		account.Get("/account", a.withError(a.renderAccountPage))
+		account.Post("/account/profile", a.withError(a.updateProfileFromPage))
+		account.Post("/account/email", a.withError(a.changeEmailFromPage))
+		account.Post("/account/password", a.withError(a.changePasswordFromPage))
+		account.Post("/account/delete", a.withError(a.deleteAccountFromPage))
+		account.Get("/account/security", a.withError(a.renderAccountSecurityPage))
+		account.Post("/account/2fa/setup", a.withError(a.setupTwoFactor))
+		account.Post("/account/2fa/enable", a.withError(a.enableTwoFactor))
+		account.Post("/account/2fa/verify", a.withError(a.verifyTwoFactorSession))
+		account.Post("/account/2fa/recovery-codes", a.withError(a.regenerateRecoveryCodes))
+		account.Post("/account/2fa/disable", a.withError(a.disableTwoFactor))
+		account.Get("/account/tokens", a.withError(a.renderAPITokensPage))
+		account.Post("/account/tokens", a.withError(a.createAPITokenFromPage))
+		account.Post("/account/tokens/{id}/revoke", a.withError(a.revokeAPITokenFromPage))
+		account.Get("/account/notifications", a.withError(a.renderNotificationPreferencesPage))
+		account.Post("/account/notifications", a.withError(a.updateNotificationPreferencesFromPage))
+		account.Get("/account/waitlist", a.withError(a.renderWaitlistPage))
+		account.Post("/account/waitlist/{id}/cancel", a.withError(a.cancelWaitlistFromPage))
+	})
 
 	r.Get("/hotels", a.withError(a.renderHotelsPage))
 	r.Group(func(admin chi.Router) {
@@ -43,6 +85,14 @@ func (a *App) Router() http.Handler {
 		admin.Get("/hotels/{id}/edit", a.withError(a.renderEditHotelPage))
 		admin.Post("/hotels/{id}", a.withError(a.updateHotelFromPage))
 		admin.Post("/hotels/{id}/delete", a.withError(a.deleteHotelFromPage))
+		admin.Get("/admin/contact-requests", a.withError(a.renderContactInboxPage))
+		admin.Get("/admin/contact-requests/{id}", a.withError(a.renderContactRequestPage))
+		admin.Post("/admin/contact-requests/{id}/status", a.withError(a.updateContactStatusFromPage))
+		admin.Post("/admin/contact-requests/{id}/assign", a.withError(a.assignContactRequestFromPage))
+		admin.Post("/admin/contact-requests/{id}/notes", a.withError(a.addContactNoteFromPage))
+		admin.Post("/admin/contact-requests/{id}/reply", a.withError(a.replyToContactRequestFromPage))
+		admin.Get("/admin/broadcasts", a.withError(a.renderBroadcastsPage))
+		admin.Post("/admin/broadcasts", a.withError(a.createBroadcastFromPage))
 	})
 	r.Get("/hotels/{id}", a.withError(a.renderHotelDetailsPage))
 	r.With(middleware.RequireAuth).Post("/hotels/{id}/rate", a.withError(a.rateHotelFromPage))
@@ -50,8 +100,8 @@ func (a *App) Router() http.Handler {
 	r.Group(func(protected chi.Router) {
 		protected.Use(middleware.RequireAuth)
 		protected.Get("/bookings", a.withError(a.renderBookingsPage))
-		protected.Get("/bookings/new", a.withError(a.renderNewBookingPage))
-		protected.Post("/bookings", a.withError(a.createBookingFromPage))
+		protected.With(middleware.RequireVerifiedEmail).Get("/bookings/new", a.withError(a.renderNewBookingPage))
+		protected.With(middleware.RequireVerifiedEmail).Post("/bookings", a.withError(a.createBookingFromPage))
 		protected.Get("/bookings/{id}", a.withError(a.renderBookingDetailsPage))
 		protected.Get("/bookings/{id}/edit", a.withError(a.renderEditBookingPage))
 		protected.Post("/bookings/{id}", a.withError(a.updateBookingFromPage))
@@ -59,7 +109,27 @@ func (a *App) Router() http.Handler {
 	})
 
 	r.Route("/api", func(api chi.Router) {
+		api.Use(middleware.SecurityHeaders(a.securityPolicy(middleware.APISecurityPolicy())))
+		api.Use(middleware.RateLimit(a.RateLimits, middleware.RateLimitRule{Name: "api", Limit: rateLimit(a.Env.RateLimitAPI)}))
+		api.Post("/csp-report", a.withError(a.reportCSPViolation))
 		api.Get("/auth/session", a.withError(a.getSessionStatusAPI))
+		api.Group(func(account chi.Router) {
+			account.Use(middleware.RequireSessionAuth)
+			account.Get("/account", a.withError(a.getAccountAPI))
+			account.Put("/account", a.withError(a.updateAccountAPI))
+			account.Post("/account/password", a.withError(a.changePasswordAPI))
+			account.Post("/account/email", a.withError(a.changeEmailAPI))
+			account.Delete("/account", a.withError(a.deleteAccountAPI))
+			account.Post("/me/export", a.withError(a.exportUserDataAPI))
+			account.Get("/me/export/{id}", a.withError(a.getDataExportAPI))
+			account.Get("/me/export/{id}/download", a.withError(a.downloadDataExportAPI))
+			account.Get("/auth/sessions", a.withError(a.listSessionsAPI))
+			account.Delete("/auth/sessions", a.withError(a.revokeOtherSessionsAPI))
+			account.Delete("/auth/sessions/{id}", a.withError(a.revokeSessionAPI))
+			account.Get("/auth/tokens", a.withError(a.listAPITokensAPI))
+			account.Post("/auth/tokens", a.withError(a.createAPITokenAPI))
+			account.Delete("/auth/tokens/{id}", a.withError(a.revokeAPITokenAPI))
+		})
 		api.Get("/hotels", a.withError(a.getHotelsAPI))
 		api.Get("/hotels/{id}", a.withError(a.getHotelByIDAPI))
 		api.Get("/hotels/{id}/presence/status", a.withError(a.getHotelPresenceStatusAPI))
@@ -67,26 +137,63 @@ func (a *App) Router() http.Handler {
 
 		api.Group(func(admin chi.Router) {
 			admin.Use(middleware.RequireRole("admin"))
-			admin.Post("/hotels", a.withError(a.createHotelAPI))
-			admin.Put("/hotels/{id}", a.withError(a.updateHotelAPI))
-			admin.Delete("/hotels/{id}", a.withError(a.deleteHotelAPI))
+			admin.With(middleware.RequireScope(models.ScopeHotelsWrite)).Post("/hotels", a.withError(a.createHotelAPI))
+			admin.With(middleware.RequireScope(models.ScopeHotelsWrite)).Put("/hotels/{id}", a.withError(a.updateHotelAPI))
+			admin.With(middleware.RequireScope(models.ScopeHotelsWrite)).Delete("/hotels/{id}", a.withError(a.deleteHotelAPI))
+			admin.With(middleware.RequireSessionAuth).Delete("/admin/users/{id}/sessions", a.withError(a.forceLogoutUserAPI))
+			admin.Group(func(inbox chi.Router) {
+				inbox.Use(middleware.RequireSessionAuth)
+				inbox.Get("/admin/contact-requests", a.withError(a.listContactRequestsAPI))
+				inbox.Get("/admin/contact-requests/{id}", a.withError(a.getContactRequestAPI))
+				inbox.Post("/admin/contact-requests/{id}/status", a.withError(a.updateContactStatusAPI))
+				inbox.Post("/admin/contact-requests/{id}/assign", a.withError(a.assignContactRequestAPI))
+				inbox.Post("/admin/contact-requests/{id}/notes", a.withError(a.addContactNoteAPI))
+				inbox.Post("/admin/contact-requests/{id}/reply", a.withError(a.replyToContactRequestAPI))
+			})
+			admin.With(middleware.RequireSessionAuth).Get("/admin/jobs", a.withError(a.listJobsAPI))
+			admin.With(middleware.RequireSessionAuth).Post("/admin/jobs/{id}/retry", a.withError(a.retryJobAPI))
+			admin.Group(func(broadcasts chi.Router) {
+				broadcasts.Use(middleware.RequireSessionAuth)
+				broadcasts.Get("/admin/broadcasts", a.withError(a.listBroadcastsAPI))
+				broadcasts.Post("/admin/broadcasts", a.withError(a.createBroadcastAPI))
+				broadcasts.Get("/admin/broadcasts/{id}", a.withError(a.getBroadcastAPI))
+			})
 		})
-		api.With(middleware.RequireAuth).Post("/hotels/{id}/rate", a.withError(a.rateHotelAPI))
+		api.With(middleware.RequireSessionAuth).Post("/hotels/{id}/rate", a.withError(a.rateHotelAPI))
 
 		api.Group(func(protected chi.Router) {
 			protected.Use(middleware.RequireAuth)
-			protected.Get("/bookings/fallback", a.withError(a.getFallbackBookingByGroupIDAPI))
-			protected.Get("/bookings/availability", a.withError(a.getBookingAvailabilityAPI))
-			protected.Get("/bookings", a.withError(a.getBookingsAPI))
-			protected.Get("/bookings/{id}", a.withError(a.getBookingByIDAPI))
-			protected.Post("/bookings", a.withError(a.createBookingAPI))
-			protected.Put("/bookings/{id}", a.withError(a.updateBookingAPI))
-			protected.Delete("/bookings/{id}", a.withError(a.deleteBookingAPI))
-
-			protected.Post("/notifications/subscribe", a.withError(a.subscribeNotificationsAPI))
-			protected.Get("/notifications", a.withError(a.getNotificationsAPI))
-			protected.Post("/notifications/read-all", a.withError(a.markAllNotificationsReadAPI))
-			protected.Post("/notifications/{id}/read", a.withError(a.markNotificationReadAPI))
+			protected.Group(func(read chi.Router) {
+				read.Use(middleware.RequireScope(models.ScopeBookingsRead))
+				read.Get("/bookings/fallback", a.withError(a.getFallbackBookingByGroupIDAPI))
+				read.Get("/bookings/availability", a.withError(a.getBookingAvailabilityAPI))
+				read.Get("/bookings", a.withError(a.getBookingsAPI))
+				read.Get("/bookings/{id}", a.withError(a.getBookingByIDAPI))
+			})
+			protected.Group(func(write chi.Router) {
+				write.Use(middleware.RequireScope(models.ScopeBookingsWrite))
+				write.With(middleware.RequireVerifiedEmail).Post("/bookings", a.withError(a.createBookingAPI))
+				write.Put("/bookings/{id}", a.withError(a.updateBookingAPI))
+				write.Delete("/bookings/{id}", a.withError(a.deleteBookingAPI))
+			})
+
+			protected.Group(func(read chi.Router) {
+				read.Use(middleware.RequireScope(models.ScopeNotificationsRead))
+				read.Get("/notifications", a.withError(a.getNotificationsAPI))
+				read.Get("/notifications/stream", a.withError(a.streamNotificationsAPI))
+				read.Get("/notifications/preferences", a.withError(a.getNotificationPreferencesAPI))
+				read.Get("/waitlist", a.withError(a.getWaitlistAPI))
+			})
+			protected.Group(func(write chi.Router) {
+				write.Use(middleware.RequireScope(models.ScopeNotificationsWrite))
+				write.With(middleware.RequireVerifiedEmail).Post("/notifications/subscribe", a.withError(a.subscribeNotificationsAPI))
+				write.Post("/notifications/read-all", a.withError(a.markAllNotificationsReadAPI))
+				write.Put("/notifications/preferences", a.withError(a.updateNotificationPreferencesAPI))
+				write.Post("/notifications/{id}/read", a.withError(a.markNotificationReadAPI))
+				write.Post("/notifications/{id}/archive", a.withError(a.archiveNotificationAPI))
+				write.Delete("/notifications/{id}", a.withError(a.deleteNotificationAPI))
+				write.Delete("/waitlist/{id}", a.withError(a.deleteWaitlistAPI))
+			})
 		})
 	})
 
@@ -94,3 +201,7 @@ func (a *App) Router() http.Handler {
 
 	return r
 }
+
+func rateLimit(limit config.RateLimit) ratelimit.Limit {
+	return ratelimit.Limit{Requests: limit.Requests, Period: limit.Period}
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"easybook/internal/models"
+	"easybook/internal/scheduler"
+)
+
+// newScheduler registers the periodic jobs. Every job is idempotent, so it is
+// fine for several server instances to run them.
+func (a *App) newScheduler() *scheduler.Scheduler {
+	interval := time.Duration(a.Env.SchedulerIntervalMinutes) * time.Minute
+	tasks := scheduler.New()
+
+	tasks.Add(scheduler.Task{
+		Name:     "booking reminders",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			sent, err := a.Store.SendBookingReminders(ctx, a.Env.BookingReminderDays)
+			if sent > 0 {
+				log.Printf("Sent %d booking reminders", sent)
+			}
+			return err
+		},
+	})
+	if a.Env.ReviewRequestAfterDays > 0 {
+		tasks.Add(scheduler.Task{
+			Name:     "review requests",
+			Interval: interval,
+			Run: func(ctx context.Context) error {
+				sent, err := a.Store.SendReviewRequests(ctx, a.Env.ReviewRequestAfterDays)
+				if sent > 0 {
+					log.Printf("Sent %d review requests", sent)
+				}
+				return err
+			},
+		})
+	}
+	tasks.Add(scheduler.Task{
+		Name:     "waitlist expiry",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			expired, err := a.Store.ExpireWaitlistEntries(ctx)
+			if expired > 0 {
+				log.Printf("Expired %d waitlist subscriptions", expired)
+			}
+			return err
+		},
+	})
+	// Holds normally pass on through the job queued for their expiry; this
+	// catches the ones whose job was lost.
+	tasks.Add(scheduler.Task{
+		Name:     "waitlist holds",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			rooms, err := a.Store.ReleaseExpiredWaitlistHolds(ctx, "")
+			if len(rooms) > 0 {
+				log.Printf("Released expired waitlist holds on %d rooms", len(rooms))
+				a.queueWaitlistProcessing(ctx, rooms...)
+			}
+			return err
+		},
+	})
+	// Notifications pick up their purge time when they are read; this covers
+	// the ones read before retention was turned on, so once a day is enough.
+	if a.Env.NotificationRetentionDays > 0 {
+		tasks.Add(scheduler.Task{
+			Name:     "notification retention",
+			Interval: 24 * time.Hour,
+			Run: func(ctx context.Context) error {
+				scheduled, err := a.Store.ScheduleNotificationPurges(ctx)
+				if scheduled > 0 {
+					log.Printf("Scheduled %d read notifications for purging", scheduled)
+				}
+				return err
+			},
+		})
+	}
+	// The daily key lets only one instance queue the purge each day.
+	tasks.Add(scheduler.Task{
+		Name:     "job cleanup",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			return a.Jobs.Enqueue(ctx, models.Job{
+				Kind: jobPurgeJobs,
+				Key:  jobPurgeJobs + ":" + time.Now().UTC().Format("2006-01-02"),
+			})
+		},
+	})
+	return tasks
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"easybook/internal/middleware"
+)
+
+const maxCSPReportBytes = 64 << 10
+
+// securityPolicy applies the environment settings to one of the base
+// policies. Single sign-on providers are allowed as form targets because the
+// login form is redirected to them.
+func (a *App) securityPolicy(policy middleware.SecurityPolicy) middleware.SecurityPolicy {
+	policy.CSPReportOnly = a.Env.CSPReportOnly
+	if a.Env.IsProduction && a.Env.HSTSMaxAgeDays > 0 {
+		policy.HSTSMaxAge = time.Duration(a.Env.HSTSMaxAgeDays) * 24 * time.Hour
+	}
+
+	if len(a.SSOProviders) > 0 {
+		targets := []string{"'self'"}
+		for _, provider := range a.SSOProviders {
+			if issuer, err := url.Parse(provider.Issuer); err == nil && issuer.Host != "" {
+				targets = append(targets, issuer.Scheme+"://"+issuer.Host)
+			}
+		}
+		for _, directive := range policy.CSP {
+			if directive.Name == "form-action" {
+				policy.CSP = policy.CSP.With("form-action", targets...)
+				break
+			}
+		}
+	}
+	return policy
+}
+
+type cspViolation struct {
+	DocumentURI        string `json:"document-uri"`
+	BlockedURI         string `json:"blocked-uri"`
+	ViolatedDirective  string `json:"violated-directive"`
+	EffectiveDirective string `json:"effective-directive"`
+	SourceFile         string `json:"source-file"`
+	LineNumber         int    `json:"line-number"`
+}
+
+// reportCSPViolation logs reports in both the legacy report-uri format and
+// the Reporting API format.
+func (a *App) reportCSPViolation(w http.ResponseWriter, r *http.Request) error {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxCSPReportBytes))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return nil
+	}
+
+	var violations []cspViolation
+	var legacy struct {
+		Report *cspViolation `json:"csp-report"`
+	}
+	var reports []struct {
+		Type string `json:"type"`
+		Body struct {
+			DocumentURL        string `json:"documentURL"`
+			BlockedURL         string `json:"blockedURL"`
+			EffectiveDirective string `json:"effectiveDirective"`
+			SourceFile         string `json:"sourceFile"`
+			LineNumber         int    `json:"lineNumber"`
+		} `json:"body"`
+	}
+	switch {
+	case json.Unmarshal(body, &legacy) == nil && legacy.Report != nil:
+		violations = append(violations, *legacy.Report)
+	case json.Unmarshal(body, &reports) == nil:
+		for _, report := range reports {
+			if report.Type != "csp-violation" {
+				continue
+			}
+			violations = append(violations, cspViolation{
+				DocumentURI:        report.Body.DocumentURL,
+				BlockedURI:         report.Body.BlockedURL,
+				EffectiveDirective: report.Body.EffectiveDirective,
+				SourceFile:         report.Body.SourceFile,
+				LineNumber:         report.Body.LineNumber,
+			})
+		}
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		return nil
+	}
+
+	for _, violation := range violations {
+		directive := violation.EffectiveDirective
+		if directive == "" {
+			directive = violation.ViolatedDirective
+		}
+		log.Printf(
+			"CSP violation: directive=%q blocked=%q page=%q source=%q line=%d",
+			directive, violation.BlockedURI, violation.DocumentURI, violation.SourceFile, violation.LineNumber,
+		)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
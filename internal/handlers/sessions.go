@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"easybook/internal/session"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func (a *App) listSessionsAPI(w http.ResponseWriter, r *http.Request) error {
+	user := session.CurrentUser(r)
+
+	items, err := a.Sessions.ListUserSessions(r, user.ID)
+	if err != nil {
+		return err
+	}
+
+	a.writeJSON(w, http.StatusOK, map[string]any{"items": items})
+	return nil
+}
+
+func (a *App) revokeSessionAPI(w http.ResponseWriter, r *http.Request) error {
+	user := session.CurrentUser(r)
+
+	revoked, err := a.Sessions.RevokeUserSession(r.Context(), user.ID, chi.URLParam(r, "id"))
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		a.writeJSON(w, http.StatusNotFound, map[string]string{"error": "Session not found"})
+		return nil
+	}
+
+	a.writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+	return nil
+}
+
+func (a *App) revokeOtherSessionsAPI(w http.ResponseWriter, r *http.Request) error {
+	user := session.CurrentUser(r)
+
+	revoked, err := a.Sessions.DestroyOtherUserSessions(r, user.ID)
+	if err != nil {
+		return err
+	}
+
+	a.writeJSON(w, http.StatusOK, map[string]any{"ok": true, "revoked": revoked})
+	return nil
+}
+
+func (a *App) forceLogoutUserAPI(w http.ResponseWriter, r *http.Request) error {
+	targetUser, err := a.Store.FindUserByID(r.Context(), strings.TrimSpace(chi.URLParam(r, "id")))
+	if err != nil {
+		return err
+	}
+	if targetUser == nil {
+		a.writeJSON(w, http.StatusNotFound, map[string]string{"error": "User not found"})
+		return nil
+	}
+
+	revoked, err := a.Sessions.DestroyUserSessions(r.Context(), targetUser.ID.Hex())
+	if err != nil {
+		return err
+	}
+
+	a.writeJSON(w, http.StatusOK, map[string]any{"ok": true, "revoked": revoked})
+	return nil
+}
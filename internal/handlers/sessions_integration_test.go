@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"easybook/internal/config"
+	"easybook/internal/db"
+	"easybook/internal/models"
+	"easybook/internal/session"
+	"easybook/internal/view"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestSessionListAndRevoke(t *testing.T) {
+	mongoURI := strings.TrimSpace(os.Getenv("MONGO_URI"))
+	if mongoURI == "" {
+		t.Skip("MONGO_URI is not set; skipping integration test")
+	}
+
+	dbName := "easybook_sessions_test_" + primitive.NewObjectID().Hex()
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURI))
+	if err != nil {
+		t.Fatalf("connect mongo: %v", err)
+	}
+	defer func() {
+		_ = client.Disconnect(context.Background())
+	}()
+
+	database := client.Database(dbName)
+	defer func() {
+		_ = database.Drop(context.Background())
+	}()
+
+	if err := db.EnsureStartupMaintenance(ctx, database); err != nil {
+		t.Fatalf("ensure indexes: %v", err)
+	}
+
+	sessions, err := session.NewManager(ctx, database, false, "sessions-integration-secret-123")
+	if err != nil {
+		t.Fatalf("init sessions: %v", err)
+	}
+
+	app := NewApp(config.Env{}, models.NewStore(database), sessions, view.NewRenderer("../../views"), "../../views")
+	server := httptest.NewServer(app.Router())
+	defer server.Close()
+
+	userID := primitive.NewObjectID().Hex()
+	laptop := createSessionCookieForTests(t, sessions, userID, "devices@example.com", "user")
+	phone := createSessionCookieForTests(t, sessions, userID, "devices@example.com", "user")
+
+	listSessions := func(cookie *http.Cookie) (int, []session.Info) {
+		request, _ := http.NewRequest(http.MethodGet, server.URL+"/api/auth/sessions", nil)
+		request.AddCookie(cookie)
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatalf("list sessions: %v", err)
+		}
+		defer response.Body.Close()
+
+		var body struct {
+			Items []session.Info `json:"items"`
+		}
+		_ = json.NewDecoder(response.Body).Decode(&body)
+		return response.StatusCode, body.Items
+	}
+
+	status, items := listSessions(laptop)
+	if status != http.StatusOK || len(items) != 2 {
+		t.Fatalf("expected 2 sessions, got status %d and %d items", status, len(items))
+	}
+
+	var otherID string
+	for _, item := range items {
+		if !item.Current {
+			otherID = item.ID
+		}
+	}
+	if otherID == "" {
+		t.Fatal("expected one non-current session")
+	}
+
+	request, _ := http.NewRequest(http.MethodDelete, server.URL+"/api/auth/sessions/"+otherID, nil)
+	request.Header.Set(session.CSRFHeaderName, fetchCSRFTokenForTests(t, http.DefaultClient, server.URL, laptop))
+	request.AddCookie(laptop)
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("revoke session: %v", err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("expected revoke status 200, got %d", response.StatusCode)
+	}
+
+	if status, _ := listSessions(phone); status != http.StatusUnauthorized {
+		t.Fatalf("expected revoked session to be signed out, got %d", status)
+	}
+	if status, items := listSessions(laptop); status != http.StatusOK || len(items) != 1 || !items[0].Current {
+		t.Fatalf("expected only the current session to remain, got status %d and %v", status, items)
+	}
+}
@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"easybook/internal/config"
+	"easybook/internal/models"
+	"easybook/internal/oidc"
+	"easybook/internal/session"
+	"easybook/internal/utils"
+	"easybook/internal/view"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func newSSOProviders(configs []config.OIDCProvider) []*oidc.Provider {
+	providers := make([]*oidc.Provider, 0, len(configs))
+	for _, item := range configs {
+		providers = append(providers, oidc.NewProvider(oidc.Config{
+			ID:           item.ID,
+			Name:         item.Name,
+			Issuer:       item.Issuer,
+			ClientID:     item.ClientID,
+			ClientSecret: item.ClientSecret,
+			Scopes:       item.Scopes,
+			AllowSignup:  item.AllowSignup,
+		}, nil))
+	}
+	return providers
+}
+
+func (a *App) ssoProvider(id string) *oidc.Provider {
+	for _, provider := range a.SSOProviders {
+		if provider.ID == id {
+			return provider
+		}
+	}
+	return nil
+}
+
+func (a *App) ssoRedirectURI(provider *oidc.Provider) string {
+	return a.Env.AppBaseURL + "/login/sso/" + provider.ID + "/callback"
+}
+
+// renderSSOButtons adds one submit button per provider to the login form, so
+// "next" and "keep me signed in" travel with the single sign-on request too.
+func (a *App) renderSSOButtons() view.SafeHTML {
+	if len(a.SSOProviders) == 0 {
+		return view.Safe("")
+	}
+
+	var buttons strings.Builder
+	buttons.WriteString(`<div class="sso-buttons" style="margin-top: 14px; text-align:center;"><p>or</p>`)
+	for _, provider := range a.SSOProviders {
+		buttons.WriteString(fmt.Sprintf(
+			`<button type="submit" class="btn btn-outline btn-full" formaction="/login/sso/%s" formnovalidate>Sign in with %s</button>`,
+			view.EscapeHTML(provider.ID),
+			view.EscapeHTML(provider.Name),
+		))
+	}
+	buttons.WriteString(`</div>`)
+	return view.Safe(buttons.String())
+}
+
+func (a *App) renderSSOLoginError(w http.ResponseWriter, r *http.Request, status int, nextPath, message string) error {
+	return a.renderHTML(w, r, status, "login.html", map[string]any{
+		"next":          nextPath,
+		"errorMessage":  message,
+		"noticeMessage": renderNotice("success", ""),
+		"emailValue":    "",
+		"ssoButtons":    a.renderSSOButtons(),
+	})
+}
+
+func (a *App) startSSOLogin(w http.ResponseWriter, r *http.Request) error {
+	provider := a.ssoProvider(chi.URLParam(r, "provider"))
+	if provider == nil {
+		a.NotFoundHandler(w, r)
+		return nil
+	}
+
+	payload, err := a.parsePayload(r)
+	if err != nil {
+		return err
+	}
+	nextPath := getSafeRedirectPath(utils.ToTrimmedString(payload["next"]), "/hotels")
+	rememberRaw := utils.ToTrimmedString(payload["remember"])
+	rememberMe := rememberRaw == "on" || rememberRaw == "true" || payload["remember"] == true
+
+	verifier, challenge, err := oidc.NewPKCE()
+	if err != nil {
+		return err
+	}
+	nonce, err := oidc.RandomString(16)
+	if err != nil {
+		return err
+	}
+
+	state, err := a.Sessions.StartSSOLogin(w, r, session.SSOLogin{
+		Provider:     provider.ID,
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		Next:         nextPath,
+		RememberMe:   rememberMe,
+	})
+	if err != nil {
+		return err
+	}
+
+	authURL, err := provider.AuthCodeURL(r.Context(), a.ssoRedirectURI(provider), state, nonce, challenge)
+	if err != nil {
+		log.Printf("sso discovery failed for %s: %v", provider.ID, err)
+		return a.renderSSOLoginError(w, r, http.StatusBadGateway, nextPath, "Sign-in with "+provider.Name+" is temporarily unavailable.")
+	}
+
+	http.Redirect(w, r, authURL, http.StatusFound)
+	return nil
+}
+
+func (a *App) ssoCallback(w http.ResponseWriter, r *http.Request) error {
+	provider := a.ssoProvider(chi.URLParam(r, "provider"))
+	if provider == nil {
+		a.NotFoundHandler(w, r)
+		return nil
+	}
+
+	query := r.URL.Query()
+	login, err := a.Sessions.ConsumeSSOLogin(w, r, query.Get("state"))
+	if errors.Is(err, session.ErrSSOLoginNotFound) || (err == nil && login.Provider != provider.ID) {
+		return a.renderSSOLoginError(w, r, http.StatusBadRequest, "/hotels", "Your sign-in request has expired. Please try again.")
+	}
+	if err != nil {
+		return err
+	}
+
+	if query.Get("error") != "" {
+		return a.renderSSOLoginError(w, r, http.StatusUnauthorized, login.Next, "Sign-in with "+provider.Name+" was cancelled or denied.")
+	}
+
+	claims, err := provider.Exchange(r.Context(), query.Get("code"), login.CodeVerifier, a.ssoRedirectURI(provider), login.Nonce)
+	if err != nil {
+		log.Printf("sso exchange failed for %s: %v", provider.ID, err)
+		return a.renderSSOLoginError(w, r, http.StatusUnauthorized, login.Next, "We could not verify your sign-in with "+provider.Name+".")
+	}
+
+	user, refusal, err := a.resolveSSOUser(r.Context(), provider, claims)
+	if err != nil {
+		return err
+	}
+	if refusal != "" {
+		return a.renderSSOLoginError(w, r, http.StatusForbidden, login.Next, refusal)
+	}
+
+	return a.completeLogin(w, r, user, login.Next, login.RememberMe)
+}
+
+// resolveSSOUser finds the account for an external identity. Unknown
+// identities are linked to an existing account only through an email address
+// that both the provider and this app have verified; otherwise a refusal
+// message for the login page is returned.
+func (a *App) resolveSSOUser(ctx context.Context, provider *oidc.Provider, claims *oidc.Claims) (*models.User, string, error) {
+	user, err := a.Store.FindUserByIdentity(ctx, provider.ID, claims.Subject)
+	if err != nil || user != nil {
+		return user, "", err
+	}
+
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, provider.Name + " did not confirm your email address, so it cannot be linked to an account.", nil
+	}
+
+	user, err = a.Store.FindUserByEmail(ctx, claims.Email)
+	if err != nil {
+		return nil, "", err
+	}
+
+	switch {
+	case user == nil && !provider.AllowSignup:
+		return nil, "No account uses " + claims.Email + ". Register first, then sign in with " + provider.Name + ".", nil
+	case user == nil:
+		user, err = a.Store.CreateExternalUser(ctx, claims.Email)
+		if err != nil {
+			return nil, "", err
+		}
+	case !user.EmailVerified:
+		// Linking an unconfirmed account would hand it to whoever registered
+		// the address first.
+		return nil, "Confirm the email address of your account first, then sign in with " + provider.Name + ".", nil
+	}
+
+	if err := a.Store.LinkIdentity(ctx, user.ID.Hex(), provider.ID, claims.Subject, claims.Email); err != nil {
+		if !models.IsDuplicateKeyError(err, "") {
+			return nil, "", err
+		}
+		user, err = a.Store.FindUserByIdentity(ctx, provider.ID, claims.Subject)
+		return user, "", err
+	}
+	return user, "", nil
+}
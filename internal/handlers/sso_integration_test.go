@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"easybook/internal/config"
+	"easybook/internal/db"
+	"easybook/internal/models"
+	"easybook/internal/oidc/oidctest"
+	"easybook/internal/session"
+	"easybook/internal/view"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestSSOLoginLinksVerifiedEmail(t *testing.T) {
+	mongoURI := strings.TrimSpace(os.Getenv("MONGO_URI"))
+	if mongoURI == "" {
+		t.Skip("MONGO_URI is not set; skipping integration test")
+	}
+
+	dbName := "easybook_sso_test_" + primitive.NewObjectID().Hex()
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURI))
+	if err != nil {
+		t.Fatalf("connect mongo: %v", err)
+	}
+	defer func() {
+		_ = client.Disconnect(context.Background())
+	}()
+
+	database := client.Database(dbName)
+	defer func() {
+		_ = database.Drop(context.Background())
+	}()
+
+	if err := db.EnsureStartupMaintenance(ctx, database); err != nil {
+		t.Fatalf("ensure indexes: %v", err)
+	}
+
+	sessions, err := session.NewManager(ctx, database, false, "sso-integration-secret-123")
+	if err != nil {
+		t.Fatalf("init sessions: %v", err)
+	}
+
+	issuer := oidctest.NewIssuer("easybook", "issuer-secret")
+	defer issuer.Close()
+
+	store := models.NewStore(database)
+	app := NewApp(config.Env{
+		OIDCProviders: []config.OIDCProvider{{
+			ID:           "corp",
+			Name:         "Corp",
+			Issuer:       issuer.URL,
+			ClientID:     issuer.ClientID,
+			ClientSecret: issuer.ClientSecret,
+		}},
+	}, store, sessions, view.NewRenderer("../../views"), "../../views")
+	server := httptest.NewServer(app.Router())
+	defer server.Close()
+	app.Env.AppBaseURL = server.URL
+
+	userID, err := store.CreateUser(ctx, "employee@example.com", "Passw0rd!", "user")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	token, err := store.CreateEmailVerificationToken(ctx, userID, "employee@example.com", time.Hour)
+	if err != nil {
+		t.Fatalf("create verification token: %v", err)
+	}
+	if _, err := store.ConsumeEmailVerificationToken(ctx, token); err != nil {
+		t.Fatalf("verify email: %v", err)
+	}
+
+	signIn := func(user oidctest.User) (*http.Client, *http.Response) {
+		issuer.SetUser(user)
+		browser := newPresenceTestHTTPClient()
+
+		form := url.Values{"next": {"/bookings"}, "_csrf": {fetchCSRFTokenForTests(t, browser, server.URL)}}
+		response, err := browser.PostForm(server.URL+"/login/sso/corp", form)
+		if err != nil {
+			t.Fatalf("start sso: %v", err)
+		}
+		response.Body.Close()
+		if response.StatusCode != http.StatusFound {
+			t.Fatalf("expected redirect to issuer, got %d", response.StatusCode)
+		}
+
+		for step := 0; step < 2; step++ {
+			response, err = browser.Get(response.Header.Get("Location"))
+			if err != nil {
+				t.Fatalf("follow sso redirect: %v", err)
+			}
+			response.Body.Close()
+		}
+		return browser, response
+	}
+
+	sessionEmail := func(browser *http.Client) string {
+		response, err := browser.Get(server.URL + "/api/auth/session")
+		if err != nil {
+			t.Fatalf("session status: %v", err)
+		}
+		defer response.Body.Close()
+
+		var body struct {
+			User struct {
+				Email string `json:"email"`
+			} `json:"user"`
+		}
+		_ = json.NewDecoder(response.Body).Decode(&body)
+		return body.User.Email
+	}
+
+	browser, response := signIn(oidctest.User{Subject: "emp-1", Email: "Employee@example.com", EmailVerified: true})
+	if response.StatusCode != http.StatusFound || response.Header.Get("Location") != "/bookings" {
+		t.Fatalf("expected redirect to /bookings, got %d %q", response.StatusCode, response.Header.Get("Location"))
+	}
+	if email := sessionEmail(browser); email != "employee@example.com" {
+		t.Fatalf("expected the existing account to be signed in, got %q", email)
+	}
+
+	// The link is by subject now, so a changed address at the provider still
+	// reaches the same account.
+	browser, _ = signIn(oidctest.User{Subject: "emp-1", Email: "renamed@example.com", EmailVerified: true})
+	if email := sessionEmail(browser); email != "employee@example.com" {
+		t.Fatalf("expected linked identity to sign in the same account, got %q", email)
+	}
+
+	_, response = signIn(oidctest.User{Subject: "emp-2", Email: "employee@example.com", EmailVerified: false})
+	if response.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected unverified provider email to be refused, got %d", response.StatusCode)
+	}
+
+	_, response = signIn(oidctest.User{Subject: "emp-3", Email: "stranger@example.com", EmailVerified: true})
+	if response.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected unknown email to be refused without signup, got %d", response.StatusCode)
+	}
+}
@@ -0,0 +1,493 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"easybook/internal/models"
+	"easybook/internal/session"
+	"easybook/internal/totp"
+	"easybook/internal/types"
+	"easybook/internal/utils"
+	"easybook/internal/view"
+
+	"github.com/skip2/go-qrcode"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const recoveryCodeCount = 10
+
+func (a *App) renderLoginTwoFactorPage(w http.ResponseWriter, r *http.Request) error {
+	challenge, err := a.Sessions.CurrentLoginChallenge(r)
+	if errors.Is(err, session.ErrChallengeNotFound) {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return a.renderHTML(w, r, http.StatusOK, "login-2fa.html", map[string]any{
+		"emailValue":   challenge.Email,
+		"errorMessage": "",
+	})
+}
+
+func (a *App) loginTwoFactor(w http.ResponseWriter, r *http.Request) error {
+	payload, err := a.parsePayload(r)
+	if err != nil {
+		return err
+	}
+
+	challenge, err := a.Sessions.CurrentLoginChallenge(r)
+	if errors.Is(err, session.ErrChallengeNotFound) {
+		return a.renderLoginExpired(w, r, "Your sign-in attempt has expired. Please sign in again.")
+	}
+	if err != nil {
+		return err
+	}
+
+	user, err := a.Store.FindUserByID(r.Context(), challenge.UserID)
+	if err != nil {
+		return err
+	}
+	if user == nil || !user.TOTPEnabled {
+		a.Sessions.ClearLoginChallenge(w, r)
+		return a.renderLoginExpired(w, r, "Please sign in again.")
+	}
+
+	accountKey := models.LoginAccountKey(user.Email)
+	clientKey := models.LoginClientKey(utils.ClientIP(r))
+	throttle, err := a.Store.CheckLoginThrottle(r.Context(), accountKey, clientKey)
+	if err != nil {
+		return err
+	}
+	if throttle.Blocked() {
+		a.Sessions.ClearLoginChallenge(w, r)
+		return a.renderHTML(w, r, http.StatusTooManyRequests, "login.html", map[string]any{
+			"next":          challenge.Next,
+			"errorMessage":  loginThrottleMessage(throttle),
+			"noticeMessage": renderNotice("success", ""),
+			"emailValue":    user.Email,
+			"ssoButtons":    a.renderSSOButtons(),
+		})
+	}
+
+	ok, err := a.verifySecondFactor(r.Context(), user, utils.ToTrimmedString(payload["code"]))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		if err := a.recordFailedLogin(r.Context(), user, accountKey, clientKey); err != nil {
+			return err
+		}
+		usable, err := a.Sessions.FailLoginChallenge(w, r, challenge)
+		if err != nil {
+			return err
+		}
+		if !usable {
+			return a.renderLoginExpired(w, r, "Too many invalid codes. Please sign in again.")
+		}
+		return a.renderHTML(w, r, http.StatusUnauthorized, "login-2fa.html", map[string]any{
+			"emailValue":   user.Email,
+			"errorMessage": "Invalid authentication code",
+		})
+	}
+
+	a.Sessions.ClearLoginChallenge(w, r)
+	if err := a.Store.ClearLoginFailures(r.Context(), accountKey); err != nil {
+		return err
+	}
+
+	if err := a.Sessions.StartSession(w, r, types.CurrentUser{
+		ID:                user.ID.Hex(),
+		Email:             user.Email,
+		Role:              user.Role,
+		EmailVerified:     user.EmailVerified,
+		TwoFactorVerified: true,
+	}, challenge.RememberMe); err != nil {
+		return err
+	}
+
+	http.Redirect(w, r, getSafeRedirectPath(challenge.Next, "/hotels"), http.StatusFound)
+	return nil
+}
+
+func (a *App) renderLoginExpired(w http.ResponseWriter, r *http.Request, message string) error {
+	return a.renderHTML(w, r, http.StatusUnauthorized, "login.html", map[string]any{
+		"next":          "/hotels",
+		"errorMessage":  message,
+		"noticeMessage": renderNotice("success", ""),
+		"emailValue":    "",
+		"ssoButtons":    a.renderSSOButtons(),
+	})
+}
+
+func (a *App) renderAccountSecurityPage(w http.ResponseWriter, r *http.Request) error {
+	user, err := a.currentAccount(r)
+	if err != nil {
+		return err
+	}
+
+	notice := ""
+	if r.URL.Query().Get("required") == "1" {
+		notice = "Your role requires two-factor authentication. Complete the step below to continue."
+	}
+	return a.renderAccountSecurity(w, r, http.StatusOK, user, notice, "", "")
+}
+
+func (a *App) setupTwoFactor(w http.ResponseWriter, r *http.Request) error {
+	user, err := a.currentAccount(r)
+	if err != nil {
+		return err
+	}
+	if user.TOTPEnabled {
+		return a.renderAccountSecurity(w, r, http.StatusConflict, user, "", "Two-factor authentication is already enabled.", "")
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return err
+	}
+	if err := a.Store.SetPendingTOTPSecret(r.Context(), user.ID.Hex(), secret); err != nil {
+		return err
+	}
+
+	block, err := a.renderTwoFactorSetupBlock(user.Email, secret)
+	if err != nil {
+		return err
+	}
+	return a.renderAccountSecurity(w, r, http.StatusOK, user, "", "", block)
+}
+
+func (a *App) enableTwoFactor(w http.ResponseWriter, r *http.Request) error {
+	payload, err := a.parsePayload(r)
+	if err != nil {
+		return err
+	}
+
+	user, err := a.currentAccount(r)
+	if err != nil {
+		return err
+	}
+	if user.TOTPEnabled {
+		return a.renderAccountSecurity(w, r, http.StatusConflict, user, "", "Two-factor authentication is already enabled.", "")
+	}
+	if user.TOTPPendingSecret == "" {
+		return a.renderAccountSecurity(w, r, http.StatusBadRequest, user, "", "Start the setup again to get a new key.", "")
+	}
+
+	step, ok := totp.Validate(user.TOTPPendingSecret, utils.ToTrimmedString(payload["code"]), time.Now(), 1)
+	if !ok {
+		block, err := a.renderTwoFactorSetupBlock(user.Email, user.TOTPPendingSecret)
+		if err != nil {
+			return err
+		}
+		return a.renderAccountSecurity(w, r, http.StatusBadRequest, user, "", "Invalid authentication code. Check the time on your device and try again.", block)
+	}
+
+	codes, err := totp.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return err
+	}
+	if err := a.Store.EnableTOTP(r.Context(), user.ID.Hex(), user.TOTPPendingSecret, step, codes); err != nil {
+		if errors.Is(err, models.ErrInvalidTwoFactorCode) {
+			return a.renderAccountSecurity(w, r, http.StatusConflict, user, "", "Start the setup again to get a new key.", "")
+		}
+		return err
+	}
+	if err := a.markSessionTwoFactorVerified(r); err != nil {
+		return err
+	}
+
+	user.TOTPEnabled = true
+	return a.renderAccountSecurity(w, r, http.StatusOK, user, "Two-factor authentication is now enabled.", "", renderRecoveryCodesBlock(codes))
+}
+
+func (a *App) verifyTwoFactorSession(w http.ResponseWriter, r *http.Request) error {
+	payload, err := a.parsePayload(r)
+	if err != nil {
+		return err
+	}
+
+	user, err := a.currentAccount(r)
+	if err != nil {
+		return err
+	}
+	if !user.TOTPEnabled {
+		return a.renderAccountSecurity(w, r, http.StatusBadRequest, user, "", "Two-factor authentication is not enabled.", "")
+	}
+
+	status, message, err := a.verifyAccountSecondFactor(r, user, nil, utils.ToTrimmedString(payload["code"]))
+	if err != nil {
+		return err
+	}
+	if message != "" {
+		return a.renderAccountSecurity(w, r, status, user, "", message, "")
+	}
+	if err := a.markSessionTwoFactorVerified(r); err != nil {
+		return err
+	}
+
+	return a.renderAccountSecurity(w, r, http.StatusOK, user, "This session is now verified.", "", "")
+}
+
+func (a *App) regenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) error {
+	payload, err := a.parsePayload(r)
+	if err != nil {
+		return err
+	}
+
+	user, err := a.currentAccount(r)
+	if err != nil {
+		return err
+	}
+	if !user.TOTPEnabled {
+		return a.renderAccountSecurity(w, r, http.StatusBadRequest, user, "", "Two-factor authentication is not enabled.", "")
+	}
+
+	status, message, err := a.verifyAccountSecondFactor(r, user, nil, utils.ToTrimmedString(payload["code"]))
+	if err != nil {
+		return err
+	}
+	if message != "" {
+		return a.renderAccountSecurity(w, r, status, user, "", message, "")
+	}
+
+	codes, err := totp.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return err
+	}
+	if err := a.Store.ReplaceRecoveryCodes(r.Context(), user.ID.Hex(), codes); err != nil {
+		return err
+	}
+
+	return a.renderAccountSecurity(w, r, http.StatusOK, user, "New recovery codes were generated. The old codes no longer work.", "", renderRecoveryCodesBlock(codes))
+}
+
+func (a *App) disableTwoFactor(w http.ResponseWriter, r *http.Request) error {
+	payload, err := a.parsePayload(r)
+	if err != nil {
+		return err
+	}
+
+	user, err := a.currentAccount(r)
+	if err != nil {
+		return err
+	}
+	if !user.TOTPEnabled {
+		return a.renderAccountSecurity(w, r, http.StatusBadRequest, user, "", "Two-factor authentication is not enabled.", "")
+	}
+	if a.twoFactorRequiredFor(user.Role) {
+		return a.renderAccountSecurity(w, r, http.StatusForbidden, user, "", "Two-factor authentication is mandatory for your role.", "")
+	}
+
+	password := utils.ToTrimmedString(payload["password"])
+	status, message, err := a.verifyAccountSecondFactor(r, user, &password, utils.ToTrimmedString(payload["code"]))
+	if err != nil {
+		return err
+	}
+	if message != "" {
+		return a.renderAccountSecurity(w, r, status, user, "", message, "")
+	}
+
+	if err := a.Store.DisableTOTP(r.Context(), user.ID.Hex()); err != nil {
+		return err
+	}
+
+	user.TOTPEnabled = false
+	return a.renderAccountSecurity(w, r, http.StatusOK, user, "Two-factor authentication has been disabled.", "", "")
+}
+
+// verifySecondFactor accepts either a current TOTP code or an unused recovery code.
+func (a *App) verifySecondFactor(ctx context.Context, user *models.User, code string) (bool, error) {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if code == "" || user == nil || !user.TOTPEnabled {
+		return false, nil
+	}
+
+	if len(code) == totp.Digits {
+		step, ok := totp.Validate(user.TOTPSecret, code, time.Now(), 1)
+		if !ok {
+			return false, nil
+		}
+		return a.Store.ConsumeTOTPStep(ctx, user.ID.Hex(), step)
+	}
+
+	return a.Store.ConsumeRecoveryCode(ctx, user.ID.Hex(), totp.NormalizeRecoveryCode(code))
+}
+
+// verifyAccountSecondFactor checks a code entered by a signed-in user, and
+// password as well when given, under the sign-in throttle, so a stolen
+// session cannot guess its way to turning two-factor authentication off. A
+// non-empty message means the attempt was refused with status.
+func (a *App) verifyAccountSecondFactor(r *http.Request, user *models.User, password *string, code string) (int, string, error) {
+	accountKey := models.LoginAccountKey(user.Email)
+	clientKey := models.LoginClientKey(utils.ClientIP(r))
+	throttle, err := a.Store.CheckLoginThrottle(r.Context(), accountKey, clientKey)
+	if err != nil {
+		return 0, "", err
+	}
+	if throttle.Blocked() {
+		return http.StatusTooManyRequests, loginThrottleMessage(throttle), nil
+	}
+
+	message := ""
+	if password != nil && (*password == "" || bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(*password)) != nil) {
+		message = "Invalid password"
+	} else {
+		ok, err := a.verifySecondFactor(r.Context(), user, code)
+		if err != nil {
+			return 0, "", err
+		}
+		if !ok {
+			message = "Invalid authentication code"
+		}
+	}
+	if message != "" {
+		if err := a.recordFailedLogin(r.Context(), user, accountKey, clientKey); err != nil {
+			return 0, "", err
+		}
+		return http.StatusUnauthorized, message, nil
+	}
+	return http.StatusOK, "", a.Store.ClearLoginFailures(r.Context(), accountKey)
+}
+
+func (a *App) markSessionTwoFactorVerified(r *http.Request) error {
+	if err := a.Sessions.MarkTwoFactorVerified(r); err != nil {
+		return err
+	}
+	if currentUser := session.CurrentUser(r); currentUser != nil {
+		currentUser.TwoFactorVerified = true
+	}
+	return nil
+}
+
+func (a *App) twoFactorRequiredFor(role string) bool {
+	for _, required := range a.Env.TOTPRequiredRoles {
+		if required == role {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *App) currentAccount(r *http.Request) (*models.User, error) {
+	currentUser := session.CurrentUser(r)
+	user, err := a.Store.FindUserByID(r.Context(), currentUser.ID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, fmt.Errorf("account %s no longer exists", currentUser.ID)
+	}
+	return user, nil
+}
+
+func (a *App) renderAccountSecurity(w http.ResponseWriter, r *http.Request, statusCode int, user *models.User, noticeMessage, errorMessage, extraBlock string) error {
+	currentUser := session.CurrentUser(r)
+
+	var block strings.Builder
+	block.WriteString(extraBlock)
+
+	switch {
+	case !user.TOTPEnabled && extraBlock == "":
+		block.WriteString(`
+      <p>Two-factor authentication is <strong>off</strong>. Protect your account with a 6-digit code from an authenticator app in addition to your password.</p>
+      <form method="POST" action="/account/2fa/setup">
+        <button type="submit" class="btn">Set up two-factor authentication</button>
+      </form>
+    `)
+	case user.TOTPEnabled:
+		block.WriteString(`<p>Two-factor authentication is <strong>on</strong>.</p>`)
+		if !currentUser.TwoFactorVerified {
+			block.WriteString(`
+      <form method="POST" action="/account/2fa/verify" class="contact-form">
+        <div class="form-group">
+          <label for="verifyCode">Verify this session</label>
+          <input id="verifyCode" type="text" name="code" inputmode="numeric" autocomplete="one-time-code" maxlength="16" required />
+        </div>
+        <button type="submit" class="btn">Verify</button>
+      </form>
+    `)
+		}
+		block.WriteString(`
+      <h3>Recovery codes</h3>
+      <form method="POST" action="/account/2fa/recovery-codes" class="contact-form">
+        <div class="form-group">
+          <label for="recoveryCode">Authentication code</label>
+          <input id="recoveryCode" type="text" name="code" inputmode="numeric" autocomplete="one-time-code" maxlength="16" required />
+        </div>
+        <button type="submit" class="btn btn-outline">Generate new recovery codes</button>
+      </form>
+    `)
+		if !a.twoFactorRequiredFor(user.Role) {
+			block.WriteString(`
+      <h3>Disable two-factor authentication</h3>
+      <form method="POST" action="/account/2fa/disable" class="contact-form">
+        <div class="form-group">
+          <label for="disablePassword">Password</label>
+          <input id="disablePassword" type="password" name="password" autocomplete="current-password" required />
+        </div>
+        <div class="form-group">
+          <label for="disableCode">Authentication code</label>
+          <input id="disableCode" type="text" name="code" inputmode="numeric" autocomplete="one-time-code" maxlength="16" required />
+        </div>
+        <button type="submit" class="btn btn-outline">Disable</button>
+      </form>
+    `)
+		}
+	}
+
+	block.WriteString(`<p style="margin-top: 16px;"><a href="/account/tokens">Manage personal API tokens</a></p>`)
+
+	return a.renderHTML(w, r, statusCode, "account-security.html", map[string]any{
+		"authControls":   view.Safe(renderAuthControls(currentUser, "/account/security")),
+		"noticeMessage":  renderNotice("success", noticeMessage),
+		"errorMessage":   errorMessage,
+		"twoFactorBlock": view.Safe(block.String()),
+	})
+}
+
+func (a *App) renderTwoFactorSetupBlock(email, secret string) (string, error) {
+	uri := totp.ProvisioningURI(a.Env.TOTPIssuer, email, secret)
+	png, err := qrcode.Encode(uri, qrcode.Medium, 220)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf(`
+      <p>Scan this QR code with your authenticator app, then enter the 6-digit code it shows.</p>
+      <p style="text-align:center;"><img src="data:image/png;base64,%s" width="220" height="220" alt="Two-factor QR code" /></p>
+      <p>Can't scan it? Enter this key manually: <code>%s</code></p>
+      <form method="POST" action="/account/2fa/enable" class="contact-form">
+        <div class="form-group">
+          <label for="enableCode">Authentication code</label>
+          <input id="enableCode" type="text" name="code" inputmode="numeric" autocomplete="one-time-code" maxlength="6" required />
+        </div>
+        <button type="submit" class="btn">Enable two-factor authentication</button>
+      </form>
+    `,
+		base64.StdEncoding.EncodeToString(png),
+		view.EscapeHTML(secret),
+	), nil
+}
+
+func renderRecoveryCodesBlock(codes []string) string {
+	var items strings.Builder
+	for _, code := range codes {
+		items.WriteString("<li><code>" + view.EscapeHTML(code) + "</code></li>")
+	}
+
+	return fmt.Sprintf(`
+      <div class="notice notice-warning">
+        <p>Save these recovery codes somewhere safe. Each code works once if you lose access to your authenticator app. They will not be shown again.</p>
+        <ul>%s</ul>
+      </div>
+    `, items.String())
+}
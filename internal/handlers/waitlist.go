@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"easybook/internal/models"
+	"easybook/internal/session"
+	"easybook/internal/view"
+
+	"github.com/go-chi/chi/v5"
+)
+
+var waitlistStatusLabels = map[string]string{
+	models.WaitlistStatusActive:   "Waiting",
+	models.WaitlistStatusNotified: "Room offered",
+	models.WaitlistStatusHeld:     "Room held for you",
+	models.WaitlistStatusExpired:  "Expired",
+}
+
+func (a *App) getWaitlistAPI(w http.ResponseWriter, r *http.Request) error {
+	user := session.CurrentUser(r)
+	entries, err := a.Store.ListWaitlistEntries(r.Context(), user.ID, r.URL.Query().Get("status") == models.WaitlistStatusActive)
+	if err != nil {
+		return err
+	}
+
+	a.writeJSON(w, http.StatusOK, map[string]any{"items": entries})
+	return nil
+}
+
+func (a *App) deleteWaitlistAPI(w http.ResponseWriter, r *http.Request) error {
+	user := session.CurrentUser(r)
+	cancelled, err := a.cancelWaitlistEntry(r, user.ID)
+	if err != nil {
+		return err
+	}
+	if !cancelled {
+		a.writeJSON(w, http.StatusNotFound, map[string]string{"error": "Waitlist subscription not found"})
+		return nil
+	}
+
+	a.writeJSON(w, http.StatusOK, map[string]string{"message": "Subscription cancelled"})
+	return nil
+}
+
+// cancelWaitlistEntry removes the subscription named in the URL. Nights it
+// held go to the next subscriber.
+func (a *App) cancelWaitlistEntry(r *http.Request, userID string) (bool, error) {
+	entry, err := a.Store.CancelWaitlistEntry(r.Context(), userID, chi.URLParam(r, "id"))
+	if err != nil || entry == nil {
+		return false, err
+	}
+	if entry.HoldUntil != nil && entry.HoldEndedAt == nil {
+		a.queueWaitlistProcessing(r.Context(), entry.RoomID.Hex())
+	}
+	return true, nil
+}
+
+func (a *App) renderWaitlistPage(w http.ResponseWriter, r *http.Request) error {
+	return a.renderWaitlistTemplate(w, r, http.StatusOK, "", "")
+}
+
+func (a *App) cancelWaitlistFromPage(w http.ResponseWriter, r *http.Request) error {
+	user := session.CurrentUser(r)
+	cancelled, err := a.cancelWaitlistEntry(r, user.ID)
+	if err != nil {
+		return err
+	}
+	if !cancelled {
+		return a.renderWaitlistTemplate(w, r, http.StatusNotFound, "", "Waitlist subscription not found")
+	}
+
+	return a.renderWaitlistTemplate(w, r, http.StatusOK, "Subscription cancelled.", "")
+}
+
+func (a *App) renderWaitlistTemplate(w http.ResponseWriter, r *http.Request, statusCode int, noticeMessage, errorMessage string) error {
+	user := session.CurrentUser(r)
+	entries, err := a.Store.ListWaitlistEntries(r.Context(), user.ID, false)
+	if err != nil {
+		return err
+	}
+
+	var list strings.Builder
+	if len(entries) == 0 {
+		list.WriteString(`<p>You are not on any waitlist. When the dates you want are taken, the booking form lets you subscribe to be notified.</p>`)
+	}
+	for _, entry := range entries {
+		hotel := entry.HotelTitle
+		if hotel == "" {
+			hotel = "Hotel no longer listed"
+		}
+		state := waitlistStatusLabels[entry.Status]
+		switch entry.Status {
+		case models.WaitlistStatusActive:
+			state = fmt.Sprintf("%s &middot; position %d in the %s queue", state, entry.Position, view.EscapeHTML(entry.Type))
+		case models.WaitlistStatusHeld:
+			state = fmt.Sprintf("%s until %s", state, entry.HoldUntil.In(time.Local).Format("2006-01-02 15:04"))
+		}
+		dates := fmt.Sprintf("%s to %s", view.EscapeHTML(entry.CheckIn), view.EscapeHTML(entry.CheckOut))
+		if entry.Nights > 0 {
+			dates = fmt.Sprintf("%d night(s) between %s", entry.Nights, dates)
+			if entry.MinNights > 0 && entry.MinNights < entry.Nights {
+				dates = fmt.Sprintf("%s (at least %d)", dates, entry.MinNights)
+			}
+		}
+		action := ""
+		if entry.IsActive || entry.Status == models.WaitlistStatusHeld {
+			label := "Leave waitlist"
+			if !entry.IsActive {
+				label = "Release the room"
+			}
+			action = fmt.Sprintf(`
+        <form method="POST" action="/account/waitlist/%s/cancel" style="display:inline;">
+          <button type="submit" class="btn btn-outline btn-small">%s</button>
+        </form>`, entry.ID.Hex(), label)
+		}
+		list.WriteString(fmt.Sprintf(`
+      <div class="auth-row">
+        <span><a href="/hotels/%s"><strong>%s</strong></a><br />%s &middot; %s</span>%s
+      </div>
+    `,
+			entry.RoomID.Hex(),
+			view.EscapeHTML(hotel),
+			dates,
+			state,
+			action,
+		))
+	}
+
+	return a.renderHTML(w, r, statusCode, "account-waitlist.html", map[string]any{
+		"authControls":  view.Safe(renderAuthControls(user, "/account/waitlist")),
+		"noticeMessage": renderNotice("success", noticeMessage),
+		"errorMessage":  errorMessage,
+		"waitlistBlock": view.Safe(list.String()),
+	})
+}
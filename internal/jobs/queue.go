@@ -0,0 +1,173 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"easybook/internal/models"
+)
+
+// ErrPermanent marks failures that retrying cannot fix; the job goes
+// straight to the dead-letter state.
+var ErrPermanent = errors.New("permanent job failure")
+
+const (
+	jobLease      = 5 * time.Minute
+	jobTimeout    = 2 * time.Minute
+	maxRetryDelay = 6 * time.Hour
+)
+
+// Handler runs one job. Jobs are delivered at least once, so handlers must
+// tolerate running again after a crash or a lost lease.
+type Handler func(ctx context.Context, job models.Job) error
+
+type Config struct {
+	Workers      int
+	MaxAttempts  int
+	RetryDelay   time.Duration
+	PollInterval time.Duration
+}
+
+// Queue runs jobs stored in Mongo on a pool of in-process workers, retrying
+// failures with exponential backoff and dead-lettering jobs that keep
+// failing.
+type Queue struct {
+	store    *models.Store
+	config   Config
+	handlers map[string]Handler
+	kinds    []string
+	wake     chan struct{}
+}
+
+func NewQueue(store *models.Store, config Config) *Queue {
+	if config.Workers <= 0 {
+		config.Workers = 2
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 5
+	}
+	if config.RetryDelay == 0 {
+		config.RetryDelay = 30 * time.Second
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = 5 * time.Second
+	}
+	return &Queue{
+		store:    store,
+		config:   config,
+		handlers: map[string]Handler{},
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// Register sets the handler for kind. Call it before Run; only registered
+// kinds are claimed, so instances running older code leave new kinds alone.
+func (q *Queue) Register(kind string, handler Handler) {
+	if _, ok := q.handlers[kind]; !ok {
+		q.kinds = append(q.kinds, kind)
+	}
+	q.handlers[kind] = handler
+}
+
+// Enqueue stores job and wakes a worker. A job whose key was already used is
+// not added again.
+func (q *Queue) Enqueue(ctx context.Context, job models.Job) error {
+	if _, _, err := q.store.EnqueueJob(ctx, job); err != nil {
+		return fmt.Errorf("enqueue %s job: %w", job.Kind, err)
+	}
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Run processes due jobs until ctx is done.
+func (q *Queue) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < q.config.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.work(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (q *Queue) work(ctx context.Context) {
+	ticker := time.NewTicker(q.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		q.Drain(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-q.wake:
+		}
+	}
+}
+
+// Drain runs due jobs on the calling goroutine until none are left.
+func (q *Queue) Drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		job, err := q.store.ClaimJob(ctx, q.kinds, jobLease)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Claim job: %v", err)
+			}
+			return
+		}
+		if job == nil {
+			return
+		}
+		q.process(ctx, *job)
+	}
+}
+
+func (q *Queue) process(ctx context.Context, job models.Job) {
+	err := q.attempt(ctx, job)
+	switch {
+	case err == nil:
+		err = q.store.CompleteJob(ctx, job.ID)
+	case errors.Is(err, ErrPermanent) || job.Attempts >= q.config.MaxAttempts:
+		log.Printf("Job %s (%s) failed after %d attempt(s): %v", job.ID.Hex(), job.Kind, job.Attempts, err)
+		err = q.store.DeadLetterJob(ctx, job.ID, err.Error())
+	default:
+		err = q.store.RetryJob(ctx, job.ID, err.Error(), time.Now().Add(q.retryDelay(job.Attempts)))
+	}
+	if err != nil {
+		log.Printf("Update job %s: %v", job.ID.Hex(), err)
+	}
+}
+
+func (q *Queue) attempt(ctx context.Context, job models.Job) (err error) {
+	handler, ok := q.handlers[job.Kind]
+	if !ok {
+		return fmt.Errorf("%w: no handler for %s", ErrPermanent, job.Kind)
+	}
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("panic: %v", recovered)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, jobTimeout)
+	defer cancel()
+	return handler(ctx, job)
+}
+
+// retryDelay doubles the configured delay after every failed attempt.
+func (q *Queue) retryDelay(attempts int) time.Duration {
+	delay := q.config.RetryDelay
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryDelay)
+}
@@ -0,0 +1,101 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"easybook/internal/db"
+	"easybook/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestQueueRetriesDeadLettersAndDeduplicates(t *testing.T) {
+	mongoURI := strings.TrimSpace(os.Getenv("MONGO_URI"))
+	if mongoURI == "" {
+		t.Skip("MONGO_URI is not set; skipping integration test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURI))
+	if err != nil {
+		t.Fatalf("connect mongo: %v", err)
+	}
+	defer func() {
+		_ = client.Disconnect(context.Background())
+	}()
+
+	database := client.Database("easybook_jobs_test_" + primitive.NewObjectID().Hex())
+	defer func() {
+		_ = database.Drop(context.Background())
+	}()
+	if err := db.EnsureStartupMaintenance(ctx, database); err != nil {
+		t.Fatalf("ensure indexes: %v", err)
+	}
+
+	store := models.NewStore(database)
+	// A negative delay makes retries due immediately.
+	queue := NewQueue(store, Config{MaxAttempts: 3, RetryDelay: -time.Second})
+	runs := map[string]int{}
+	queue.Register("test.flaky", func(ctx context.Context, job models.Job) error {
+		runs[job.Payload["name"]]++
+		if runs[job.Payload["name"]] < 2 {
+			return errors.New("temporarily unavailable")
+		}
+		return nil
+	})
+	queue.Register("test.broken", func(ctx context.Context, job models.Job) error {
+		return errors.New("always fails")
+	})
+
+	for i := 0; i < 2; i++ {
+		if err := queue.Enqueue(ctx, models.Job{Kind: "test.flaky", Key: "flaky-once", Payload: map[string]string{"name": "flaky"}}); err != nil {
+			t.Fatalf("enqueue: %v", err)
+		}
+	}
+	if err := queue.Enqueue(ctx, models.Job{Kind: "test.broken"}); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	queue.Drain(ctx)
+
+	if runs["flaky"] != 2 {
+		t.Fatalf("expected the keyed job to run once and retry once, got %d runs", runs["flaky"])
+	}
+	done, _, err := store.ListJobs(ctx, models.JobDone, "test.flaky", 0, 10)
+	if err != nil || len(done) != 1 || done[0].Attempts != 2 {
+		t.Fatalf("expected one finished flaky job after 2 attempts, got %+v (%v)", done, err)
+	}
+	if _, _, err := store.EnqueueJob(ctx, models.Job{Kind: "test.mail", Payload: map[string]string{"to": "guest@example.com", "text": "secret link"}}); err != nil {
+		t.Fatalf("enqueue mail job: %v", err)
+	}
+	mails, _, err := store.ListJobs(ctx, "", "test.mail", 0, 10)
+	if err != nil || len(mails) != 1 || mails[0].Payload["to"] == "" || mails[0].Payload["text"] != "" {
+		t.Fatalf("expected the mail body to be left out of the listing, got %+v (%v)", mails, err)
+	}
+	dead, _, err := store.ListJobs(ctx, models.JobDead, "", 0, 10)
+	if err != nil || len(dead) != 1 || dead[0].Kind != "test.broken" || dead[0].Attempts != 3 || dead[0].LastError != "always fails" {
+		t.Fatalf("expected the broken job in the dead letters after 3 attempts, got %+v (%v)", dead, err)
+	}
+
+	requeued, err := store.RequeueJob(ctx, dead[0].ID.Hex())
+	if err != nil || !requeued {
+		t.Fatalf("requeue dead job: %v %v", requeued, err)
+	}
+	job, err := store.FindJob(ctx, dead[0].ID)
+	if err != nil || job.Status != models.JobPending || job.Attempts != 0 {
+		t.Fatalf("expected the dead job to be pending again, got %+v (%v)", job, err)
+	}
+
+	purged, err := store.PurgeFinishedJobs(ctx, time.Now().Add(time.Minute))
+	if err != nil || purged != 1 {
+		t.Fatalf("expected the finished job to be purged, got %d (%v)", purged, err)
+	}
+}
@@ -0,0 +1,15 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryDelayBacksOff(t *testing.T) {
+	queue := NewQueue(nil, Config{RetryDelay: time.Minute})
+	for attempts, want := range map[int]time.Duration{1: time.Minute, 2: 2 * time.Minute, 4: 8 * time.Minute, 30: maxRetryDelay} {
+		if got := queue.retryDelay(attempts); got != want {
+			t.Errorf("retryDelay(%d) = %s, want %s", attempts, got, want)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"easybook/internal/config"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+type Sender interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// ErrInvalidMessage marks messages that can never be sent as given.
+var ErrInvalidMessage = errors.New("invalid mail message")
+
+func NewSender(env config.Env) Sender {
+	switch strings.ToLower(strings.TrimSpace(env.MailDriver)) {
+	case "smtp":
+		return &SMTPSender{
+			Host:     env.SMTPHost,
+			Port:     env.SMTPPort,
+			Username: env.SMTPUsername,
+			Password: env.SMTPPassword,
+			From:     env.MailFrom,
+		}
+	case "file":
+		return &FileSender{Dir: env.MailFileDir, From: env.MailFrom}
+	default:
+		return &LogSender{From: env.MailFrom}
+	}
+}
+
+func validateMessage(message Message) error {
+	if strings.TrimSpace(message.To) == "" {
+		return fmt.Errorf("%w: recipient is required", ErrInvalidMessage)
+	}
+	if strings.TrimSpace(message.Subject) == "" {
+		return fmt.Errorf("%w: subject is required", ErrInvalidMessage)
+	}
+	return nil
+}
+
+// LogSender writes outgoing mail to the server log. Intended for local development.
+type LogSender struct {
+	From string
+}
+
+func (s *LogSender) Send(ctx context.Context, message Message) error {
+	if err := validateMessage(message); err != nil {
+		return err
+	}
+	log.Printf("[mail] from=%s to=%s subject=%q\n%s", s.From, message.To, message.Subject, message.Text)
+	return nil
+}
+
+// FileSender writes each message as an .eml file into Dir so it can be opened in a mail client.
+type FileSender struct {
+	Dir  string
+	From string
+}
+
+var fileSequence uint64
+
+func (s *FileSender) Send(ctx context.Context, message Message) error {
+	if err := validateMessage(message); err != nil {
+		return err
+	}
+
+	dir := strings.TrimSpace(s.Dir)
+	if dir == "" {
+		dir = "tmp/mail"
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("create mail dir: %w", err)
+	}
+
+	now := time.Now().UTC()
+	fileName := fmt.Sprintf("%s-%d.eml", now.Format("20060102T150405"), atomic.AddUint64(&fileSequence, 1))
+
+	return os.WriteFile(filepath.Join(dir, fileName), buildMessage(s.From, message, now), 0o644)
+}
+
+// buildMessage renders message as RFC 5322 text. Messages with an HTML
+// part are sent as multipart/alternative with the text part first.
+func buildMessage(from string, message Message, now time.Time) []byte {
+	var builder strings.Builder
+	builder.WriteString("From: " + from + "\r\n")
+	builder.WriteString("To: " + message.To + "\r\n")
+	builder.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", message.Subject) + "\r\n")
+	builder.WriteString("Date: " + now.Format(time.RFC1123Z) + "\r\n")
+	builder.WriteString("MIME-Version: 1.0\r\n")
+
+	if message.HTML == "" {
+		builder.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+		builder.WriteString(message.Text)
+		return []byte(builder.String())
+	}
+
+	boundary := fmt.Sprintf("easybook-%d-%d", now.UnixNano(), atomic.AddUint64(&fileSequence, 1))
+	builder.WriteString("Content-Type: multipart/alternative; boundary=\"" + boundary + "\"\r\n\r\n")
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain", message.Text},
+		{"text/html", message.HTML},
+	} {
+		builder.WriteString("--" + boundary + "\r\n")
+		builder.WriteString("Content-Type: " + part.contentType + "; charset=utf-8\r\n\r\n")
+		builder.WriteString(part.body + "\r\n")
+	}
+	builder.WriteString("--" + boundary + "--\r\n")
+	return []byte(builder.String())
+}
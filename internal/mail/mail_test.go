@@ -0,0 +1,46 @@
+package mail
+
+import (
+	"errors"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildMessageAddsHTMLAlternative(t *testing.T) {
+	now := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	plain := string(buildMessage("Easy Booking <no-reply@example.com>", Message{To: "guest@example.com", Subject: "Hi", Text: "Hello"}, now))
+	if !strings.Contains(plain, "Content-Type: text/plain; charset=utf-8\r\n\r\nHello") {
+		t.Fatalf("expected a plain text message, got %q", plain)
+	}
+
+	multipart := string(buildMessage("Easy Booking <no-reply@example.com>", Message{To: "guest@example.com", Subject: "Zimmer verfügbar", Text: "Hello", HTML: "<p>Hello</p>"}, now))
+	if !strings.Contains(multipart, "multipart/alternative") || !strings.Contains(multipart, "<p>Hello</p>") {
+		t.Fatalf("expected a multipart message, got %q", multipart)
+	}
+	if strings.Index(multipart, "text/plain") > strings.Index(multipart, "text/html") {
+		t.Fatal("expected the text part before the HTML part")
+	}
+	if !strings.Contains(multipart, "Subject: =?utf-8?q?") {
+		t.Fatalf("expected an encoded subject, got %q", multipart)
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{&textproto.Error{Code: 451, Msg: "try again later"}, true},
+		{&textproto.Error{Code: 550, Msg: "no such user"}, false},
+		{validateMessage(Message{Subject: "Hi"}), false},
+		{errors.New("connection reset by peer"), true},
+	}
+	for _, tc := range cases {
+		if got := IsTransient(tc.err); got != tc.want {
+			t.Errorf("IsTransient(%v) = %t, want %t", tc.err, got, tc.want)
+		}
+	}
+}
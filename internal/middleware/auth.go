@@ -67,6 +67,65 @@ func RequireRole(roles ...string) func(http.Handler) http.Handler {
 	}
 }
 
+func RequireVerifiedEmail(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := session.CurrentUser(r)
+		if user == nil {
+			RequireAuth(next).ServeHTTP(w, r)
+			return
+		}
+		if user.EmailVerified {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if IsAPIRequest(r) {
+			writeJSONError(w, http.StatusForbidden, "Email verification required")
+			return
+		}
+
+		http.Redirect(w, r, "/verify-email?required=1", http.StatusFound)
+	})
+}
+
+// RequireScope lets cookie sessions through and limits API token requests to
+// tokens that were granted scope.
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := session.CurrentUser(r)
+			if user == nil {
+				RequireAuth(next).ServeHTTP(w, r)
+				return
+			}
+			if user.HasScope(scope) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			writeJSONError(w, http.StatusForbidden, "Token is missing the "+scope+" scope")
+		})
+	}
+}
+
+// RequireSessionAuth rejects API token requests on endpoints that manage the
+// account itself, such as sessions and tokens.
+func RequireSessionAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := session.CurrentUser(r)
+		if user == nil {
+			RequireAuth(next).ServeHTTP(w, r)
+			return
+		}
+		if user.APITokenID == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		writeJSONError(w, http.StatusForbidden, "This endpoint requires a signed-in session")
+	})
+}
+
 func CanAccessOwnerResource(r *http.Request, ownerID string) bool {
 	ownerID = strings.TrimSpace(ownerID)
 	if ownerID == "" {
@@ -89,3 +148,44 @@ func writeJSONError(w http.ResponseWriter, status int, message string) {
 	w.WriteHeader(status)
 	_, _ = w.Write([]byte("{\"error\":\"" + message + "\"}"))
 }
+
+// RequireTwoFactorForRoles keeps signed-in users whose role mandates 2FA on the
+// account security page until their session has passed a second factor.
+func RequireTwoFactorForRoles(roles []string) func(http.Handler) http.Handler {
+	requiredRoles := make(map[string]struct{}, len(roles))
+	for _, role := range roles {
+		role = strings.TrimSpace(role)
+		if role != "" {
+			requiredRoles[role] = struct{}{}
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := session.CurrentUser(r)
+			if user == nil || user.TwoFactorVerified || isTwoFactorExemptPath(r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if _, ok := requiredRoles[user.Role]; !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if IsAPIRequest(r) {
+				writeJSONError(w, http.StatusForbidden, "Two-factor authentication required")
+				return
+			}
+
+			http.Redirect(w, r, "/account/security?required=1", http.StatusFound)
+		})
+	}
+}
+
+func isTwoFactorExemptPath(path string) bool {
+	switch path {
+	case "/account/security", "/logout", "/api/auth/session", CSPReportPath:
+		return true
+	}
+	return strings.HasPrefix(path, "/account/2fa/") || strings.HasPrefix(path, "/login")
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"easybook/internal/utils"
+)
+
+func TestClientAddressHonoursOnlyTrustedProxies(t *testing.T) {
+	_, proxies, _ := net.ParseCIDR("10.0.0.0/8")
+	var seen string
+	handler := ClientAddress([]*net.IPNet{proxies})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = utils.ClientIP(r)
+	}))
+
+	cases := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		want       string
+	}{
+		{"direct client", "203.0.113.7:1234", "198.51.100.1", "203.0.113.7"},
+		{"behind a proxy", "10.0.0.2:1234", "198.51.100.1", "198.51.100.1"},
+		{"spoofed first hop", "10.0.0.2:1234", "192.0.2.9, 198.51.100.1, 10.0.0.3", "198.51.100.1"},
+		{"malformed hop", "10.0.0.2:1234", "192.0.2.9, bogus, 10.0.0.3", "10.0.0.3"},
+		{"no header", "10.0.0.2:1234", "", "10.0.0.2"},
+	}
+	for _, tc := range cases {
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		request.RemoteAddr = tc.remoteAddr
+		if tc.forwarded != "" {
+			request.Header.Set("X-Forwarded-For", tc.forwarded)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), request)
+		if seen != tc.want {
+			t.Fatalf("%s: expected %s, got %s", tc.name, tc.want, seen)
+		}
+	}
+}
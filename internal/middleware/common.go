@@ -4,14 +4,27 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"path"
 	"path/filepath"
 	"strings"
 	"time"
+
+	"easybook/internal/utils"
 )
 
+// ClientAddress resolves the client address once per request, honouring
+// forwarding headers only from trusted proxies, for utils.ClientIP.
+func ClientAddress(trusted []*net.IPNet) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, utils.WithClientIP(r, utils.ResolveClientIP(r, trusted)))
+		})
+	}
+}
+
 func RequestLogger(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		log.Printf("[%s] %s %s", time.Now().UTC().Format(time.RFC3339), r.Method, r.URL.RequestURI())
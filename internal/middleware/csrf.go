@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+
+	"easybook/internal/session"
+)
+
+// CSRFProtect rejects state-changing requests that do not echo the client's
+// CSRF token in the X-CSRF-Token header or the _csrf form field. Bearer token
+// requests are exempt because browsers never attach those automatically, and
+// so are CSP violation reports, which change no state.
+func CSRFProtect(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+			next.ServeHTTP(w, r)
+			return
+		}
+		if session.UsesBearerAuth(r) || r.URL.Path == CSPReportPath {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		provided := r.Header.Get(session.CSRFHeaderName)
+		if provided == "" && !IsAPIRequest(r) {
+			provided = r.PostFormValue(session.CSRFFormField)
+		}
+		if session.ValidCSRFToken(r, provided) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if IsAPIRequest(r) {
+			writeJSONError(w, http.StatusForbidden, "Invalid CSRF token")
+			return
+		}
+
+		http.Error(w, "Invalid or missing CSRF token. Reload the page and try again.", http.StatusForbidden)
+	})
+}
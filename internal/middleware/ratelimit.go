@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"time"
+
+	"easybook/internal/ratelimit"
+	"easybook/internal/session"
+	"easybook/internal/utils"
+)
+
+// RateLimitRule is one named bucket family. Requests are counted per API
+// token, signed-in user or client IP, whichever is most specific, unless
+// ByIP forces the IP.
+type RateLimitRule struct {
+	Name  string
+	Limit ratelimit.Limit
+	ByIP  bool
+}
+
+// RateLimit rejects requests over the rule with 429 and reports the bucket in
+// RateLimit-* headers. Storage errors let the request through.
+func RateLimit(store ratelimit.Store, rule RateLimitRule) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if store == nil || !rule.Limit.Enabled() {
+			return next
+		}
+
+		policy := fmt.Sprintf("%d;w=%d", rule.Limit.Requests, int(rule.Limit.Period.Seconds()))
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			result, err := store.Take(r.Context(), rule.Name+":"+rateLimitSubject(r, rule.ByIP), rule.Limit)
+			if err != nil {
+				log.Printf("rate limit %s unavailable: %v", rule.Name, err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			headers := w.Header()
+			headers.Set("RateLimit-Policy", policy)
+			headers.Set("RateLimit-Limit", fmt.Sprint(result.Limit))
+			headers.Set("RateLimit-Remaining", fmt.Sprint(result.Remaining))
+			headers.Set("RateLimit-Reset", fmt.Sprint(ceilSeconds(result.ResetAfter)))
+			if result.Allowed {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			headers.Set("Retry-After", fmt.Sprint(ceilSeconds(result.RetryAfter)))
+			if IsAPIRequest(r) {
+				writeJSONError(w, http.StatusTooManyRequests, "Too many requests")
+				return
+			}
+			http.Error(w, "Too many requests. Please slow down and try again shortly.", http.StatusTooManyRequests)
+		})
+	}
+}
+
+func rateLimitSubject(r *http.Request, byIP bool) string {
+	if !byIP {
+		if user := session.CurrentUser(r); user != nil {
+			if user.APITokenID != "" {
+				return "token:" + user.APITokenID
+			}
+			return "user:" + user.ID
+		}
+	}
+	return "ip:" + utils.ClientIP(r)
+}
+
+func ceilSeconds(duration time.Duration) int {
+	return int(math.Ceil(duration.Seconds()))
+}
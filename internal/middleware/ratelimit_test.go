@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"easybook/internal/ratelimit"
+)
+
+func TestRateLimitSetsHeadersAndRejects(t *testing.T) {
+	handler := RateLimit(ratelimit.NewMemoryStore(), RateLimitRule{
+		Name:  "api",
+		Limit: ratelimit.Limit{Requests: 2, Period: time.Minute},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	call := func(remoteAddr string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodGet, "/api/hotels", nil)
+		request.RemoteAddr = remoteAddr
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	first := call("10.0.0.1:1234")
+	if first.Code != http.StatusOK || first.Header().Get("RateLimit-Remaining") != "1" || first.Header().Get("RateLimit-Policy") != "2;w=60" {
+		t.Fatalf("unexpected first response %d %v", first.Code, first.Header())
+	}
+	call("10.0.0.1:1234")
+
+	denied := call("10.0.0.1:1234")
+	if denied.Code != http.StatusTooManyRequests || denied.Header().Get("Retry-After") != "30" {
+		t.Fatalf("expected 429 with Retry-After 30, got %d %v", denied.Code, denied.Header())
+	}
+	if other := call("10.0.0.2:1234"); other.Code != http.StatusOK {
+		t.Fatalf("expected another client to have its own bucket, got %d", other.Code)
+	}
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSecurityHeadersOverrideKeepsRequestNonce(t *testing.T) {
+	var seenNonce string
+	handler := SecurityHeaders(PageSecurityPolicy())(
+		SecurityHeaders(APISecurityPolicy())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			seenNonce = CSPNonce(r)
+		})),
+	)
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/hotels", nil))
+
+	if seenNonce == "" {
+		t.Fatal("expected a nonce in the request context")
+	}
+	if got := recorder.Header().Get("Content-Security-Policy"); got != "default-src 'none'; frame-ancestors 'none'" {
+		t.Fatalf("expected the group policy to replace the page policy, got %q", got)
+	}
+	if recorder.Header().Get("Permissions-Policy") != "" {
+		t.Fatal("expected Permissions-Policy to be dropped by the API policy")
+	}
+	if recorder.Header().Get("Strict-Transport-Security") != "" {
+		t.Fatal("expected no HSTS header without a max age")
+	}
+}
+
+func TestPageSecurityPolicyUsesNonce(t *testing.T) {
+	policy := PageSecurityPolicy()
+	policy.CSP = policy.CSP.With("img-src", "'self'")
+	policy.CSPReportOnly = true
+
+	var nonce string
+	handler := SecurityHeaders(policy)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nonce = CSPNonce(r)
+	}))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/hotels", nil))
+
+	header := recorder.Header().Get("Content-Security-Policy-Report-Only")
+	if !strings.Contains(header, "script-src 'self' 'nonce-"+nonce+"'") {
+		t.Fatalf("expected script-src with the request nonce, got %q", header)
+	}
+	if !strings.Contains(header, "img-src 'self';") {
+		t.Fatalf("expected img-src override, got %q", header)
+	}
+	if recorder.Header().Get("X-Frame-Options") != "DENY" {
+		t.Fatalf("expected X-Frame-Options DENY, got %q", recorder.Header().Get("X-Frame-Options"))
+	}
+}
@@ -0,0 +1,149 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AccountDeletion summarizes what DeleteUserAccount did with the user's data.
+type AccountDeletion struct {
+	// Rooms freed by cancelled bookings and released waitlist holds.
+	CancelledRoomIDs   []string
+	CancelledBookings  int
+	AnonymizedBookings int64
+}
+
+func (s *Store) UpdateUserProfile(ctx context.Context, userIDText, name, phone, language string) (*User, error) {
+	userID, err := primitive.ObjectIDFromHex(strings.TrimSpace(userIDText))
+	if err != nil {
+		return nil, errors.New("invalid user id")
+	}
+
+	var user User
+	err = s.collection("users").FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": userID},
+		bson.M{"$set": bson.M{
+			"name":              name,
+			"phone":             phone,
+			"preferredLanguage": language,
+			"updatedAt":         time.Now().UTC(),
+		}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&user)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// DeleteUserAccount removes a user and everything tied to the account.
+// Bookings that have not started yet are cancelled and free their rooms;
+// past and current stays are kept for the hotel's records but detached from
+// the user and stripped of their notes. The user document goes last, so a
+// failed run can simply be repeated.
+func (s *Store) DeleteUserAccount(ctx context.Context, userIDText string) (AccountDeletion, error) {
+	result := AccountDeletion{CancelledRoomIDs: []string{}}
+	userID, err := primitive.ObjectIDFromHex(strings.TrimSpace(userIDText))
+	if err != nil {
+		return result, errors.New("invalid user id")
+	}
+
+	user, err := s.FindUserByID(ctx, userIDText)
+	if err != nil {
+		return result, err
+	}
+
+	today := time.Now().In(time.Local).Format("2006-01-02")
+	cursor, err := s.collection(bookingsCollection).Find(
+		ctx,
+		bson.M{"userId": userID, "checkIn": bson.M{"$gt": today}},
+		options.Find().SetProjection(bson.M{"_id": 1, "roomId": 1, "hotelId": 1}),
+	)
+	if err != nil {
+		return result, err
+	}
+	var upcoming []bson.M
+	if err := cursor.All(ctx, &upcoming); err != nil {
+		return result, err
+	}
+
+	for _, booking := range upcoming {
+		bookingID, _ := booking["_id"].(primitive.ObjectID)
+		deleted, err := s.DeleteBookingByID(ctx, bookingID.Hex())
+		if err != nil {
+			return result, err
+		}
+		if deleted == 0 {
+			continue
+		}
+		result.CancelledBookings++
+
+		roomID, ok := booking["roomId"].(primitive.ObjectID)
+		if !ok {
+			roomID, ok = booking["hotelId"].(primitive.ObjectID)
+		}
+		if ok {
+			result.CancelledRoomIDs = append(result.CancelledRoomIDs, roomID.Hex())
+		}
+	}
+
+	heldRooms, err := s.releaseWaitlistHolds(ctx, bson.M{"holdFor": userID})
+	if err != nil {
+		return result, err
+	}
+	for _, roomID := range heldRooms {
+		result.CancelledRoomIDs = append(result.CancelledRoomIDs, roomID.Hex())
+	}
+
+	anonymized, err := s.collection(bookingsCollection).UpdateMany(
+		ctx,
+		bson.M{"userId": userID},
+		bson.M{
+			"$set":   bson.M{"notes": "", "anonymizedAt": time.Now().UTC()},
+			"$unset": bson.M{"userId": ""},
+		},
+	)
+	if err != nil {
+		return result, err
+	}
+	result.AnonymizedBookings = anonymized.ModifiedCount
+
+	for _, name := range []string{
+		waitlistCollection,
+		notificationsCollection,
+		jobsCollection,
+		apiTokensCollection,
+		userIdentitiesCollection,
+		emailVerificationsCollection,
+		passwordResetsCollection,
+		dataExportsCollection,
+	} {
+		if _, err := s.collection(name).DeleteMany(ctx, bson.M{"userId": userID}); err != nil {
+			return result, err
+		}
+	}
+	if _, err := s.collection(notificationPreferencesCollection).DeleteOne(ctx, bson.M{"_id": userID}); err != nil {
+		return result, err
+	}
+	if user != nil {
+		if _, err := s.collection(loginAttemptsCollection).DeleteOne(ctx, bson.M{"_id": LoginAccountKey(user.Email)}); err != nil {
+			return result, err
+		}
+	}
+
+	if _, err := s.collection("users").DeleteOne(ctx, bson.M{"_id": userID}); err != nil {
+		return result, err
+	}
+	return result, nil
+}
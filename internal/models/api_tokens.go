@@ -0,0 +1,205 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	apiTokensCollection = "api_tokens"
+	apiTokenPrefix      = "ebk_"
+	apiTokenTouchEvery  = time.Minute
+	maxAPITokensPerUser = 20
+)
+
+const (
+	ScopeBookingsRead       = "bookings:read"
+	ScopeBookingsWrite      = "bookings:write"
+	ScopeNotificationsRead  = "notifications:read"
+	ScopeNotificationsWrite = "notifications:write"
+	ScopeHotelsWrite        = "hotels:write"
+)
+
+var APITokenScopes = []string{
+	ScopeBookingsRead,
+	ScopeBookingsWrite,
+	ScopeNotificationsRead,
+	ScopeNotificationsWrite,
+	ScopeHotelsWrite,
+}
+
+type APIToken struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID     primitive.ObjectID `bson:"userId" json:"-"`
+	Name       string             `bson:"name" json:"name"`
+	TokenHash  string             `bson:"tokenHash" json:"-"`
+	Prefix     string             `bson:"prefix" json:"prefix"`
+	Scopes     []string           `bson:"scopes" json:"scopes"`
+	CreatedAt  time.Time          `bson:"createdAt" json:"createdAt"`
+	ExpiresAt  *time.Time         `bson:"expiresAt,omitempty" json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time         `bson:"lastUsedAt,omitempty" json:"lastUsedAt,omitempty"`
+}
+
+func NormalizeAPITokenScopes(scopes []string) ([]string, error) {
+	allowed := make(map[string]struct{}, len(APITokenScopes))
+	for _, scope := range APITokenScopes {
+		allowed[scope] = struct{}{}
+	}
+
+	seen := map[string]struct{}{}
+	result := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		if scope == "" {
+			continue
+		}
+		if _, ok := allowed[scope]; !ok {
+			return nil, fmt.Errorf("%w: unknown scope %q", ErrInvalidAPITokenPayload, scope)
+		}
+		if _, duplicate := seen[scope]; duplicate {
+			continue
+		}
+		seen[scope] = struct{}{}
+		result = append(result, scope)
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("%w: at least one scope is required", ErrInvalidAPITokenPayload)
+	}
+	return result, nil
+}
+
+// CreateAPIToken stores a new token for the user and returns the raw value. Only
+// its hash is persisted, so the raw value cannot be shown again later.
+func (s *Store) CreateAPIToken(ctx context.Context, userIDText, name string, scopes []string, expiresAt *time.Time) (string, *APIToken, error) {
+	userID, err := primitive.ObjectIDFromHex(strings.TrimSpace(userIDText))
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: invalid user id", ErrInvalidAPITokenPayload)
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 60 {
+		return "", nil, fmt.Errorf("%w: name must be 1-60 characters", ErrInvalidAPITokenPayload)
+	}
+	scopes, err = NormalizeAPITokenScopes(scopes)
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := time.Now().UTC()
+	if expiresAt != nil && !expiresAt.After(now) {
+		return "", nil, fmt.Errorf("%w: expiry must be in the future", ErrInvalidAPITokenPayload)
+	}
+
+	count, err := s.collection(apiTokensCollection).CountDocuments(ctx, bson.M{"userId": userID})
+	if err != nil {
+		return "", nil, err
+	}
+	if count >= maxAPITokensPerUser {
+		return "", nil, fmt.Errorf("%w: token limit reached", ErrInvalidAPITokenPayload)
+	}
+
+	secret, err := generateSecretToken()
+	if err != nil {
+		return "", nil, err
+	}
+	raw := apiTokenPrefix + secret
+
+	token := &APIToken{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		Name:      name,
+		TokenHash: hashSecretToken(raw),
+		Prefix:    raw[:len(apiTokenPrefix)+6],
+		Scopes:    scopes,
+		CreatedAt: now,
+		ExpiresAt: expiresAt,
+	}
+	if _, err := s.collection(apiTokensCollection).InsertOne(ctx, token); err != nil {
+		return "", nil, err
+	}
+
+	return raw, token, nil
+}
+
+func (s *Store) ListAPITokens(ctx context.Context, userIDText string) ([]APIToken, error) {
+	userID, err := primitive.ObjectIDFromHex(strings.TrimSpace(userIDText))
+	if err != nil {
+		return []APIToken{}, nil
+	}
+
+	cursor, err := s.collection(apiTokensCollection).Find(
+		ctx,
+		bson.M{"userId": userID},
+		options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	tokens := []APIToken{}
+	if err := cursor.All(ctx, &tokens); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func (s *Store) RevokeAPIToken(ctx context.Context, userIDText, tokenIDText string) (bool, error) {
+	userID, err := primitive.ObjectIDFromHex(strings.TrimSpace(userIDText))
+	if err != nil {
+		return false, nil
+	}
+	tokenID, err := primitive.ObjectIDFromHex(strings.TrimSpace(tokenIDText))
+	if err != nil {
+		return false, nil
+	}
+
+	result, err := s.collection(apiTokensCollection).DeleteOne(ctx, bson.M{"_id": tokenID, "userId": userID})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount > 0, nil
+}
+
+// AuthenticateAPIToken resolves a raw bearer token to its owner. It returns
+// nil without an error when the token is unknown or expired.
+func (s *Store) AuthenticateAPIToken(ctx context.Context, raw string) (*APIToken, *User, error) {
+	raw = strings.TrimSpace(raw)
+	if !strings.HasPrefix(raw, apiTokenPrefix) {
+		return nil, nil, nil
+	}
+
+	var token APIToken
+	err := s.collection(apiTokensCollection).FindOne(ctx, bson.M{"tokenHash": hashSecretToken(raw)}).Decode(&token)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now().UTC()
+	if token.ExpiresAt != nil && !token.ExpiresAt.After(now) {
+		return nil, nil, nil
+	}
+
+	user, err := s.FindUserByID(ctx, token.UserID.Hex())
+	if err != nil || user == nil {
+		return nil, nil, err
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= apiTokenTouchEvery {
+		_, _ = s.collection(apiTokensCollection).UpdateOne(ctx, bson.M{"_id": token.ID}, bson.M{"$set": bson.M{"lastUsedAt": now}})
+		token.LastUsedAt = &now
+	}
+
+	return &token, user, nil
+}
@@ -186,7 +186,9 @@ func (s *Store) FindBookingByIDWithDetails(ctx context.Context, id string) (bson
 	return items[0], nil
 }
 
-func (s *Store) CheckRoomAvailability(ctx context.Context, roomIDText, checkIn, checkOut string, excludeBookingIDText string) (bool, error) {
+// CheckRoomAvailability reports whether the room is free for the stay. Nights
+// held for userIDText count as free.
+func (s *Store) CheckRoomAvailability(ctx context.Context, roomIDText, checkIn, checkOut, excludeBookingIDText, userIDText string) (bool, error) {
 	roomID, err := primitive.ObjectIDFromHex(strings.TrimSpace(roomIDText))
 	if err != nil {
 		return false, fmt.Errorf("%w: invalid room id", ErrInvalidBookingPayload)
@@ -205,7 +207,12 @@ func (s *Store) CheckRoomAvailability(ctx context.Context, roomIDText, checkIn,
 		excludeID = &parsed
 	}
 
-	conflict, err := s.hasBookingConflict(ctx, roomID, strings.TrimSpace(checkIn), strings.TrimSpace(checkOut), excludeID)
+	var holderID *primitive.ObjectID
+	if parsed, parseErr := primitive.ObjectIDFromHex(strings.TrimSpace(userIDText)); parseErr == nil {
+		holderID = &parsed
+	}
+
+	conflict, err := s.hasBookingConflict(ctx, roomID, strings.TrimSpace(checkIn), strings.TrimSpace(checkOut), excludeID, holderID)
 	if err != nil {
 		return false, err
 	}
@@ -255,7 +262,11 @@ func (s *Store) CreateBooking(ctx context.Context, booking bson.M, userID string
 	}
 
 	err = s.runAtomically(ctx, func(txCtx context.Context) error {
-		conflict, conflictErr := s.hasBookingConflict(txCtx, roomID, checkIn, checkOut, nil)
+		if holdErr := s.takeOverWaitlistHolds(txCtx, roomID, ownerID, checkIn, checkOut); holdErr != nil {
+			return holdErr
+		}
+
+		conflict, conflictErr := s.hasBookingConflict(txCtx, roomID, checkIn, checkOut, nil, nil)
 		if conflictErr != nil {
 			return conflictErr
 		}
@@ -280,6 +291,7 @@ func (s *Store) CreateBooking(ctx context.Context, booking bson.M, userID string
 		return "", err
 	}
 
+	s.notifyBooking(ctx, doc, NotificationBookingConfirmed, "Booking confirmed", "Your stay at %s from %s to %s is confirmed.")
 	return bookingID.Hex(), nil
 }
 
@@ -290,6 +302,7 @@ func (s *Store) UpdateBookingByID(ctx context.Context, id string, booking bson.M
 	}
 
 	matchedCount := int64(0)
+	var updated bson.M
 	err = s.runAtomically(ctx, func(txCtx context.Context) error {
 		var existing bson.M
 		findErr := s.collection(bookingsCollection).FindOne(txCtx, bson.M{"_id": objectID}).Decode(&existing)
@@ -336,7 +349,13 @@ func (s *Store) UpdateBookingByID(ctx context.Context, id string, booking bson.M
 			return fmt.Errorf("%w: check-in date must be today or later", ErrInvalidBookingPayload)
 		}
 
-		conflict, conflictErr := s.hasBookingConflict(txCtx, nextRoomID, nextCheckIn, nextCheckOut, &objectID)
+		if ownerID, ok := existing["userId"].(primitive.ObjectID); ok {
+			if holdErr := s.takeOverWaitlistHolds(txCtx, nextRoomID, ownerID, nextCheckIn, nextCheckOut); holdErr != nil {
+				return holdErr
+			}
+		}
+
+		conflict, conflictErr := s.hasBookingConflict(txCtx, nextRoomID, nextCheckIn, nextCheckOut, &objectID, nil)
 		if conflictErr != nil {
 			return conflictErr
 		}
@@ -381,14 +400,24 @@ func (s *Store) UpdateBookingByID(ctx context.Context, id string, booking bson.M
 			bson.M{"_id": objectID},
 			bson.M{"$set": updateFields},
 		)
-		return updateErr
+		if updateErr != nil {
+			return updateErr
+		}
+
+		updated = existing
+		for key, value := range updateFields {
+			updated[key] = value
+		}
+		return nil
 	})
 	if err != nil {
 		return 0, err
 	}
 
+	if updated != nil {
+		s.notifyBooking(ctx, updated, NotificationBookingUpdated, "Booking updated", "Your booking at %s now runs from %s to %s.")
+	}
 	return matchedCount, nil
-
 }
 
 func (s *Store) DeleteBookingByID(ctx context.Context, id string) (int64, error) {
@@ -397,16 +426,16 @@ func (s *Store) DeleteBookingByID(ctx context.Context, id string) (int64, error)
 		return 0, nil
 	}
 
-	deletedCount := int64(0)
+	var deleted bson.M
 	err = s.runAtomically(ctx, func(txCtx context.Context) error {
-		result, deleteErr := s.collection(bookingsCollection).DeleteOne(txCtx, bson.M{"_id": objectID})
+		deleted = nil
+		deleteErr := s.collection(bookingsCollection).FindOneAndDelete(txCtx, bson.M{"_id": objectID}).Decode(&deleted)
+		if errors.Is(deleteErr, mongo.ErrNoDocuments) {
+			return nil
+		}
 		if deleteErr != nil {
 			return deleteErr
 		}
-		deletedCount = result.DeletedCount
-		if deletedCount == 0 {
-			return nil
-		}
 
 		_, calendarErr := s.collection(roomCalendarCollection).DeleteMany(txCtx, bson.M{"bookingId": objectID})
 		return calendarErr
@@ -414,11 +443,17 @@ func (s *Store) DeleteBookingByID(ctx context.Context, id string) (int64, error)
 	if err != nil {
 		return 0, err
 	}
+	if deleted == nil {
+		return 0, nil
+	}
 
-	return deletedCount, nil
+	s.notifyBooking(ctx, deleted, NotificationBookingCancelled, "Booking cancelled", "Your stay at %s from %s to %s has been cancelled.")
+	return 1, nil
 }
 
-func (s *Store) hasBookingConflict(ctx context.Context, roomID primitive.ObjectID, checkIn, checkOut string, excludeBookingID *primitive.ObjectID) (bool, error) {
+// hasBookingConflict reports whether anything occupies a night of the stay.
+// Waitlist holds count until they expire, except those of holderID.
+func (s *Store) hasBookingConflict(ctx context.Context, roomID primitive.ObjectID, checkIn, checkOut string, excludeBookingID, holderID *primitive.ObjectID) (bool, error) {
 	newCheckInDate, newCheckOutDate, err := parseBookingDateRange(checkIn, checkOut)
 	if err != nil {
 		return false, err
@@ -430,12 +465,16 @@ func (s *Store) hasBookingConflict(ctx context.Context, roomID primitive.ObjectI
 	}
 	if len(days) > 0 {
 		calendarFilter := bson.M{
-			"roomId": roomID,
-			"day":    bson.M{"$in": days},
+			"roomId":    roomID,
+			"day":       bson.M{"$in": days},
+			"holdUntil": bson.M{"$not": bson.M{"$lte": time.Now().UTC()}},
 		}
 		if excludeBookingID != nil {
 			calendarFilter["bookingId"] = bson.M{"$ne": *excludeBookingID}
 		}
+		if holderID != nil {
+			calendarFilter["holdFor"] = bson.M{"$ne": *holderID}
+		}
 
 		calendarCount, calendarErr := s.collection(roomCalendarCollection).CountDocuments(ctx, calendarFilter)
 		if calendarErr != nil {
@@ -0,0 +1,212 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// reviewRequestWindowDays bounds how far back SendReviewRequests looks, so a
+// scheduler that was down for a few days still catches up without mailing
+// guests about stays from long ago.
+const reviewRequestWindowDays = 7
+
+// notifyBooking tells the booking's owner about a change. textFormat receives
+// the hotel title, check-in and check-out. Failures are logged rather than
+// returned: the booking itself has already been saved.
+func (s *Store) notifyBooking(ctx context.Context, booking bson.M, notificationType, title, textFormat string) {
+	notification, ok := s.bookingNotification(ctx, booking, notificationType, title, textFormat)
+	if !ok {
+		return
+	}
+	if _, err := s.insertNotification(ctx, notification); err != nil {
+		bookingID, _ := booking["_id"].(primitive.ObjectID)
+		log.Printf("Notify user %s about booking %s: %v", notification.UserID.Hex(), bookingID.Hex(), err)
+	}
+}
+
+// bookingNotification builds a notification to the booking's owner linking
+// to the booking. It reports false for bookings without an owner.
+func (s *Store) bookingNotification(ctx context.Context, booking bson.M, notificationType, title, textFormat string) (Notification, bool) {
+	userID, ok := booking["userId"].(primitive.ObjectID)
+	if !ok {
+		return Notification{}, false
+	}
+	bookingID, _ := booking["_id"].(primitive.ObjectID)
+
+	return Notification{
+		UserID: userID,
+		Type:   notificationType,
+		Title:  title,
+		Text:   fmt.Sprintf(textFormat, s.bookingHotelTitle(ctx, booking), booking["checkIn"], booking["checkOut"]),
+		Link:   "/bookings/" + bookingID.Hex(),
+	}, true
+}
+
+func (s *Store) bookingHotelTitle(ctx context.Context, booking bson.M) string {
+	roomID, ok := booking["roomId"].(primitive.ObjectID)
+	if !ok {
+		roomID, ok = booking["hotelId"].(primitive.ObjectID)
+	}
+	if ok {
+		hotel, err := s.FindHotelByID(ctx, roomID.Hex(), bson.M{"title": 1})
+		if err == nil && hotel != nil {
+			if title := strings.TrimSpace(fmt.Sprint(hotel["title"])); title != "" && title != "<nil>" {
+				return title
+			}
+		}
+	}
+	return "your hotel"
+}
+
+// SendBookingReminders notifies guests whose stay starts in exactly one of
+// days days. Each reminder is sent at most once per booking, so the job can
+// run as often as needed. It returns how many reminders went out.
+func (s *Store) SendBookingReminders(ctx context.Context, days []int) (int, error) {
+	today := toLocalDate(time.Now())
+	sent := 0
+	for _, daysAhead := range days {
+		if daysAhead <= 0 {
+			continue
+		}
+		checkIn := today.AddDate(0, 0, daysAhead).Format("2006-01-02")
+		bookings, err := s.findBookingsForNotification(ctx, bson.M{
+			"checkIn":       checkIn,
+			"remindersSent": bson.M{"$ne": daysAhead},
+		})
+		if err != nil {
+			return sent, err
+		}
+
+		text := "Your stay at %s starts on %s (check-out %s)."
+		if daysAhead == 1 {
+			text = "Your stay at %s starts tomorrow, %s (check-out %s)."
+		}
+		for _, booking := range bookings {
+			notification, ok := s.bookingNotification(ctx, booking, NotificationBookingReminder, "Upcoming stay", text)
+			if !ok {
+				continue
+			}
+			claimed, err := s.claimBookingNotification(ctx, booking["_id"],
+				bson.M{"remindersSent": bson.M{"$ne": daysAhead}},
+				bson.M{"$addToSet": bson.M{"remindersSent": daysAhead}},
+			)
+			if err != nil {
+				return sent, err
+			}
+			if !claimed {
+				continue
+			}
+			if _, err := s.insertNotification(ctx, notification); err != nil {
+				s.releaseBookingNotification(ctx, booking["_id"], bson.M{"$pull": bson.M{"remindersSent": daysAhead}})
+				return sent, err
+			}
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+// SendReviewRequests asks guests to review their stay afterDays days after
+// check-out. Stays are only asked about once.
+func (s *Store) SendReviewRequests(ctx context.Context, afterDays int) (int, error) {
+	if afterDays <= 0 {
+		return 0, nil
+	}
+	latest := toLocalDate(time.Now()).AddDate(0, 0, -afterDays)
+	earliest := latest.AddDate(0, 0, -reviewRequestWindowDays)
+	bookings, err := s.findBookingsForNotification(ctx, bson.M{
+		"checkOut": bson.M{
+			"$gte": earliest.Format("2006-01-02"),
+			"$lte": latest.Format("2006-01-02"),
+		},
+		"reviewRequestedAt": bson.M{"$exists": false},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, booking := range bookings {
+		userID, ok := booking["userId"].(primitive.ObjectID)
+		if !ok {
+			continue
+		}
+		claimed, err := s.claimBookingNotification(ctx, booking["_id"],
+			bson.M{"reviewRequestedAt": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"reviewRequestedAt": time.Now().UTC()}},
+		)
+		if err != nil {
+			return sent, err
+		}
+		if !claimed {
+			continue
+		}
+
+		roomID, ok := booking["roomId"].(primitive.ObjectID)
+		if !ok {
+			roomID, _ = booking["hotelId"].(primitive.ObjectID)
+		}
+		_, err = s.insertNotification(ctx, Notification{
+			UserID: userID,
+			Type:   NotificationReviewRequest,
+			Title:  "How was your stay?",
+			Text:   fmt.Sprintf("Tell other guests about your stay at %s from %s to %s.", s.bookingHotelTitle(ctx, booking), booking["checkIn"], booking["checkOut"]),
+			Link:   "/hotels/" + roomID.Hex(),
+		})
+		if err != nil {
+			s.releaseBookingNotification(ctx, booking["_id"], bson.M{"$unset": bson.M{"reviewRequestedAt": ""}})
+			return sent, err
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// findBookingsForNotification returns active bookings that still have an
+// owner and match filter.
+func (s *Store) findBookingsForNotification(ctx context.Context, filter bson.M) ([]bson.M, error) {
+	filter["userId"] = bson.M{"$exists": true}
+	filter["status"] = bson.M{"$nin": []string{"cancelled", "canceled"}}
+
+	cursor, err := s.collection(bookingsCollection).Find(
+		ctx,
+		filter,
+		options.Find().SetProjection(bson.M{
+			"_id": 1, "userId": 1, "roomId": 1, "hotelId": 1, "checkIn": 1, "checkOut": 1,
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	var bookings []bson.M
+	if err := cursor.All(ctx, &bookings); err != nil {
+		return nil, err
+	}
+	return bookings, nil
+}
+
+// claimBookingNotification marks a booking as notified when guard still
+// matches, so concurrent runs never send the same notification twice.
+func (s *Store) claimBookingNotification(ctx context.Context, bookingID any, guard, update bson.M) (bool, error) {
+	guard["_id"] = bookingID
+	result, err := s.collection(bookingsCollection).UpdateOne(ctx, guard, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// releaseBookingNotification undoes a claim whose notification could not be
+// saved, so the next run tries again.
+func (s *Store) releaseBookingNotification(ctx context.Context, bookingID any, update bson.M) {
+	if _, err := s.collection(bookingsCollection).UpdateOne(ctx, bson.M{"_id": bookingID}, update); err != nil {
+		log.Printf("Release notification claim on booking %v: %v", bookingID, err)
+	}
+}
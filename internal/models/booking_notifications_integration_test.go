@@ -0,0 +1,146 @@
+package models
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"easybook/internal/db"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestBookingLifecycleNotifications(t *testing.T) {
+	mongoURI := strings.TrimSpace(os.Getenv("MONGO_URI"))
+	if mongoURI == "" {
+		t.Skip("MONGO_URI is not set; skipping integration test")
+	}
+
+	dbName := "easybook_test_" + primitive.NewObjectID().Hex()
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURI))
+	if err != nil {
+		t.Fatalf("connect mongo: %v", err)
+	}
+	defer func() {
+		_ = client.Disconnect(context.Background())
+	}()
+
+	database := client.Database(dbName)
+	defer func() {
+		_ = database.Drop(context.Background())
+	}()
+
+	if err := ensureTransactionsSupported(ctx, client); err != nil {
+		t.Skipf("transactions are not supported in this Mongo deployment: %v", err)
+	}
+	if err := db.EnsureStartupMaintenance(ctx, database); err != nil {
+		t.Fatalf("ensure indexes: %v", err)
+	}
+
+	store := NewStore(database)
+	userID := primitive.NewObjectID()
+	roomID := primitive.NewObjectID()
+	if _, err := database.Collection("hotels").InsertOne(ctx, bson.M{"_id": roomID, "title": "Harbour View"}); err != nil {
+		t.Fatalf("insert hotel: %v", err)
+	}
+
+	countByType := func(notificationType string) int64 {
+		t.Helper()
+		count, err := database.Collection(notificationsCollection).CountDocuments(ctx, bson.M{"userId": userID, "type": notificationType})
+		if err != nil {
+			t.Fatalf("count notifications: %v", err)
+		}
+		return count
+	}
+
+	today := toLocalDate(time.Now())
+	bookingID, err := store.CreateBooking(ctx, bson.M{
+		"roomId":   roomID,
+		"checkIn":  today.AddDate(0, 0, 10).Format("2006-01-02"),
+		"checkOut": today.AddDate(0, 0, 12).Format("2006-01-02"),
+	}, userID.Hex())
+	if err != nil {
+		t.Fatalf("create booking: %v", err)
+	}
+	if countByType(NotificationBookingConfirmed) != 1 {
+		t.Fatal("expected a confirmation notification")
+	}
+
+	var confirmation Notification
+	if err := database.Collection(notificationsCollection).FindOne(ctx, bson.M{"type": NotificationBookingConfirmed}).Decode(&confirmation); err != nil {
+		t.Fatalf("find confirmation: %v", err)
+	}
+	if !strings.Contains(confirmation.Text, "Harbour View") || confirmation.Link != "/bookings/"+bookingID {
+		t.Fatalf("unexpected confirmation: %+v", confirmation)
+	}
+
+	if _, err := store.UpdateBookingByID(ctx, bookingID, bson.M{
+		"checkIn":  today.AddDate(0, 0, 1).Format("2006-01-02"),
+		"checkOut": today.AddDate(0, 0, 3).Format("2006-01-02"),
+	}); err != nil {
+		t.Fatalf("update booking: %v", err)
+	}
+	if countByType(NotificationBookingUpdated) != 1 {
+		t.Fatal("expected a change notification")
+	}
+
+	for run := 0; run < 2; run++ {
+		sent, err := store.SendBookingReminders(ctx, []int{3, 1})
+		if err != nil {
+			t.Fatalf("send reminders: %v", err)
+		}
+		if want := 1 - run; sent != want {
+			t.Fatalf("run %d: expected %d reminders, got %d", run, want, sent)
+		}
+	}
+
+	pastStay := bson.M{
+		"_id":      primitive.NewObjectID(),
+		"userId":   userID,
+		"roomId":   roomID,
+		"checkIn":  today.AddDate(0, 0, -4).Format("2006-01-02"),
+		"checkOut": today.AddDate(0, 0, -2).Format("2006-01-02"),
+		"status":   "confirmed",
+	}
+	if _, err := database.Collection(bookingsCollection).InsertOne(ctx, pastStay); err != nil {
+		t.Fatalf("insert past stay: %v", err)
+	}
+	setValidator := func(validator bson.M) {
+		t.Helper()
+		if err := database.RunCommand(ctx, bson.D{{Key: "collMod", Value: notificationsCollection}, {Key: "validator", Value: validator}}).Err(); err != nil {
+			t.Fatalf("set notification validator: %v", err)
+		}
+	}
+	setValidator(bson.M{"type": bson.M{"$ne": NotificationReviewRequest}})
+	if _, err := store.SendReviewRequests(ctx, 1); err == nil {
+		t.Fatal("expected the rejected review request to fail")
+	}
+	if count, err := database.Collection(bookingsCollection).CountDocuments(ctx, bson.M{"_id": pastStay["_id"], "reviewRequestedAt": bson.M{"$exists": true}}); err != nil || count != 0 {
+		t.Fatalf("expected the failed review request to be released, got %d (%v)", count, err)
+	}
+	setValidator(bson.M{})
+	for run := 0; run < 2; run++ {
+		sent, err := store.SendReviewRequests(ctx, 1)
+		if err != nil {
+			t.Fatalf("send review requests: %v", err)
+		}
+		if want := 1 - run; sent != want {
+			t.Fatalf("run %d: expected %d review requests, got %d", run, want, sent)
+		}
+	}
+
+	if deleted, err := store.DeleteBookingByID(ctx, bookingID); err != nil || deleted != 1 {
+		t.Fatalf("delete booking: deleted=%d err=%v", deleted, err)
+	}
+	if countByType(NotificationBookingCancelled) != 1 {
+		t.Fatal("expected a cancellation notification")
+	}
+}
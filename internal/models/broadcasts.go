@@ -0,0 +1,434 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const broadcastsCollection = "broadcasts"
+
+const (
+	maxBroadcastTitleLen = 120
+	maxBroadcastTextLen  = 2000
+)
+
+// Broadcast audiences.
+const (
+	BroadcastAll = "all"
+	// BroadcastBookings reaches users with a booking at HotelID and/or
+	// overlapping From–To.
+	BroadcastBookings = "bookings"
+	BroadcastRole     = "role"
+)
+
+var BroadcastAudiences = []string{BroadcastAll, BroadcastBookings, BroadcastRole}
+
+const (
+	BroadcastSending = "sending"
+	BroadcastSent    = "sent"
+)
+
+type BroadcastTarget struct {
+	Audience string `bson:"audience" json:"audience"`
+	HotelID  string `bson:"hotelId,omitempty" json:"hotelId,omitempty"`
+	From     string `bson:"from,omitempty" json:"from,omitempty"`
+	To       string `bson:"to,omitempty" json:"to,omitempty"`
+	Role     string `bson:"role,omitempty" json:"role,omitempty"`
+}
+
+// Broadcast is an announcement sent as a notification to every user the
+// target matches. Delivery runs in batches ordered by user id; LastUserID is
+// where the next batch starts.
+type Broadcast struct {
+	ID             primitive.ObjectID `bson:"_id" json:"id"`
+	Title          string             `bson:"title" json:"title"`
+	Text           string             `bson:"text" json:"text"`
+	Link           string             `bson:"link,omitempty" json:"link,omitempty"`
+	Target         BroadcastTarget    `bson:"target" json:"target"`
+	Status         string             `bson:"status" json:"status"`
+	Recipients     int64              `bson:"recipients" json:"recipients"`
+	LastUserID     primitive.ObjectID `bson:"lastUserId,omitempty" json:"-"`
+	CreatedBy      primitive.ObjectID `bson:"createdBy" json:"-"`
+	CreatedByEmail string             `bson:"createdByEmail" json:"createdBy"`
+	CreatedAt      time.Time          `bson:"createdAt" json:"createdAt"`
+	FinishedAt     *time.Time         `bson:"finishedAt,omitempty" json:"finishedAt,omitempty"`
+
+	// Filled in when listed: recipients who have read it in-app.
+	Read int64 `bson:"-" json:"read"`
+}
+
+// normalize validates t and drops the fields its audience does not use.
+func (t BroadcastTarget) normalize() (BroadcastTarget, error) {
+	target := BroadcastTarget{Audience: strings.ToLower(strings.TrimSpace(t.Audience))}
+	switch target.Audience {
+	case BroadcastAll:
+	case BroadcastRole:
+		target.Role = strings.TrimSpace(t.Role)
+		if target.Role == "" {
+			return target, fmt.Errorf("%w: role is required", ErrInvalidBroadcast)
+		}
+	case BroadcastBookings:
+		target.HotelID = strings.TrimSpace(t.HotelID)
+		target.From = strings.TrimSpace(t.From)
+		target.To = strings.TrimSpace(t.To)
+		if target.HotelID == "" && target.From == "" && target.To == "" {
+			return target, fmt.Errorf("%w: choose a hotel, a date range or both", ErrInvalidBroadcast)
+		}
+		if target.HotelID != "" && !primitive.IsValidObjectID(target.HotelID) {
+			return target, fmt.Errorf("%w: invalid hotel id", ErrInvalidBroadcast)
+		}
+		if target.From != "" || target.To != "" {
+			from, err := time.ParseInLocation("2006-01-02", target.From, time.Local)
+			if err != nil {
+				return target, fmt.Errorf("%w: from must be a YYYY-MM-DD date", ErrInvalidBroadcast)
+			}
+			to, err := time.ParseInLocation("2006-01-02", target.To, time.Local)
+			if err != nil {
+				return target, fmt.Errorf("%w: to must be a YYYY-MM-DD date", ErrInvalidBroadcast)
+			}
+			if to.Before(from) {
+				return target, fmt.Errorf("%w: to must not be before from", ErrInvalidBroadcast)
+			}
+		}
+	default:
+		return target, fmt.Errorf("%w: audience must be one of %s", ErrInvalidBroadcast, strings.Join(BroadcastAudiences, ", "))
+	}
+	return target, nil
+}
+
+// broadcastRecipientFilter returns the users filter for target.
+func (s *Store) broadcastRecipientFilter(ctx context.Context, target BroadcastTarget) (bson.M, error) {
+	switch target.Audience {
+	case BroadcastRole:
+		return bson.M{"role": target.Role}, nil
+	case BroadcastBookings:
+		bookings := bson.M{
+			"userId": bson.M{"$exists": true},
+			"status": bson.M{"$nin": []string{"cancelled", "canceled"}},
+		}
+		if target.HotelID != "" {
+			// Older bookings reference the hotel as hotelId or by its hex id.
+			hotelID, _ := primitive.ObjectIDFromHex(target.HotelID)
+			bookings["$or"] = bson.A{
+				bson.M{"roomId": hotelID},
+				bson.M{"roomId": hotelID.Hex()},
+				bson.M{"hotelId": hotelID},
+				bson.M{"hotelId": hotelID.Hex()},
+			}
+		}
+		if target.From != "" {
+			// Stays overlapping the range, both days included.
+			bookings["checkIn"] = bson.M{"$lte": target.To}
+			bookings["checkOut"] = bson.M{"$gt": target.From}
+		}
+		userIDs, err := s.collection(bookingsCollection).Distinct(ctx, "userId", bookings)
+		if err != nil {
+			return nil, err
+		}
+		return bson.M{"_id": bson.M{"$in": userIDs}}, nil
+	default:
+		return bson.M{}, nil
+	}
+}
+
+// CreateBroadcast validates and stores draft, which carries the message,
+// target and author. Delivery starts with DeliverBroadcastBatch.
+func (s *Store) CreateBroadcast(ctx context.Context, draft Broadcast) (*Broadcast, error) {
+	title := strings.TrimSpace(draft.Title)
+	text := strings.TrimSpace(draft.Text)
+	link := strings.TrimSpace(draft.Link)
+	switch {
+	case title == "":
+		return nil, fmt.Errorf("%w: title is required", ErrInvalidBroadcast)
+	case len(title) > maxBroadcastTitleLen:
+		return nil, fmt.Errorf("%w: title must be at most %d characters", ErrInvalidBroadcast, maxBroadcastTitleLen)
+	case text == "":
+		return nil, fmt.Errorf("%w: text is required", ErrInvalidBroadcast)
+	case len(text) > maxBroadcastTextLen:
+		return nil, fmt.Errorf("%w: text must be at most %d characters", ErrInvalidBroadcast, maxBroadcastTextLen)
+	case link != "" && (!strings.HasPrefix(link, "/") || strings.HasPrefix(link, "//")):
+		return nil, fmt.Errorf("%w: link must be a path on this site", ErrInvalidBroadcast)
+	}
+	target, err := draft.Target.normalize()
+	if err != nil {
+		return nil, err
+	}
+
+	broadcast := &Broadcast{
+		ID:             primitive.NewObjectID(),
+		Title:          title,
+		Text:           text,
+		Link:           link,
+		Target:         target,
+		Status:         BroadcastSending,
+		CreatedBy:      draft.CreatedBy,
+		CreatedByEmail: draft.CreatedByEmail,
+		CreatedAt:      time.Now().UTC(),
+	}
+	if _, err := s.collection(broadcastsCollection).InsertOne(ctx, broadcast); err != nil {
+		return nil, err
+	}
+	return broadcast, nil
+}
+
+// DeliverBroadcastBatch notifies the next batchSize recipients of a sending
+// broadcast and returns its updated state; the status turns to sent with the
+// last batch. Users who already got the broadcast are skipped, so a batch
+// can safely be repeated.
+func (s *Store) DeliverBroadcastBatch(ctx context.Context, idText string, batchSize int) (*Broadcast, error) {
+	broadcast, err := s.FindBroadcast(ctx, idText)
+	if err != nil || broadcast == nil || broadcast.Status != BroadcastSending {
+		return broadcast, err
+	}
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+
+	filter, err := s.broadcastRecipientFilter(ctx, broadcast.Target)
+	if err != nil {
+		return nil, err
+	}
+	if !broadcast.LastUserID.IsZero() {
+		filter = bson.M{"$and": bson.A{filter, bson.M{"_id": bson.M{"$gt": broadcast.LastUserID}}}}
+	}
+	cursor, err := s.collection("users").Find(
+		ctx,
+		filter,
+		options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(int64(batchSize)).SetProjection(bson.M{"_id": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	var users []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+
+	userIDs := make([]primitive.ObjectID, 0, len(users))
+	for _, user := range users {
+		userIDs = append(userIDs, user.ID)
+	}
+	inserted, err := s.insertBroadcastNotifications(ctx, broadcast, userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	set := bson.M{}
+	if len(userIDs) > 0 {
+		set["lastUserId"] = userIDs[len(userIDs)-1]
+	}
+	if len(userIDs) < batchSize {
+		set["status"] = BroadcastSent
+		set["finishedAt"] = now
+	}
+	update := bson.M{"$inc": bson.M{"recipients": inserted}}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	err = s.collection(broadcastsCollection).FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": broadcast.ID},
+		update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(broadcast)
+	if err != nil {
+		return nil, err
+	}
+	return broadcast, nil
+}
+
+// insertBroadcastNotifications stores the broadcast for each of userIDs that
+// does not have it yet and returns how many were added. Like
+// insertNotification it honours the users' in-app preference and runs the
+// created hook, so other channels follow as well.
+func (s *Store) insertBroadcastNotifications(ctx context.Context, broadcast *Broadcast, userIDs []primitive.ObjectID) (int64, error) {
+	if len(userIDs) == 0 {
+		return 0, nil
+	}
+
+	delivered, err := s.collection(notificationsCollection).Distinct(
+		ctx,
+		"userId",
+		bson.M{"broadcastId": broadcast.ID, "userId": bson.M{"$in": userIDs}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	skip := make(map[primitive.ObjectID]bool, len(delivered))
+	for _, value := range delivered {
+		if id, ok := value.(primitive.ObjectID); ok {
+			skip[id] = true
+		}
+	}
+
+	cursor, err := s.collection(notificationPreferencesCollection).Find(ctx, bson.M{"_id": bson.M{"$in": userIDs}})
+	if err != nil {
+		return 0, err
+	}
+	var saved []NotificationPreferences
+	if err := cursor.All(ctx, &saved); err != nil {
+		return 0, err
+	}
+	preferences := make(map[primitive.ObjectID]*NotificationPreferences, len(saved))
+	for i := range saved {
+		preferences[saved[i].UserID] = &saved[i]
+	}
+
+	now := time.Now().UTC()
+	notifications := make([]Notification, 0, len(userIDs))
+	documents := make([]any, 0, len(userIDs))
+	for _, userID := range userIDs {
+		if skip[userID] {
+			continue
+		}
+		hidden := !preferences[userID].Allows(NotificationAnnouncement, ChannelInApp, true)
+		notification := Notification{
+			ID:          primitive.NewObjectID(),
+			UserID:      userID,
+			BroadcastID: broadcast.ID,
+			Type:        NotificationAnnouncement,
+			Title:       broadcast.Title,
+			Text:        broadcast.Text,
+			Link:        broadcast.Link,
+			IsRead:      hidden,
+			Hidden:      hidden,
+			CreatedAt:   now,
+		}
+		if hidden {
+			notification.PurgeAt = s.notificationPurgeAt(now)
+		}
+		notifications = append(notifications, notification)
+		documents = append(documents, notification)
+	}
+	if len(documents) == 0 {
+		return 0, nil
+	}
+
+	// Unordered, so one user who got the broadcast in the meantime does
+	// not stop the rest of the batch.
+	failed := map[int]bool{}
+	_, err = s.collection(notificationsCollection).InsertMany(ctx, documents, options.InsertMany().SetOrdered(false))
+	var bulkErr mongo.BulkWriteException
+	if errors.As(err, &bulkErr) && mongo.IsDuplicateKeyError(err) && bulkErr.WriteConcernError == nil {
+		for _, writeErr := range bulkErr.WriteErrors {
+			if !mongo.IsDuplicateKeyError(writeErr) {
+				return 0, err
+			}
+			failed[writeErr.Index] = true
+		}
+	} else if err != nil {
+		return 0, err
+	}
+
+	inserted := int64(0)
+	for i, notification := range notifications {
+		if failed[i] {
+			continue
+		}
+		inserted++
+		if !notification.Hidden {
+			s.notificationsChanged(notification.UserID)
+		}
+		if s.notificationCreated != nil {
+			s.notificationCreated(ctx, notification)
+		}
+	}
+	return inserted, nil
+}
+
+// FindBroadcast returns the broadcast with its read count, or nil when there
+// is none with that id.
+func (s *Store) FindBroadcast(ctx context.Context, idText string) (*Broadcast, error) {
+	id, err := primitive.ObjectIDFromHex(strings.TrimSpace(idText))
+	if err != nil {
+		return nil, nil
+	}
+
+	var broadcast Broadcast
+	err = s.collection(broadcastsCollection).FindOne(ctx, bson.M{"_id": id}).Decode(&broadcast)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	reads, err := s.broadcastReads(ctx, []primitive.ObjectID{id})
+	if err != nil {
+		return nil, err
+	}
+	broadcast.Read = reads[id]
+	return &broadcast, nil
+}
+
+// ListBroadcasts returns broadcasts newest first with their read counts.
+func (s *Store) ListBroadcasts(ctx context.Context, skip, limit int64) ([]Broadcast, int64, error) {
+	total, err := s.collection(broadcastsCollection).CountDocuments(ctx, bson.M{})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	cursor, err := s.collection(broadcastsCollection).Find(
+		ctx,
+		bson.M{},
+		options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}).SetSkip(skip).SetLimit(limit),
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	items := make([]Broadcast, 0)
+	if err := cursor.All(ctx, &items); err != nil {
+		return nil, 0, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	reads, err := s.broadcastReads(ctx, ids)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range items {
+		items[i].Read = reads[items[i].ID]
+	}
+	return items, total, nil
+}
+
+// broadcastReads counts the read in-app copies of each broadcast. Copies
+// hidden by the user's preferences are stored as read and left out.
+func (s *Store) broadcastReads(ctx context.Context, ids []primitive.ObjectID) (map[primitive.ObjectID]int64, error) {
+	reads := map[primitive.ObjectID]int64{}
+	if len(ids) == 0 {
+		return reads, nil
+	}
+
+	cursor, err := s.collection(notificationsCollection).Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"broadcastId": bson.M{"$in": ids}, "isRead": true, "hidden": bson.M{"$ne": true}}}},
+		{{Key: "$group", Value: bson.M{"_id": "$broadcastId", "count": bson.M{"$sum": 1}}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var counts []struct {
+		ID    primitive.ObjectID `bson:"_id"`
+		Count int64              `bson:"count"`
+	}
+	if err := cursor.All(ctx, &counts); err != nil {
+		return nil, err
+	}
+	for _, count := range counts {
+		reads[count.ID] = count.Count
+	}
+	return reads, nil
+}
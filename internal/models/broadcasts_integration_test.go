@@ -0,0 +1,136 @@
+package models
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"easybook/internal/db"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestBroadcastFanOutInBatches(t *testing.T) {
+	mongoURI := strings.TrimSpace(os.Getenv("MONGO_URI"))
+	if mongoURI == "" {
+		t.Skip("MONGO_URI is not set; skipping integration test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURI))
+	if err != nil {
+		t.Fatalf("connect mongo: %v", err)
+	}
+	defer func() {
+		_ = client.Disconnect(context.Background())
+	}()
+
+	database := client.Database("easybook_test_" + primitive.NewObjectID().Hex())
+	defer func() {
+		_ = database.Drop(context.Background())
+	}()
+	if err := db.EnsureStartupMaintenance(ctx, database); err != nil {
+		t.Fatalf("ensure indexes: %v", err)
+	}
+
+	store := NewStore(database)
+	users := make([]primitive.ObjectID, 0, 5)
+	for i := 0; i < 5; i++ {
+		role := "user"
+		if i == 4 {
+			role = "admin"
+		}
+		id := primitive.NewObjectID()
+		if _, err := database.Collection("users").InsertOne(ctx, bson.M{"_id": id, "email": id.Hex() + "@example.com", "role": role}); err != nil {
+			t.Fatalf("insert user: %v", err)
+		}
+		users = append(users, id)
+	}
+
+	hotelID := primitive.NewObjectID()
+	for _, booking := range []bson.M{
+		{"userId": users[0], "roomId": hotelID, "checkIn": "2030-05-01", "checkOut": "2030-05-04", "status": "confirmed"},
+		{"userId": users[1], "roomId": hotelID, "checkIn": "2030-06-01", "checkOut": "2030-06-03", "status": "confirmed"},
+		// A legacy booking and one cancelled with the other spelling.
+		{"userId": users[2], "hotelId": hotelID.Hex(), "checkIn": "2030-05-05", "checkOut": "2030-05-07", "status": "confirmed"},
+		{"userId": users[3], "roomId": hotelID, "checkIn": "2030-05-05", "checkOut": "2030-05-07", "status": "canceled"},
+	} {
+		if _, err := database.Collection(bookingsCollection).InsertOne(ctx, booking); err != nil {
+			t.Fatalf("insert booking: %v", err)
+		}
+	}
+
+	deliver := func(broadcast *Broadcast) *Broadcast {
+		t.Helper()
+		for i := 0; i < 10 && broadcast.Status == BroadcastSending; i++ {
+			broadcast, err = store.DeliverBroadcastBatch(ctx, broadcast.ID.Hex(), 2)
+			if err != nil {
+				t.Fatalf("deliver batch: %v", err)
+			}
+		}
+		return broadcast
+	}
+
+	everyone, err := store.CreateBroadcast(ctx, Broadcast{Title: "Maintenance", Text: "The site is down tonight.", Target: BroadcastTarget{Audience: BroadcastAll}})
+	if err != nil {
+		t.Fatalf("create broadcast: %v", err)
+	}
+	everyone = deliver(everyone)
+	if everyone.Status != BroadcastSent || everyone.Recipients != 5 {
+		t.Fatalf("expected all 5 users to be notified, got %+v", everyone)
+	}
+
+	// A repeated batch must not notify anyone twice.
+	if _, err := database.Collection(broadcastsCollection).UpdateOne(ctx, bson.M{"_id": everyone.ID}, bson.M{
+		"$set":   bson.M{"status": BroadcastSending},
+		"$unset": bson.M{"lastUserId": ""},
+	}); err != nil {
+		t.Fatalf("reset broadcast: %v", err)
+	}
+	everyone.Status = BroadcastSending
+	everyone = deliver(everyone)
+	copies, _ := database.Collection(notificationsCollection).CountDocuments(ctx, bson.M{"broadcastId": everyone.ID})
+	if copies != 5 || everyone.Recipients != 5 {
+		t.Fatalf("expected one copy per user, got %d copies and %d recipients", copies, everyone.Recipients)
+	}
+
+	if _, err := store.MarkAllNotificationsRead(ctx, users[0].Hex()); err != nil {
+		t.Fatalf("mark read: %v", err)
+	}
+	found, err := store.FindBroadcast(ctx, everyone.ID.Hex())
+	if err != nil || found == nil || found.Read != 1 {
+		t.Fatalf("expected one read copy, got %+v (%v)", found, err)
+	}
+
+	hotelInMay, err := store.CreateBroadcast(ctx, Broadcast{
+		Title:  "Closed for renovation",
+		Text:   "Please rebook.",
+		Target: BroadcastTarget{Audience: BroadcastBookings, HotelID: hotelID.Hex(), From: "2030-05-03", To: "2030-05-10"},
+	})
+	if err != nil {
+		t.Fatalf("create hotel broadcast: %v", err)
+	}
+	if hotelInMay = deliver(hotelInMay); hotelInMay.Recipients != 2 {
+		t.Fatalf("expected only the guests staying in May, got %+v", hotelInMay)
+	}
+
+	admins, err := store.CreateBroadcast(ctx, Broadcast{Title: "Staff", Text: "Meeting at 10.", Target: BroadcastTarget{Audience: BroadcastRole, Role: "admin"}})
+	if err != nil {
+		t.Fatalf("create role broadcast: %v", err)
+	}
+	if admins = deliver(admins); admins.Recipients != 1 {
+		t.Fatalf("expected only the admin, got %+v", admins)
+	}
+
+	items, total, err := store.ListBroadcasts(ctx, 0, 10)
+	if err != nil || total != 3 || len(items) != 3 || items[2].Read != 1 {
+		t.Fatalf("expected three broadcasts newest first, got %+v (%d, %v)", items, total, err)
+	}
+}
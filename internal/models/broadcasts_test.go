@@ -0,0 +1,31 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBroadcastTargetNormalize(t *testing.T) {
+	target, err := BroadcastTarget{Audience: " Role ", Role: "admin", HotelID: "ignored"}.normalize()
+	if err != nil || target != (BroadcastTarget{Audience: BroadcastRole, Role: "admin"}) {
+		t.Fatalf("expected unused fields to be dropped, got %+v (%v)", target, err)
+	}
+
+	invalid := []BroadcastTarget{
+		{Audience: "everyone"},
+		{Audience: BroadcastRole},
+		{Audience: BroadcastBookings},
+		{Audience: BroadcastBookings, HotelID: "not-an-id"},
+		{Audience: BroadcastBookings, From: "2030-05-01"},
+		{Audience: BroadcastBookings, From: "2030-05-03", To: "2030-05-01"},
+	}
+	for _, target := range invalid {
+		if _, err := target.normalize(); !errors.Is(err, ErrInvalidBroadcast) {
+			t.Fatalf("expected %+v to be rejected, got %v", target, err)
+		}
+	}
+
+	if _, err := (BroadcastTarget{Audience: BroadcastBookings, From: "2030-05-01", To: "2030-05-01"}).normalize(); err != nil {
+		t.Fatalf("expected a one-day range to be accepted, got %v", err)
+	}
+}
@@ -3,25 +3,114 @@ package models
 import (
 	"context"
 	"errors"
+	"fmt"
+	"regexp"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-func (s *Store) CreateContactRequest(ctx context.Context, payload map[string]string) (string, error) {
+const contactRequestsCollection = "contact_requests"
+
+const (
+	ContactStatusNew        = "new"
+	ContactStatusInProgress = "in_progress"
+	ContactStatusResolved   = "resolved"
+	ContactStatusSpam       = "spam"
+)
+
+var ContactStatuses = []string{
+	ContactStatusNew,
+	ContactStatusInProgress,
+	ContactStatusResolved,
+	ContactStatusSpam,
+}
+
+// contactTransitions lists the statuses a request may move to from each
+// status. Resolved requests can be reopened and spam can be taken back.
+var contactTransitions = map[string][]string{
+	ContactStatusNew:        {ContactStatusInProgress, ContactStatusResolved, ContactStatusSpam},
+	ContactStatusInProgress: {ContactStatusNew, ContactStatusResolved, ContactStatusSpam},
+	ContactStatusResolved:   {ContactStatusInProgress},
+	ContactStatusSpam:       {ContactStatusNew},
+}
+
+type ContactRequest struct {
+	ID            primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	Name          string              `bson:"name" json:"name"`
+	Phone         string              `bson:"phone" json:"phone"`
+	City          string              `bson:"city" json:"city"`
+	Email         string              `bson:"email" json:"email"`
+	Message       string              `bson:"message" json:"message"`
+	Status        string              `bson:"status" json:"status"`
+	AssignedTo    *primitive.ObjectID `bson:"assignedTo,omitempty" json:"assignedTo,omitempty"`
+	AssignedEmail string              `bson:"assignedEmail,omitempty" json:"assignedEmail,omitempty"`
+	SpamScore     int                 `bson:"spamScore,omitempty" json:"spamScore,omitempty"`
+	SpamReasons   []string            `bson:"spamReasons,omitempty" json:"spamReasons,omitempty"`
+	Notes         []ContactNote       `bson:"notes,omitempty" json:"notes"`
+	Replies       []ContactReply      `bson:"replies,omitempty" json:"replies"`
+	CreatedAt     time.Time           `bson:"createdAt" json:"createdAt"`
+	UpdatedAt     time.Time           `bson:"updatedAt" json:"updatedAt"`
+}
+
+// ContactNote is an internal remark that is never shown to the sender.
+type ContactNote struct {
+	AuthorID    primitive.ObjectID `bson:"authorId" json:"authorId"`
+	AuthorEmail string             `bson:"authorEmail" json:"authorEmail"`
+	Text        string             `bson:"text" json:"text"`
+	CreatedAt   time.Time          `bson:"createdAt" json:"createdAt"`
+}
+
+// ContactReply records a response mailed to the sender.
+type ContactReply struct {
+	AuthorID    primitive.ObjectID `bson:"authorId" json:"authorId"`
+	AuthorEmail string             `bson:"authorEmail" json:"authorEmail"`
+	Subject     string             `bson:"subject" json:"subject"`
+	Text        string             `bson:"text" json:"text"`
+	SentAt      time.Time          `bson:"sentAt" json:"sentAt"`
+}
+
+// ContactScreening is the anti-spam outcome stored with a new request.
+// Requests judged as spam are kept, but start in the spam status.
+type ContactScreening struct {
+	Spam    bool
+	Score   int
+	Reasons []string
+}
+
+type ContactRequestFilter struct {
+	Status string
+	// Assignee is a user id, "unassigned", or empty for everyone.
+	Assignee string
+	Query    string
+}
+
+func (s *Store) CreateContactRequest(ctx context.Context, payload map[string]string, screening ContactScreening) (string, error) {
 	now := time.Now().UTC()
 
-	result, err := s.collection("contact_requests").InsertOne(ctx, bson.M{
+	document := bson.M{
 		"name":      payload["name"],
 		"phone":     payload["phone"],
 		"city":      payload["city"],
 		"email":     payload["email"],
 		"message":   payload["message"],
-		"status":    "new",
+		"status":    ContactStatusNew,
 		"createdAt": now,
 		"updatedAt": now,
-	})
+	}
+	if screening.Spam {
+		document["status"] = ContactStatusSpam
+	}
+	if screening.Score > 0 {
+		document["spamScore"] = screening.Score
+		document["spamReasons"] = screening.Reasons
+	}
+
+	result, err := s.collection(contactRequestsCollection).InsertOne(ctx, document)
 	if err != nil {
 		return "", err
 	}
@@ -33,3 +122,184 @@ func (s *Store) CreateContactRequest(ctx context.Context, payload map[string]str
 
 	return insertedID.Hex(), nil
 }
+
+func (s *Store) ListContactRequests(ctx context.Context, filter ContactRequestFilter, skip, limit int64) ([]ContactRequest, int64, error) {
+	query := bson.M{}
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+	switch filter.Assignee {
+	case "":
+	case "unassigned":
+		query["assignedTo"] = bson.M{"$exists": false}
+	default:
+		assigneeID, err := primitive.ObjectIDFromHex(filter.Assignee)
+		if err != nil {
+			return []ContactRequest{}, 0, nil
+		}
+		query["assignedTo"] = assigneeID
+	}
+	if text := strings.TrimSpace(filter.Query); text != "" {
+		pattern := primitive.Regex{Pattern: regexp.QuoteMeta(text), Options: "i"}
+		query["$or"] = bson.A{
+			bson.M{"name": pattern},
+			bson.M{"email": pattern},
+			bson.M{"message": pattern},
+		}
+	}
+
+	total, err := s.collection(contactRequestsCollection).CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	cursor, err := s.collection(contactRequestsCollection).Find(
+		ctx,
+		query,
+		options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}).SetSkip(skip).SetLimit(limit),
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	items := []ContactRequest{}
+	if err := cursor.All(ctx, &items); err != nil {
+		return nil, 0, err
+	}
+	return items, total, nil
+}
+
+func (s *Store) FindContactRequestByID(ctx context.Context, id string) (*ContactRequest, error) {
+	objectID, err := primitive.ObjectIDFromHex(strings.TrimSpace(id))
+	if err != nil {
+		return nil, nil
+	}
+
+	var request ContactRequest
+	err = s.collection(contactRequestsCollection).FindOne(ctx, bson.M{"_id": objectID}).Decode(&request)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+// UpdateContactRequestStatus moves the request to status if that is allowed
+// from the status it has at the time of the update.
+func (s *Store) UpdateContactRequestStatus(ctx context.Context, id, status string) (*ContactRequest, error) {
+	status = strings.ToLower(strings.TrimSpace(status))
+	from := make([]string, 0, len(contactTransitions))
+	for source, targets := range contactTransitions {
+		for _, target := range targets {
+			if target == status {
+				from = append(from, source)
+			}
+		}
+	}
+	if len(from) == 0 {
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidContactPayload, status)
+	}
+
+	current, err := s.FindContactRequestByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil {
+		return nil, ErrContactRequestNotFound
+	}
+
+	updated, err := s.updateContactRequest(ctx, bson.M{"_id": current.ID, "status": bson.M{"$in": from}}, bson.M{
+		"$set": bson.M{"status": status, "updatedAt": time.Now().UTC()},
+	})
+	if errors.Is(err, ErrContactRequestNotFound) {
+		return nil, fmt.Errorf("%w: cannot move from %s to %s", ErrInvalidContactTransition, current.Status, status)
+	}
+	return updated, err
+}
+
+// AssignContactRequest hands the request to a staff member, or unassigns it
+// when assignee is nil.
+func (s *Store) AssignContactRequest(ctx context.Context, id string, assignee *User) (*ContactRequest, error) {
+	objectID, err := primitive.ObjectIDFromHex(strings.TrimSpace(id))
+	if err != nil {
+		return nil, ErrContactRequestNotFound
+	}
+
+	update := bson.M{
+		"$set":   bson.M{"updatedAt": time.Now().UTC()},
+		"$unset": bson.M{"assignedTo": "", "assignedEmail": ""},
+	}
+	if assignee != nil {
+		update = bson.M{"$set": bson.M{
+			"assignedTo":    assignee.ID,
+			"assignedEmail": assignee.Email,
+			"updatedAt":     time.Now().UTC(),
+		}}
+	}
+	return s.updateContactRequest(ctx, bson.M{"_id": objectID}, update)
+}
+
+func (s *Store) AddContactNote(ctx context.Context, id string, note ContactNote) (*ContactRequest, error) {
+	objectID, err := primitive.ObjectIDFromHex(strings.TrimSpace(id))
+	if err != nil {
+		return nil, ErrContactRequestNotFound
+	}
+
+	note.Text = strings.TrimSpace(note.Text)
+	if note.Text == "" {
+		return nil, fmt.Errorf("%w: note text is required", ErrInvalidContactPayload)
+	}
+	note.CreatedAt = time.Now().UTC()
+	return s.updateContactRequest(ctx, bson.M{"_id": objectID}, bson.M{
+		"$push": bson.M{"notes": note},
+		"$set":  bson.M{"updatedAt": note.CreatedAt},
+	})
+}
+
+// AddContactReply logs a reply that has been mailed. Replying to a new
+// request also takes it in progress.
+func (s *Store) AddContactReply(ctx context.Context, id string, reply ContactReply) (*ContactRequest, error) {
+	objectID, err := primitive.ObjectIDFromHex(strings.TrimSpace(id))
+	if err != nil {
+		return nil, ErrContactRequestNotFound
+	}
+
+	reply.SentAt = time.Now().UTC()
+	updated, err := s.updateContactRequest(ctx, bson.M{"_id": objectID}, bson.M{
+		"$push": bson.M{"replies": reply},
+		"$set":  bson.M{"updatedAt": reply.SentAt},
+	})
+	if err != nil || updated.Status != ContactStatusNew {
+		return updated, err
+	}
+	taken, err := s.updateContactRequest(ctx, bson.M{"_id": objectID, "status": ContactStatusNew}, bson.M{
+		"$set": bson.M{"status": ContactStatusInProgress},
+	})
+	if errors.Is(err, ErrContactRequestNotFound) {
+		return updated, nil
+	}
+	return taken, err
+}
+
+func (s *Store) updateContactRequest(ctx context.Context, filter, update bson.M) (*ContactRequest, error) {
+	var updated ContactRequest
+	err := s.collection(contactRequestsCollection).FindOneAndUpdate(
+		ctx,
+		filter,
+		update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrContactRequestNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// ContactStatusTargets returns the statuses a request in status can move to.
+func ContactStatusTargets(status string) []string {
+	return contactTransitions[status]
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"easybook/internal/db"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestContactRequestWorkflow(t *testing.T) {
+	mongoURI := strings.TrimSpace(os.Getenv("MONGO_URI"))
+	if mongoURI == "" {
+		t.Skip("MONGO_URI is not set; skipping integration test")
+	}
+
+	dbName := "easybook_contact_test_" + primitive.NewObjectID().Hex()
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURI))
+	if err != nil {
+		t.Fatalf("connect mongo: %v", err)
+	}
+	defer func() {
+		_ = client.Disconnect(context.Background())
+	}()
+
+	database := client.Database(dbName)
+	defer func() {
+		_ = database.Drop(context.Background())
+	}()
+
+	if err := db.EnsureStartupMaintenance(ctx, database); err != nil {
+		t.Fatalf("ensure indexes: %v", err)
+	}
+
+	store := NewStore(database)
+	id, err := store.CreateContactRequest(ctx, map[string]string{"name": "Guest", "email": "guest@example.com", "message": "Is breakfast included?"}, ContactScreening{})
+	if err != nil {
+		t.Fatalf("create contact request: %v", err)
+	}
+
+	staffID, err := store.CreateUser(ctx, "staff@example.com", "Passw0rd!", "admin")
+	if err != nil {
+		t.Fatalf("create staff: %v", err)
+	}
+	staff, _ := store.FindUserByID(ctx, staffID)
+	if _, err := store.AssignContactRequest(ctx, id, staff); err != nil {
+		t.Fatalf("assign: %v", err)
+	}
+	mine, total, err := store.ListContactRequests(ctx, ContactRequestFilter{Assignee: staffID}, 0, 10)
+	if err != nil || total != 1 || len(mine) != 1 {
+		t.Fatalf("expected the request in the assignee filter, got %d (%v)", total, err)
+	}
+
+	reply := ContactReply{AuthorID: staff.ID, AuthorEmail: staff.Email, Subject: "Re", Text: "Yes, it is."}
+	updated, err := store.AddContactReply(ctx, id, reply)
+	if err != nil {
+		t.Fatalf("add reply: %v", err)
+	}
+	if updated.Status != ContactStatusInProgress || len(updated.Replies) != 1 {
+		t.Fatalf("expected the reply to be logged and the request taken in progress, got %+v", updated)
+	}
+
+	if _, err := store.UpdateContactRequestStatus(ctx, id, ContactStatusResolved); err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if _, err := store.UpdateContactRequestStatus(ctx, id, ContactStatusSpam); !errors.Is(err, ErrInvalidContactTransition) {
+		t.Fatalf("expected resolved -> spam to be rejected, got %v", err)
+	}
+	if _, err := store.UpdateContactRequestStatus(ctx, id, "archived"); !errors.Is(err, ErrInvalidContactPayload) {
+		t.Fatalf("expected an unknown status to be rejected, got %v", err)
+	}
+}
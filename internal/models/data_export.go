@@ -0,0 +1,262 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const dataExportsCollection = "data_exports"
+
+const (
+	DataExportPending = "pending"
+	DataExportReady   = "ready"
+	DataExportFailed  = "failed"
+)
+
+// DataExport is a personal data archive generated in the background. The
+// archive itself is kept in the document until ExpiresAt.
+type DataExport struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID      primitive.ObjectID `bson:"userId" json:"-"`
+	Format      string             `bson:"format" json:"format"`
+	Status      string             `bson:"status" json:"status"`
+	FileName    string             `bson:"fileName,omitempty" json:"fileName,omitempty"`
+	ContentType string             `bson:"contentType,omitempty" json:"-"`
+	Data        []byte             `bson:"data,omitempty" json:"-"`
+	Size        int                `bson:"size,omitempty" json:"size,omitempty"`
+	Error       string             `bson:"error,omitempty" json:"error,omitempty"`
+	CreatedAt   time.Time          `bson:"createdAt" json:"createdAt"`
+	CompletedAt *time.Time         `bson:"completedAt,omitempty" json:"completedAt,omitempty"`
+	ExpiresAt   time.Time          `bson:"expiresAt" json:"expiresAt"`
+}
+
+// UserData is everything stored about one user, grouped the way it is
+// written to the export archive.
+type UserData struct {
+	Profile         map[string]any `json:"profile"`
+	Bookings        []bson.M       `json:"bookings"`
+	Waitlist        []bson.M       `json:"waitlist"`
+	Notifications   []bson.M       `json:"notifications"`
+	Reviews         []bson.M       `json:"reviews"`
+	ContactRequests []bson.M       `json:"contactRequests"`
+	Identities      []bson.M       `json:"identities"`
+	APITokens       []bson.M       `json:"apiTokens"`
+}
+
+// CountUserData returns how many records an export of the user would contain.
+func (s *Store) CountUserData(ctx context.Context, userIDText, email string) (int64, error) {
+	userID, err := primitive.ObjectIDFromHex(strings.TrimSpace(userIDText))
+	if err != nil {
+		return 0, errors.New("invalid user id")
+	}
+
+	var total int64
+	for _, name := range []string{bookingsCollection, waitlistCollection, notificationsCollection} {
+		count, err := s.collection(name).CountDocuments(ctx, bson.M{"userId": userID})
+		if err != nil {
+			return 0, err
+		}
+		total += count
+	}
+	count, err := s.collection(contactRequestsCollection).CountDocuments(ctx, contactEmailFilter(email))
+	if err != nil {
+		return 0, err
+	}
+	return total + count, nil
+}
+
+// CollectUserData reads the user's records. Secrets such as password and
+// token hashes are left out. Hotel ratings are stored only as aggregates, so
+// Reviews stays empty until ratings are kept per user.
+func (s *Store) CollectUserData(ctx context.Context, userIDText string) (*UserData, error) {
+	user, err := s.FindUserByID(ctx, userIDText)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+
+	data := &UserData{
+		Profile: map[string]any{
+			"id":                user.ID.Hex(),
+			"email":             user.Email,
+			"role":              user.Role,
+			"name":              user.Name,
+			"phone":             user.Phone,
+			"preferredLanguage": user.PreferredLanguage,
+			"emailVerified":     user.EmailVerified,
+			"emailVerifiedAt":   user.EmailVerifiedAt,
+			"twoFactorEnabled":  user.TOTPEnabled,
+			"createdAt":         user.CreatedAt,
+			"updatedAt":         user.UpdatedAt,
+		},
+		Reviews: []bson.M{},
+	}
+
+	byUser := bson.M{"userId": user.ID}
+	sections := []struct {
+		collection string
+		filter     bson.M
+		projection bson.M
+		target     *[]bson.M
+	}{
+		{bookingsCollection, byUser, nil, &data.Bookings},
+		{waitlistCollection, byUser, nil, &data.Waitlist},
+		{notificationsCollection, byUser, nil, &data.Notifications},
+		{contactRequestsCollection, contactEmailFilter(user.Email), contactExportProjection, &data.ContactRequests},
+		{userIdentitiesCollection, byUser, nil, &data.Identities},
+		{apiTokensCollection, byUser, bson.M{"tokenHash": 0}, &data.APITokens},
+	}
+	for _, section := range sections {
+		findOptions := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}})
+		if section.projection != nil {
+			findOptions.SetProjection(section.projection)
+		}
+		cursor, err := s.collection(section.collection).Find(ctx, section.filter, findOptions)
+		if err != nil {
+			return nil, err
+		}
+		items := []bson.M{}
+		if err := cursor.All(ctx, &items); err != nil {
+			return nil, err
+		}
+		*section.target = items
+	}
+
+	return data, nil
+}
+
+// contactExportProjection keeps what the sender wrote and was sent back.
+// Staff notes, assignment, spam scoring and reply authors stay internal.
+var contactExportProjection = bson.M{
+	"name":            1,
+	"phone":           1,
+	"city":            1,
+	"email":           1,
+	"message":         1,
+	"status":          1,
+	"replies.subject": 1,
+	"replies.text":    1,
+	"replies.sentAt":  1,
+	"createdAt":       1,
+	"updatedAt":       1,
+}
+
+// contactEmailFilter matches contact requests by the address typed into the
+// form, which is stored as entered.
+func contactEmailFilter(email string) bson.M {
+	return bson.M{"email": primitive.Regex{Pattern: "^" + regexp.QuoteMeta(normalizeEmail(email)) + "$", Options: "i"}}
+}
+
+func (s *Store) CreateDataExport(ctx context.Context, userIDText, format string, ttl time.Duration) (*DataExport, error) {
+	userID, err := primitive.ObjectIDFromHex(strings.TrimSpace(userIDText))
+	if err != nil {
+		return nil, errors.New("invalid user id")
+	}
+
+	now := time.Now().UTC()
+	export := &DataExport{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		Format:    format,
+		Status:    DataExportPending,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+	if _, err := s.collection(dataExportsCollection).InsertOne(ctx, export); err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+func (s *Store) CompleteDataExport(ctx context.Context, id primitive.ObjectID, fileName, contentType string, data []byte) error {
+	now := time.Now().UTC()
+	_, err := s.collection(dataExportsCollection).UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{
+		"status":      DataExportReady,
+		"fileName":    fileName,
+		"contentType": contentType,
+		"data":        data,
+		"size":        len(data),
+		"completedAt": now,
+	}})
+	return err
+}
+
+func (s *Store) FailDataExport(ctx context.Context, id primitive.ObjectID, message string) error {
+	now := time.Now().UTC()
+	_, err := s.collection(dataExportsCollection).UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{
+		"status":      DataExportFailed,
+		"error":       message,
+		"completedAt": now,
+	}})
+	return err
+}
+
+// FindDataExport returns the user's export, or nil when it does not exist,
+// belongs to someone else or has expired.
+func (s *Store) FindDataExport(ctx context.Context, userIDText, exportIDText string, withData bool) (*DataExport, error) {
+	userID, err := primitive.ObjectIDFromHex(strings.TrimSpace(userIDText))
+	if err != nil {
+		return nil, nil
+	}
+	exportID, err := primitive.ObjectIDFromHex(strings.TrimSpace(exportIDText))
+	if err != nil {
+		return nil, nil
+	}
+
+	findOptions := options.FindOne()
+	if !withData {
+		findOptions.SetProjection(bson.M{"data": 0})
+	}
+	var export DataExport
+	err = s.collection(dataExportsCollection).FindOne(
+		ctx,
+		bson.M{"_id": exportID, "userId": userID, "expiresAt": bson.M{"$gt": time.Now().UTC()}},
+		findOptions,
+	).Decode(&export)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+// PendingDataExport returns an export of the user started after startedAfter
+// that is still being generated, so repeated requests do not start a second
+// one. Older pending exports are treated as lost.
+func (s *Store) PendingDataExport(ctx context.Context, userIDText string, startedAfter time.Time) (*DataExport, error) {
+	userID, err := primitive.ObjectIDFromHex(strings.TrimSpace(userIDText))
+	if err != nil {
+		return nil, nil
+	}
+
+	var export DataExport
+	err = s.collection(dataExportsCollection).FindOne(
+		ctx,
+		bson.M{
+			"userId":    userID,
+			"status":    DataExportPending,
+			"createdAt": bson.M{"$gt": startedAfter.UTC()},
+			"expiresAt": bson.M{"$gt": time.Now().UTC()},
+		},
+		options.FindOne().SetProjection(bson.M{"data": 0}),
+	).Decode(&export)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
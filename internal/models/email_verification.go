@@ -0,0 +1,116 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const emailVerificationsCollection = "email_verifications"
+
+func (s *Store) CreateEmailVerificationToken(ctx context.Context, userIDText, email string, ttl time.Duration) (string, error) {
+	userID, err := primitive.ObjectIDFromHex(strings.TrimSpace(userIDText))
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid user id", ErrInvalidVerificationToken)
+	}
+	cleanEmail := normalizeEmail(email)
+	if cleanEmail == "" {
+		return "", fmt.Errorf("%w: email is required", ErrInvalidVerificationToken)
+	}
+	if ttl <= 0 {
+		ttl = 48 * time.Hour
+	}
+
+	token, err := generateSecretToken()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now().UTC()
+	if _, err := s.collection(emailVerificationsCollection).UpdateMany(
+		ctx,
+		bson.M{"userId": userID, "usedAt": nil},
+		bson.M{"$set": bson.M{"usedAt": now, "revoked": true}},
+	); err != nil {
+		return "", err
+	}
+
+	_, err = s.collection(emailVerificationsCollection).InsertOne(ctx, bson.M{
+		"userId":    userID,
+		"email":     cleanEmail,
+		"tokenHash": hashSecretToken(token),
+		"usedAt":    nil,
+		"createdAt": now,
+		"expiresAt": now.Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// ConsumeEmailVerificationToken marks the address stored with the token as verified.
+// When the token was issued for an address other than the current one, the user's
+// email is switched to it. The token stays usable when that update fails, e.g.
+// because another account took the address in the meantime.
+func (s *Store) ConsumeEmailVerificationToken(ctx context.Context, token string) (*User, error) {
+	now := time.Now().UTC()
+	tokenFilter := bson.M{
+		"tokenHash": hashSecretToken(strings.TrimSpace(token)),
+		"usedAt":    nil,
+		"expiresAt": bson.M{"$gt": now},
+	}
+
+	var verification bson.M
+	err := s.collection(emailVerificationsCollection).FindOne(ctx, tokenFilter).Decode(&verification)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrInvalidVerificationToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	userID, ok := verification["userId"].(primitive.ObjectID)
+	if !ok {
+		return nil, ErrInvalidVerificationToken
+	}
+	email := normalizeEmail(fmt.Sprint(verification["email"]))
+
+	var user User
+	err = s.collection("users").FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": userID},
+		bson.M{"$set": bson.M{
+			"email":           email,
+			"emailVerified":   true,
+			"emailVerifiedAt": now,
+			"updatedAt":       now,
+		}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&user)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrInvalidVerificationToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tokenFilter["_id"] = verification["_id"]
+	if _, err := s.collection(emailVerificationsCollection).UpdateOne(
+		ctx,
+		tokenFilter,
+		bson.M{"$set": bson.M{"usedAt": now}},
+	); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"easybook/internal/db"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestEmailVerificationTokens(t *testing.T) {
+	mongoURI := strings.TrimSpace(os.Getenv("MONGO_URI"))
+	if mongoURI == "" {
+		t.Skip("MONGO_URI is not set; skipping integration test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURI))
+	if err != nil {
+		t.Fatalf("connect mongo: %v", err)
+	}
+	defer func() {
+		_ = client.Disconnect(context.Background())
+	}()
+
+	database := client.Database("easybook_test_" + primitive.NewObjectID().Hex())
+	defer func() {
+		_ = database.Drop(context.Background())
+	}()
+	if err := db.EnsureStartupMaintenance(ctx, database); err != nil {
+		t.Fatalf("ensure indexes: %v", err)
+	}
+
+	store := NewStore(database)
+	userID := primitive.NewObjectID()
+	otherID := primitive.NewObjectID()
+	for id, email := range map[primitive.ObjectID]string{userID: "guest@example.com", otherID: "taken@example.com"} {
+		if _, err := database.Collection("users").InsertOne(ctx, bson.M{"_id": id, "email": email, "emailVerified": false}); err != nil {
+			t.Fatalf("insert user: %v", err)
+		}
+	}
+
+	token, err := store.CreateEmailVerificationToken(ctx, userID.Hex(), "guest@example.com", time.Hour)
+	if err != nil {
+		t.Fatalf("create token: %v", err)
+	}
+	user, err := store.ConsumeEmailVerificationToken(ctx, token)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if !user.EmailVerified || user.Email != "guest@example.com" {
+		t.Fatalf("expected a verified address, got %+v", user)
+	}
+	if _, err := store.ConsumeEmailVerificationToken(ctx, token); !errors.Is(err, ErrInvalidVerificationToken) {
+		t.Fatalf("expected a used token to be rejected, got %v", err)
+	}
+
+	expired, err := store.CreateEmailVerificationToken(ctx, userID.Hex(), "guest@example.com", time.Hour)
+	if err != nil {
+		t.Fatalf("create token: %v", err)
+	}
+	if _, err := database.Collection(emailVerificationsCollection).UpdateOne(
+		ctx,
+		bson.M{"tokenHash": hashSecretToken(expired)},
+		bson.M{"$set": bson.M{"expiresAt": time.Now().Add(-time.Minute)}},
+	); err != nil {
+		t.Fatalf("expire token: %v", err)
+	}
+	if _, err := store.ConsumeEmailVerificationToken(ctx, expired); !errors.Is(err, ErrInvalidVerificationToken) {
+		t.Fatalf("expected an expired token to be rejected, got %v", err)
+	}
+
+	change, err := store.CreateEmailVerificationToken(ctx, userID.Hex(), "taken@example.com", time.Hour)
+	if err != nil {
+		t.Fatalf("create token: %v", err)
+	}
+	if _, err := store.ConsumeEmailVerificationToken(ctx, change); !IsDuplicateKeyError(err, "email") {
+		t.Fatalf("expected the taken address to be refused, got %v", err)
+	}
+	if _, err := database.Collection("users").UpdateOne(ctx, bson.M{"_id": otherID}, bson.M{"$set": bson.M{"email": "moved@example.com"}}); err != nil {
+		t.Fatalf("free address: %v", err)
+	}
+	user, err = store.ConsumeEmailVerificationToken(ctx, change)
+	if err != nil {
+		t.Fatalf("expected the link to still work once the address is free, got %v", err)
+	}
+	if user.Email != "taken@example.com" {
+		t.Fatalf("expected the address to change, got %q", user.Email)
+	}
+}
@@ -16,6 +16,19 @@ var (
 	ErrUnauthorizedNotificationOp = errors.New("unauthorized notification operation")
 	ErrInvalidPresencePayload     = errors.New("invalid presence payload")
 	ErrPriorityAlreadyTaken       = errors.New("priority waitlist already taken")
+	ErrInvalidPasswordResetToken  = errors.New("invalid or expired password reset token")
+	ErrInvalidVerificationToken   = errors.New("invalid or expired verification token")
+	ErrInvalidTwoFactorCode       = errors.New("invalid two-factor code")
+	ErrInvalidAPITokenPayload     = errors.New("invalid api token payload")
+	ErrInvalidAccountPayload      = errors.New("invalid account payload")
+	ErrContactRequestNotFound     = errors.New("contact request not found")
+	ErrInvalidContactPayload      = errors.New("invalid contact request payload")
+	ErrInvalidContactTransition   = errors.New("invalid contact request status change")
+
+	ErrInvalidNotificationPreferences = errors.New("invalid notification preferences")
+	ErrInvalidJobQuery                = errors.New("invalid job query")
+	ErrInvalidBroadcast               = errors.New("invalid broadcast")
+	ErrInvalidNotificationQuery       = errors.New("invalid notification query")
 )
 
 func IsDuplicateKeyError(err error, key string) bool {
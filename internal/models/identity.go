@@ -0,0 +1,112 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const userIdentitiesCollection = "user_identities"
+
+// UserIdentity links an account to a subject at an external identity provider.
+type UserIdentity struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID      primitive.ObjectID `bson:"userId" json:"-"`
+	Provider    string             `bson:"provider" json:"provider"`
+	Subject     string             `bson:"subject" json:"-"`
+	Email       string             `bson:"email" json:"email"`
+	CreatedAt   time.Time          `bson:"createdAt" json:"createdAt"`
+	LastLoginAt time.Time          `bson:"lastLoginAt" json:"lastLoginAt"`
+}
+
+// FindUserByIdentity returns the account linked to the provider subject, or
+// nil when the identity has not been linked yet.
+func (s *Store) FindUserByIdentity(ctx context.Context, provider, subject string) (*User, error) {
+	var identity UserIdentity
+	err := s.collection(userIdentitiesCollection).FindOneAndUpdate(
+		ctx,
+		bson.M{"provider": provider, "subject": subject},
+		bson.M{"$set": bson.M{"lastLoginAt": time.Now().UTC()}},
+	).Decode(&identity)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.FindUserByID(ctx, identity.UserID.Hex())
+}
+
+func (s *Store) LinkIdentity(ctx context.Context, userIDText, provider, subject, email string) error {
+	userID, err := primitive.ObjectIDFromHex(strings.TrimSpace(userIDText))
+	if err != nil {
+		return errors.New("invalid user id")
+	}
+
+	now := time.Now().UTC()
+	_, err = s.collection(userIdentitiesCollection).InsertOne(ctx, UserIdentity{
+		UserID:      userID,
+		Provider:    provider,
+		Subject:     subject,
+		Email:       normalizeEmail(email),
+		CreatedAt:   now,
+		LastLoginAt: now,
+	})
+	return err
+}
+
+func (s *Store) ListUserIdentities(ctx context.Context, userIDText string) ([]UserIdentity, error) {
+	userID, err := primitive.ObjectIDFromHex(strings.TrimSpace(userIDText))
+	if err != nil {
+		return []UserIdentity{}, nil
+	}
+
+	cursor, err := s.collection(userIdentitiesCollection).Find(ctx, bson.M{"userId": userID})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	identities := []UserIdentity{}
+	if err := cursor.All(ctx, &identities); err != nil {
+		return nil, err
+	}
+	return identities, nil
+}
+
+// CreateExternalUser provisions an account for a single sign-on user. It has
+// no password, so password login stays closed until a reset sets one, and its
+// email is already verified by the identity provider.
+func (s *Store) CreateExternalUser(ctx context.Context, email string) (*User, error) {
+	cleanEmail := normalizeEmail(email)
+	if cleanEmail == "" {
+		return nil, errors.New("email is required")
+	}
+
+	now := time.Now().UTC()
+	user := User{
+		Email:           cleanEmail,
+		Role:            "user",
+		EmailVerified:   true,
+		EmailVerifiedAt: &now,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	result, err := s.collection("users").InsertOne(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	insertedID, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return nil, errors.New("invalid inserted id type")
+	}
+	user.ID = insertedID
+	return &user, nil
+}
@@ -0,0 +1,240 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const jobsCollection = "jobs"
+
+const (
+	JobPending = "pending"
+	JobRunning = "running"
+	JobDone    = "done"
+	// JobDead jobs failed permanently or ran out of attempts. They stay in
+	// the collection until an admin retries them or they are purged.
+	JobDead = "dead"
+)
+
+// Job is one unit of background work. Kind selects the handler; Key, when
+// set, makes enqueueing idempotent: a second job with the same key is not
+// created while the first one is still stored.
+type Job struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Kind       string             `bson:"kind" json:"kind"`
+	Key        string             `bson:"key,omitempty" json:"key,omitempty"`
+	Payload    map[string]string  `bson:"payload" json:"payload"`
+	UserID     primitive.ObjectID `bson:"userId,omitempty" json:"-"`
+	Status     string             `bson:"status" json:"status"`
+	Attempts   int                `bson:"attempts" json:"attempts"`
+	LastError  string             `bson:"lastError,omitempty" json:"lastError,omitempty"`
+	RunAt      time.Time          `bson:"runAt" json:"runAt"`
+	CreatedAt  time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt  time.Time          `bson:"updatedAt" json:"updatedAt"`
+	FinishedAt *time.Time         `bson:"finishedAt,omitempty" json:"finishedAt,omitempty"`
+}
+
+// EnqueueJob stores job as pending. RunAt defaults to now. When a job with
+// the same key already exists it returns that job's id and false.
+func (s *Store) EnqueueJob(ctx context.Context, job Job) (primitive.ObjectID, bool, error) {
+	job.Kind = strings.TrimSpace(job.Kind)
+	if job.Kind == "" {
+		return primitive.NilObjectID, false, errors.New("job kind is required")
+	}
+
+	now := time.Now().UTC()
+	job.ID = primitive.NewObjectID()
+	job.Status = JobPending
+	job.Attempts = 0
+	job.LastError = ""
+	job.FinishedAt = nil
+	if job.RunAt.IsZero() {
+		job.RunAt = now
+	}
+	job.RunAt = job.RunAt.UTC()
+	job.CreatedAt = now
+	job.UpdatedAt = now
+
+	_, err := s.collection(jobsCollection).InsertOne(ctx, job)
+	if IsDuplicateKeyError(err, "key_1") {
+		var existing Job
+		findErr := s.collection(jobsCollection).FindOne(
+			ctx,
+			bson.M{"key": job.Key},
+			options.FindOne().SetProjection(bson.M{"_id": 1}),
+		).Decode(&existing)
+		if findErr != nil {
+			return primitive.NilObjectID, false, findErr
+		}
+		return existing.ID, false, nil
+	}
+	if err != nil {
+		return primitive.NilObjectID, false, err
+	}
+	return job.ID, true, nil
+}
+
+// ClaimJob picks the next due job of one of kinds and marks it as running
+// until lease has passed. Jobs left running by a worker that stopped are
+// picked up again once their lease ends. It returns nil when nothing is due.
+func (s *Store) ClaimJob(ctx context.Context, kinds []string, lease time.Duration) (*Job, error) {
+	now := time.Now().UTC()
+	var job Job
+	err := s.collection(jobsCollection).FindOneAndUpdate(
+		ctx,
+		bson.M{
+			"kind":   bson.M{"$in": kinds},
+			"status": bson.M{"$in": bson.A{JobPending, JobRunning}},
+			"runAt":  bson.M{"$lte": now},
+		},
+		bson.M{
+			"$set": bson.M{"status": JobRunning, "runAt": now.Add(lease), "updatedAt": now},
+			"$inc": bson.M{"attempts": 1},
+		},
+		options.FindOneAndUpdate().
+			SetSort(bson.D{{Key: "runAt", Value: 1}}).
+			SetReturnDocument(options.After),
+	).Decode(&job)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// CompleteJob marks a job done. Mail bodies are dropped, since a sent mail
+// is not needed again and its links should not outlive it in the queue.
+func (s *Store) CompleteJob(ctx context.Context, id primitive.ObjectID) error {
+	now := time.Now().UTC()
+	_, err := s.collection(jobsCollection).UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$set":   bson.M{"status": JobDone, "finishedAt": now, "updatedAt": now},
+		"$unset": bson.M{"lastError": "", "payload.text": "", "payload.html": ""},
+	})
+	return err
+}
+
+// RetryJob records a failed attempt and schedules the next one.
+func (s *Store) RetryJob(ctx context.Context, id primitive.ObjectID, message string, runAt time.Time) error {
+	_, err := s.collection(jobsCollection).UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{
+		"status":    JobPending,
+		"lastError": message,
+		"runAt":     runAt.UTC(),
+		"updatedAt": time.Now().UTC(),
+	}})
+	return err
+}
+
+// DeadLetterJob gives up on a job and keeps it for inspection.
+func (s *Store) DeadLetterJob(ctx context.Context, id primitive.ObjectID, message string) error {
+	now := time.Now().UTC()
+	_, err := s.collection(jobsCollection).UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{
+		"status":     JobDead,
+		"lastError":  message,
+		"finishedAt": now,
+		"updatedAt":  now,
+	}})
+	return err
+}
+
+// RequeueJob gives a dead job a fresh set of attempts. It returns false when
+// no dead job has that id.
+func (s *Store) RequeueJob(ctx context.Context, idText string) (bool, error) {
+	id, err := primitive.ObjectIDFromHex(strings.TrimSpace(idText))
+	if err != nil {
+		return false, nil
+	}
+	now := time.Now().UTC()
+	result, err := s.collection(jobsCollection).UpdateOne(
+		ctx,
+		bson.M{"_id": id, "status": JobDead},
+		bson.M{
+			"$set":   bson.M{"status": JobPending, "attempts": 0, "runAt": now, "updatedAt": now},
+			"$unset": bson.M{"finishedAt": ""},
+		},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+func (s *Store) FindJob(ctx context.Context, id primitive.ObjectID) (*Job, error) {
+	var job Job
+	err := s.collection(jobsCollection).FindOne(ctx, bson.M{"_id": id}).Decode(&job)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// redactedJobPayload leaves mail bodies out of job listings. They carry
+// password reset and verification links that would let the reader take
+// over the account.
+var redactedJobPayload = bson.M{"payload.text": 0, "payload.html": 0}
+
+// ListJobs returns a page of the newest jobs, optionally only those with
+// status and kind, and the total number of matches.
+func (s *Store) ListJobs(ctx context.Context, status, kind string, skip, limit int64) ([]Job, int64, error) {
+	filter := bson.M{}
+	if status = strings.TrimSpace(status); status != "" {
+		switch status {
+		case JobPending, JobRunning, JobDone, JobDead:
+		default:
+			return nil, 0, fmt.Errorf("%w: unknown job status %q", ErrInvalidJobQuery, status)
+		}
+		filter["status"] = status
+	}
+	if kind = strings.TrimSpace(kind); kind != "" {
+		filter["kind"] = kind
+	}
+
+	total, err := s.collection(jobsCollection).CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	cursor, err := s.collection(jobsCollection).Find(
+		ctx,
+		filter,
+		options.Find().
+			SetSort(bson.D{{Key: "_id", Value: -1}}).
+			SetSkip(skip).
+			SetLimit(limit).
+			SetProjection(redactedJobPayload),
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	jobs := make([]Job, 0)
+	if err := cursor.All(ctx, &jobs); err != nil {
+		return nil, 0, err
+	}
+	return jobs, total, nil
+}
+
+// PurgeFinishedJobs deletes done and dead jobs that finished before cutoff.
+// Their idempotency keys can be used again afterwards.
+func (s *Store) PurgeFinishedJobs(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := s.collection(jobsCollection).DeleteMany(ctx, bson.M{
+		"status":     bson.M{"$in": bson.A{JobDone, JobDead}},
+		"finishedAt": bson.M{"$lt": cutoff.UTC()},
+	})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
@@ -0,0 +1,167 @@
+package models
+
+import (
+	"context"
+	"math"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	loginAttemptsCollection = "login_attempts"
+
+	loginDelayFreeFailures = 3
+	loginMaxDelay          = time.Minute
+)
+
+type LoginThrottlePolicy struct {
+	MaxFailures int
+	Window      time.Duration
+	Lockout     time.Duration
+}
+
+type LoginThrottleStatus struct {
+	Locked     bool
+	RetryAfter time.Duration
+}
+
+func (s LoginThrottleStatus) Blocked() bool {
+	return s.RetryAfter > 0
+}
+
+type LoginFailureResult struct {
+	Failures    int
+	LockedNow   bool
+	LockedUntil time.Time
+}
+
+func LoginAccountKey(email string) string {
+	return "account:" + normalizeEmail(email)
+}
+
+func LoginClientKey(clientKey string) string {
+	return "ip:" + strings.TrimSpace(clientKey)
+}
+
+// CheckLoginThrottle reports the longest wait imposed on any of the keys,
+// whether from a lockout or from the progressive delay between failures.
+func (s *Store) CheckLoginThrottle(ctx context.Context, keys ...string) (LoginThrottleStatus, error) {
+	status := LoginThrottleStatus{}
+	if len(keys) == 0 {
+		return status, nil
+	}
+
+	cursor, err := s.collection(loginAttemptsCollection).Find(ctx, bson.M{"_id": bson.M{"$in": keys}})
+	if err != nil {
+		return status, err
+	}
+	defer cursor.Close(ctx)
+
+	type attempt struct {
+		LockedUntil   time.Time `bson:"lockedUntil"`
+		NextAllowedAt time.Time `bson:"nextAllowedAt"`
+	}
+
+	now := time.Now().UTC()
+	for cursor.Next(ctx) {
+		var item attempt
+		if err := cursor.Decode(&item); err != nil {
+			return status, err
+		}
+
+		if item.LockedUntil.After(now) {
+			status.Locked = true
+			if wait := item.LockedUntil.Sub(now); wait > status.RetryAfter {
+				status.RetryAfter = wait
+			}
+		}
+		if item.NextAllowedAt.After(now) {
+			if wait := item.NextAllowedAt.Sub(now); wait > status.RetryAfter {
+				status.RetryAfter = wait
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return status, err
+	}
+
+	return status, nil
+}
+
+func (s *Store) RecordLoginFailure(ctx context.Context, key string, policy LoginThrottlePolicy) (LoginFailureResult, error) {
+	result := LoginFailureResult{}
+	if policy.MaxFailures <= 0 {
+		policy.MaxFailures = 5
+	}
+	if policy.Window <= 0 {
+		policy.Window = 15 * time.Minute
+	}
+	if policy.Lockout <= 0 {
+		policy.Lockout = 15 * time.Minute
+	}
+
+	now := time.Now().UTC()
+	windowStart := now.Add(-policy.Window)
+	update := mongo.Pipeline{
+		bson.D{{Key: "$set", Value: bson.M{
+			"failures": bson.M{"$cond": bson.A{
+				bson.M{"$lt": bson.A{"$lastFailureAt", windowStart}},
+				1,
+				bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$failures", 0}}, 1}},
+			}},
+			"lastFailureAt": now,
+			"expiresAt":     now.Add(policy.Window + policy.Lockout),
+		}}},
+	}
+
+	var attempt struct {
+		Failures int `bson:"failures"`
+	}
+	err := s.collection(loginAttemptsCollection).FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": key},
+		update,
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&attempt)
+	if err != nil {
+		return result, err
+	}
+	result.Failures = attempt.Failures
+
+	set := bson.M{"nextAllowedAt": now.Add(loginFailureDelay(attempt.Failures))}
+	if attempt.Failures >= policy.MaxFailures {
+		result.LockedNow = true
+		result.LockedUntil = now.Add(policy.Lockout)
+		set = bson.M{
+			"failures":      0,
+			"lockedUntil":   result.LockedUntil,
+			"nextAllowedAt": result.LockedUntil,
+		}
+	}
+
+	_, err = s.collection(loginAttemptsCollection).UpdateOne(ctx, bson.M{"_id": key}, bson.M{"$set": set})
+	return result, err
+}
+
+func (s *Store) ClearLoginFailures(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	_, err := s.collection(loginAttemptsCollection).DeleteMany(ctx, bson.M{"_id": bson.M{"$in": keys}})
+	return err
+}
+
+func loginFailureDelay(failures int) time.Duration {
+	if failures <= loginDelayFreeFailures {
+		return 0
+	}
+	delay := time.Duration(math.Pow(2, float64(failures-loginDelayFreeFailures))) * time.Second
+	if delay > loginMaxDelay {
+		return loginMaxDelay
+	}
+	return delay
+}
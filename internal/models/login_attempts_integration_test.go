@@ -0,0 +1,92 @@
+package models
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"easybook/internal/db"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestLoginFailuresLockAccountAfterLimit(t *testing.T) {
+	mongoURI := strings.TrimSpace(os.Getenv("MONGO_URI"))
+	if mongoURI == "" {
+		t.Skip("MONGO_URI is not set; skipping integration test")
+	}
+
+	dbName := "easybook_login_test_" + primitive.NewObjectID().Hex()
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURI))
+	if err != nil {
+		t.Fatalf("connect mongo: %v", err)
+	}
+	defer func() {
+		_ = client.Disconnect(context.Background())
+	}()
+
+	database := client.Database(dbName)
+	defer func() {
+		_ = database.Drop(context.Background())
+	}()
+
+	if err := db.EnsureStartupMaintenance(ctx, database); err != nil {
+		t.Fatalf("ensure indexes: %v", err)
+	}
+
+	store := NewStore(database)
+	accountKey := LoginAccountKey("Locked@Example.com")
+	policy := LoginThrottlePolicy{MaxFailures: 3, Window: time.Minute, Lockout: time.Minute}
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		result, err := store.RecordLoginFailure(ctx, accountKey, policy)
+		if err != nil {
+			t.Fatalf("record failure %d: %v", attempt, err)
+		}
+		if result.Failures != attempt || result.LockedNow {
+			t.Fatalf("unexpected result after failure %d: %+v", attempt, result)
+		}
+	}
+
+	status, err := store.CheckLoginThrottle(ctx, accountKey)
+	if err != nil {
+		t.Fatalf("check throttle: %v", err)
+	}
+	if status.Blocked() {
+		t.Fatalf("expected no delay before the delay-free limit, got %+v", status)
+	}
+
+	result, err := store.RecordLoginFailure(ctx, accountKey, policy)
+	if err != nil {
+		t.Fatalf("record locking failure: %v", err)
+	}
+	if !result.LockedNow {
+		t.Fatalf("expected account to be locked, got %+v", result)
+	}
+
+	status, err = store.CheckLoginThrottle(ctx, accountKey, LoginClientKey("203.0.113.7"))
+	if err != nil {
+		t.Fatalf("check throttle after lock: %v", err)
+	}
+	if !status.Locked || status.RetryAfter <= 0 {
+		t.Fatalf("expected locked status, got %+v", status)
+	}
+
+	if err := store.ClearLoginFailures(ctx, accountKey); err != nil {
+		t.Fatalf("clear failures: %v", err)
+	}
+	status, err = store.CheckLoginThrottle(ctx, accountKey)
+	if err != nil {
+		t.Fatalf("check throttle after clear: %v", err)
+	}
+	if status.Blocked() {
+		t.Fatalf("expected throttle to be cleared, got %+v", status)
+	}
+}
@@ -0,0 +1,138 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const notificationPreferencesCollection = "notification_preferences"
+
+const (
+	ChannelInApp   = "in_app"
+	ChannelEmail   = "email"
+	ChannelWebhook = "webhook"
+	ChannelDigest  = "digest"
+)
+
+// NotificationChannels lists the channels users can choose per type.
+var NotificationChannels = []string{ChannelInApp, ChannelEmail, ChannelWebhook, ChannelDigest}
+
+type NotificationTypeInfo struct {
+	Type  string `json:"type"`
+	Label string `json:"label"`
+	// Transactional types are always delivered in-app and by email.
+	Transactional bool `json:"transactional"`
+}
+
+var NotificationTypes = []NotificationTypeInfo{
+	{Type: NotificationBookingConfirmed, Label: "Booking confirmations", Transactional: true},
+	{Type: NotificationBookingUpdated, Label: "Booking changes", Transactional: true},
+	{Type: NotificationBookingCancelled, Label: "Booking cancellations", Transactional: true},
+	{Type: NotificationBookingReminder, Label: "Upcoming stay reminders"},
+	{Type: NotificationReviewRequest, Label: "Review requests"},
+	{Type: NotificationAccountLocked, Label: "Security alerts"},
+	{Type: NotificationWaitlistAvailable, Label: "Waitlist: room available"},
+	{Type: NotificationDataExportReady, Label: "Data export ready"},
+	{Type: NotificationAnnouncement, Label: "Announcements"},
+	{Type: NotificationGeneral, Label: "Other updates"},
+}
+
+func notificationTypeInfo(notificationType string) (NotificationTypeInfo, bool) {
+	for _, info := range NotificationTypes {
+		if info.Type == notificationType {
+			return info, true
+		}
+	}
+	return NotificationTypeInfo{}, false
+}
+
+// IsMandatoryChannel reports whether users cannot turn off notificationType
+// on channel.
+func IsMandatoryChannel(notificationType, channel string) bool {
+	info, ok := notificationTypeInfo(notificationType)
+	return ok && info.Transactional && (channel == ChannelInApp || channel == ChannelEmail)
+}
+
+// NotificationPreferences holds the user's choices as type -> channel ->
+// enabled. Combinations without a saved choice use the channel's default.
+type NotificationPreferences struct {
+	UserID    primitive.ObjectID         `bson:"_id" json:"-"`
+	Settings  map[string]map[string]bool `bson:"settings" json:"settings"`
+	UpdatedAt time.Time                  `bson:"updatedAt" json:"updatedAt"`
+}
+
+// Allows reports whether notificationType may be sent on channel. fallback
+// applies when the user has not chosen; p may be nil.
+func (p *NotificationPreferences) Allows(notificationType, channel string, fallback bool) bool {
+	if IsMandatoryChannel(notificationType, channel) {
+		return true
+	}
+	if p != nil {
+		if enabled, ok := p.Settings[notificationType][channel]; ok {
+			return enabled
+		}
+	}
+	return fallback
+}
+
+// FindNotificationPreferences returns the user's saved preferences, or nil
+// when they never changed them.
+func (s *Store) FindNotificationPreferences(ctx context.Context, userIDText string) (*NotificationPreferences, error) {
+	userID, err := primitive.ObjectIDFromHex(strings.TrimSpace(userIDText))
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid user id", ErrUnauthorizedNotificationOp)
+	}
+
+	var preferences NotificationPreferences
+	err = s.collection(notificationPreferencesCollection).FindOne(ctx, bson.M{"_id": userID}).Decode(&preferences)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &preferences, nil
+}
+
+// UpdateNotificationPreferences saves the given type -> channel choices and
+// leaves the others as they were.
+func (s *Store) UpdateNotificationPreferences(ctx context.Context, userIDText string, settings map[string]map[string]bool) error {
+	userID, err := primitive.ObjectIDFromHex(strings.TrimSpace(userIDText))
+	if err != nil {
+		return fmt.Errorf("%w: invalid user id", ErrUnauthorizedNotificationOp)
+	}
+
+	update := bson.M{"updatedAt": time.Now().UTC()}
+	for notificationType, channels := range settings {
+		if _, ok := notificationTypeInfo(notificationType); !ok {
+			return fmt.Errorf("%w: unknown notification type %q", ErrInvalidNotificationPreferences, notificationType)
+		}
+		for channel, enabled := range channels {
+			if !slices.Contains(NotificationChannels, channel) {
+				return fmt.Errorf("%w: unknown channel %q", ErrInvalidNotificationPreferences, channel)
+			}
+			if !enabled && IsMandatoryChannel(notificationType, channel) {
+				info, _ := notificationTypeInfo(notificationType)
+				return fmt.Errorf("%w: %s cannot be turned off for %s", ErrInvalidNotificationPreferences, info.Label, channel)
+			}
+			update["settings."+notificationType+"."+channel] = enabled
+		}
+	}
+
+	_, err = s.collection(notificationPreferencesCollection).UpdateOne(
+		ctx,
+		bson.M{"_id": userID},
+		bson.M{"$set": update},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
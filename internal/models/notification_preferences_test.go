@@ -0,0 +1,27 @@
+package models
+
+import "testing"
+
+func TestNotificationPreferencesAllows(t *testing.T) {
+	var none *NotificationPreferences
+	if !none.Allows(NotificationGeneral, ChannelEmail, true) || none.Allows(NotificationGeneral, ChannelEmail, false) {
+		t.Fatal("expected the fallback without saved preferences")
+	}
+
+	preferences := &NotificationPreferences{Settings: map[string]map[string]bool{
+		NotificationWaitlistAvailable: {ChannelEmail: false, ChannelWebhook: true},
+		NotificationBookingConfirmed:  {ChannelEmail: false, ChannelDigest: false},
+	}}
+	if preferences.Allows(NotificationWaitlistAvailable, ChannelEmail, true) {
+		t.Fatal("expected a saved opt-out to win over the default")
+	}
+	if !preferences.Allows(NotificationWaitlistAvailable, ChannelWebhook, false) {
+		t.Fatal("expected a saved opt-in to win over the default")
+	}
+	if !preferences.Allows(NotificationBookingConfirmed, ChannelEmail, false) {
+		t.Fatal("expected transactional email to be mandatory")
+	}
+	if preferences.Allows(NotificationBookingConfirmed, ChannelDigest, true) {
+		t.Fatal("expected optional channels of transactional types to follow preferences")
+	}
+}
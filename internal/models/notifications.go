@@ -2,6 +2,7 @@ package models
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/url"
 	"strings"
@@ -18,6 +19,55 @@ const (
 	notificationsCollection = "notifications"
 )
 
+// Notification types select the templates used by delivery channels.
+const (
+	NotificationGeneral           = "general"
+	NotificationWaitlistAvailable = "waitlist_available"
+	NotificationDataExportReady   = "data_export_ready"
+	NotificationAccountLocked     = "account_locked"
+	NotificationBookingConfirmed  = "booking_confirmed"
+	NotificationBookingUpdated    = "booking_updated"
+	NotificationBookingCancelled  = "booking_cancelled"
+	NotificationBookingReminder   = "booking_reminder"
+	NotificationReviewRequest     = "review_request"
+	NotificationAnnouncement      = "announcement"
+)
+
+type Notification struct {
+	ID      primitive.ObjectID `bson:"_id" json:"id"`
+	UserID  primitive.ObjectID `bson:"userId" json:"-"`
+	GroupID primitive.ObjectID `bson:"groupId,omitempty" json:"-"`
+	// BroadcastID links copies of an admin announcement.
+	BroadcastID primitive.ObjectID `bson:"broadcastId,omitempty" json:"-"`
+	Type        string             `bson:"type" json:"type"`
+	Title       string             `bson:"title" json:"title"`
+	Text        string             `bson:"text" json:"text"`
+	Link        string             `bson:"link" json:"link"`
+	IsRead      bool               `bson:"isRead" json:"isRead"`
+	// Hidden notifications are kept for other channels only; the user
+	// turned off in-app delivery for their type.
+	Hidden     bool       `bson:"hidden,omitempty" json:"-"`
+	CreatedAt  time.Time  `bson:"createdAt" json:"createdAt"`
+	ArchivedAt *time.Time `bson:"archivedAt,omitempty" json:"archivedAt,omitempty"`
+	// PurgeAt is set once the notification is read when a retention period
+	// is configured; a TTL index removes the notification at that time.
+	PurgeAt *time.Time `bson:"purgeAt,omitempty" json:"-"`
+}
+
+// Statuses accepted by NotificationQuery. The default lists everything
+// that is not archived.
+const (
+	NotificationStatusUnread   = "unread"
+	NotificationStatusRead     = "read"
+	NotificationStatusArchived = "archived"
+)
+
+// NotificationQuery narrows a user's notification list.
+type NotificationQuery struct {
+	Status string
+	Type   string
+}
+
 func (s *Store) SubscribeToWaitlist(
 	ctx context.Context,
 	userIDText,
@@ -25,6 +75,7 @@ func (s *Store) SubscribeToWaitlist(
 	checkIn,
 	checkOut,
 	waitlistType string,
+	flexibility WaitlistFlexibility,
 ) (string, string, error) {
 	waitlistType = strings.ToLower(strings.TrimSpace(waitlistType))
 	if waitlistType == "" {
@@ -46,13 +97,22 @@ func (s *Store) SubscribeToWaitlist(
 
 	checkIn = strings.TrimSpace(checkIn)
 	checkOut = strings.TrimSpace(checkOut)
-	checkInDate, _, err := parseBookingDateRange(checkIn, checkOut)
+	checkInDate, checkOutDate, err := parseBookingDateRange(checkIn, checkOut)
 	if err != nil {
 		return "", "", fmt.Errorf("%w: %v", ErrInvalidWaitlistPayload, err)
 	}
 	if isBeforeToday(checkInDate) {
 		return "", "", fmt.Errorf("%w: cannot subscribe for past dates", ErrInvalidWaitlistPayload)
 	}
+	window, err := buildDateSlots(checkIn, checkOut)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %v", ErrInvalidWaitlistPayload, err)
+	}
+	windowNights := len(window)
+	nights, minNights, err := flexibility.normalize(windowNights)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %v", ErrInvalidWaitlistPayload, err)
+	}
 
 	dupFilter := bson.M{
 		"userId":   userID,
@@ -103,6 +163,15 @@ func (s *Store) SubscribeToWaitlist(
 		"createdAt": now,
 		"updatedAt": now,
 	}
+	// Exact subscriptions keep the original shape; flexible ones store the
+	// lengths they accept.
+	lastCheckIn := waitlistLastCheckIn(checkInDate, checkOutDate, 0)
+	if nights != windowNights || minNights != nights {
+		doc["nights"] = nights
+		doc["minNights"] = minNights
+		lastCheckIn = waitlistLastCheckIn(checkInDate, checkOutDate, minNights)
+	}
+	doc["lastCheckIn"] = lastCheckIn
 
 	if waitlistType == WaitlistPriority {
 		doc["groupId"] = groupID
@@ -133,6 +202,20 @@ func (s *Store) SubscribeToWaitlist(
 
 }
 
+func waitlistAvailableText(subscription bson.M, stay stayRange, holdUntil time.Time) string {
+	text := fmt.Sprintf("Room is now available for %s to %s.", stay.CheckIn, stay.CheckOut)
+	if _, flexible := subscription["nights"]; flexible {
+		text = fmt.Sprintf(
+			"Room is now available for %d night(s) from %s to %s, within your dates %s to %s.",
+			stay.nights(), stay.CheckIn, stay.CheckOut, subscription["checkIn"], subscription["checkOut"],
+		)
+	}
+	if !holdUntil.IsZero() {
+		text += fmt.Sprintf(" It is held for you until %s; after that it goes to the next person waiting.", holdUntil.In(time.Local).Format("2006-01-02 15:04"))
+	}
+	return text
+}
+
 func (s *Store) ProcessWaitlistForRoom(ctx context.Context, roomIDText string) (int64, error) {
 	roomIDText = strings.TrimSpace(roomIDText)
 	if roomIDText == "" {
@@ -149,7 +232,12 @@ func (s *Store) ProcessWaitlistForRoom(ctx context.Context, roomIDText string) (
 	processType := func(waitlistType string, stopAfterFirst bool) (int64, error) {
 		cursor, err := s.collection(waitlistCollection).Find(
 			ctx,
-			bson.M{"roomId": roomID, "isActive": true, "type": waitlistType},
+			bson.M{
+				"roomId":   roomID,
+				"isActive": true,
+				"type":     waitlistType,
+				"$and":     bson.A{stillOpenWaitlistFilter(toLocalDate(time.Now()).Format("2006-01-02"))},
+			},
 			options.Find().SetSort(bson.D{
 				{Key: "tyoe", Value: -1},
 				{Key: "createdAt", Value: 1},
@@ -175,29 +263,45 @@ func (s *Store) ProcessWaitlistForRoom(ctx context.Context, roomIDText string) (
 				continue
 			}
 
-			checkIn := strings.TrimSpace(fmt.Sprint(subscription["checkIn"]))
-			checkOut := strings.TrimSpace(fmt.Sprint(subscription["checkOut"]))
-			if _, _, rangeErr := parseBookingDateRange(checkIn, checkOut); rangeErr != nil {
+			stay, found, stayErr := s.findWaitlistStay(ctx, roomID, subscription)
+			if stayErr != nil {
+				return createdNotifications, stayErr
+			}
+			if !found {
 				continue
 			}
-
-			conflict, conflictErr := s.hasBookingConflict(ctx, roomID, checkIn, checkOut, nil)
-			if conflictErr != nil {
-				return createdNotifications, conflictErr
+			checkIn, checkOut := stay.CheckIn, stay.CheckOut
+
+			deactivate := bson.M{"isActive": false, "notifiedAt": time.Now().UTC(), "updatedAt": time.Now().UTC()}
+			var holdUntil time.Time
+			if s.waitlistHold > 0 {
+				holdUntil = time.Now().Add(s.waitlistHold)
+				held, holdErr := s.holdWaitlistStay(ctx, roomID, subscriptionID, userID, stay, holdUntil)
+				if holdErr != nil {
+					return createdNotifications, holdErr
+				}
+				if !held {
+					continue
+				}
+				deactivate["holdUntil"] = holdUntil.UTC()
 			}
-			if conflict {
-				continue
+			dropHold := func() {
+				if !holdUntil.IsZero() {
+					_, _ = s.collection(roomCalendarCollection).DeleteMany(ctx, bson.M{"waitlistId": subscriptionID})
+				}
 			}
 
 			deactivateResult, deactivateErr := s.collection(waitlistCollection).UpdateOne(
 				ctx,
 				bson.M{"_id": subscriptionID, "isActive": true},
-				bson.M{"$set": bson.M{"isActive": false, "updatedAt": time.Now().UTC()}},
+				bson.M{"$set": deactivate},
 			)
 			if deactivateErr != nil {
+				dropHold()
 				return createdNotifications, deactivateErr
 			}
 			if deactivateResult.ModifiedCount == 0 {
+				dropHold()
 				continue
 			}
 
@@ -205,27 +309,26 @@ func (s *Store) ProcessWaitlistForRoom(ctx context.Context, roomIDText string) (
 				"&checkIn=" + url.QueryEscape(checkIn) +
 				"&checkOut=" + url.QueryEscape(checkOut)
 
-			notificationDoc := bson.M{
-				"userId":    userID,
-				"title":     "Room is available now",
-				"text":      fmt.Sprintf("Room is now available for %s to %s.", checkIn, checkOut),
-				"link":      link,
-				"isRead":    false,
-				"createdAt": time.Now().UTC(),
+			notification := Notification{
+				UserID: userID,
+				Type:   NotificationWaitlistAvailable,
+				Title:  "Room is available now",
+				Text:   waitlistAvailableText(subscription, stay, holdUntil),
+				Link:   link,
 			}
-
 			if gid, ok := subscription["groupId"].(primitive.ObjectID); ok {
-				notificationDoc["groupId"] = gid
+				notification.GroupID = gid
 			}
 
-			_, notificationErr := s.collection(notificationsCollection).InsertOne(ctx, notificationDoc)
-
-			if notificationErr != nil {
-
+			if _, notificationErr := s.insertNotification(ctx, notification); notificationErr != nil {
+				dropHold()
 				_, _ = s.collection(waitlistCollection).UpdateOne(
 					ctx,
 					bson.M{"_id": subscriptionID},
-					bson.M{"$set": bson.M{"isActive": true, "updatedAt": time.Now().UTC()}},
+					bson.M{
+						"$set":   bson.M{"isActive": true, "updatedAt": time.Now().UTC()},
+						"$unset": bson.M{"notifiedAt": "", "holdUntil": ""},
+					},
 				)
 				return createdNotifications, notificationErr
 			}
@@ -247,24 +350,108 @@ func (s *Store) ProcessWaitlistForRoom(ctx context.Context, roomIDText string) (
 		return createdNotifications, nil
 	}
 
-	_, err = processType(WaitlistMain, false)
+	// With holds the room goes to one subscriber at a time, in queue order;
+	// without them every main subscriber hears about it and the first to
+	// book wins.
+	_, err = processType(WaitlistMain, s.waitlistHold > 0)
 	return createdNotifications, err
 }
 
-func (s *Store) ListNotifications(ctx context.Context, userIDText string, limit int64) ([]bson.M, int64, error) {
+func (s *Store) CreateNotification(ctx context.Context, userIDText, notificationType, title, text, link string) (string, error) {
+	userID, err := primitive.ObjectIDFromHex(strings.TrimSpace(userIDText))
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid user id", ErrUnauthorizedNotificationOp)
+	}
+
+	notification, err := s.insertNotification(ctx, Notification{
+		UserID: userID,
+		Type:   notificationType,
+		Title:  title,
+		Text:   text,
+		Link:   link,
+	})
+	if err != nil {
+		return "", err
+	}
+	return notification.ID.Hex(), nil
+}
+
+func (s *Store) insertNotification(ctx context.Context, notification Notification) (Notification, error) {
+	if notification.Type == "" {
+		notification.Type = NotificationGeneral
+	}
+	preferences, err := s.FindNotificationPreferences(ctx, notification.UserID.Hex())
+	if err != nil {
+		return Notification{}, err
+	}
+	notification.Hidden = !preferences.Allows(notification.Type, ChannelInApp, true)
+	notification.ID = primitive.NewObjectID()
+	notification.IsRead = notification.Hidden
+	notification.CreatedAt = time.Now().UTC()
+	if notification.Hidden {
+		notification.PurgeAt = s.notificationPurgeAt(notification.CreatedAt)
+	}
+
+	if _, err := s.collection(notificationsCollection).InsertOne(ctx, notification); err != nil {
+		return Notification{}, err
+	}
+
+	if !notification.Hidden {
+		s.notificationsChanged(notification.UserID)
+	}
+	if s.notificationCreated != nil {
+		s.notificationCreated(ctx, notification)
+	}
+	return notification, nil
+}
+
+func (s *Store) FindNotificationByID(ctx context.Context, notificationID primitive.ObjectID) (*Notification, error) {
+	var notification Notification
+	err := s.collection(notificationsCollection).FindOne(ctx, bson.M{"_id": notificationID}).Decode(&notification)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotificationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &notification, nil
+}
+
+// ListNotifications returns a page of the user's notifications matching
+// query, newest first, and the total number of matches.
+func (s *Store) ListNotifications(ctx context.Context, userIDText string, query NotificationQuery, skip, limit int64) ([]bson.M, int64, error) {
 	userID, err := primitive.ObjectIDFromHex(strings.TrimSpace(userIDText))
 	if err != nil {
 		return nil, 0, fmt.Errorf("%w: invalid user id", ErrUnauthorizedNotificationOp)
 	}
 
-	if limit <= 0 || limit > 200 {
-		limit = 100
+	filter := bson.M{"userId": userID, "hidden": bson.M{"$ne": true}}
+	switch status := strings.TrimSpace(query.Status); status {
+	case "":
+		filter["archivedAt"] = bson.M{"$exists": false}
+	case NotificationStatusUnread:
+		filter["archivedAt"] = bson.M{"$exists": false}
+		filter["isRead"] = false
+	case NotificationStatusRead:
+		filter["archivedAt"] = bson.M{"$exists": false}
+		filter["isRead"] = true
+	case NotificationStatusArchived:
+		filter["archivedAt"] = bson.M{"$exists": true}
+	default:
+		return nil, 0, fmt.Errorf("%w: unknown notification status %q", ErrInvalidNotificationQuery, status)
+	}
+	if notificationType := strings.TrimSpace(query.Type); notificationType != "" {
+		filter["type"] = notificationType
 	}
 
+	total, err := s.collection(notificationsCollection).CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
 	cursor, err := s.collection(notificationsCollection).Find(
 		ctx,
-		bson.M{"userId": userID},
-		options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}).SetLimit(limit),
+		filter,
+		options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}).SetSkip(skip).SetLimit(limit),
 	)
 	if err != nil {
 		return nil, 0, err
@@ -275,16 +462,7 @@ func (s *Store) ListNotifications(ctx context.Context, userIDText string, limit
 	if err := cursor.All(ctx, &items); err != nil {
 		return nil, 0, err
 	}
-
-	unreadCount, err := s.collection(notificationsCollection).CountDocuments(ctx, bson.M{
-		"userId": userID,
-		"isRead": false,
-	})
-	if err != nil {
-		return nil, 0, err
-	}
-
-	return items, unreadCount, nil
+	return items, total, nil
 }
 
 func (s *Store) MarkNotificationRead(ctx context.Context, userIDText, notificationIDText string) (bool, error) {
@@ -299,18 +477,23 @@ func (s *Store) MarkNotificationRead(ctx context.Context, userIDText, notificati
 
 	result, err := s.collection(notificationsCollection).UpdateOne(
 		ctx,
-		bson.M{"_id": notificationID, "userId": userID},
-		bson.M{"$set": bson.M{
-			"isRead": true,
-			"readAt": time.Now().UTC(),
-		}},
+		bson.M{"_id": notificationID, "userId": userID, "isRead": false},
+		s.markNotificationsRead(bson.M{}),
 	)
 	if err != nil {
 		return false, err
 	}
 	if result.MatchedCount == 0 {
-		return false, ErrNotificationNotFound
+		count, err := s.collection(notificationsCollection).CountDocuments(ctx, bson.M{"_id": notificationID, "userId": userID})
+		if err != nil {
+			return false, err
+		}
+		if count == 0 {
+			return false, ErrNotificationNotFound
+		}
+		return true, nil
 	}
+	s.notificationsChanged(userID)
 
 	return true, nil
 }
@@ -324,11 +507,169 @@ func (s *Store) MarkAllNotificationsRead(ctx context.Context, userIDText string)
 	result, err := s.collection(notificationsCollection).UpdateMany(
 		ctx,
 		bson.M{"userId": userID, "isRead": false},
-		bson.M{"$set": bson.M{"isRead": true, "readAt": time.Now().UTC()}},
+		s.markNotificationsRead(bson.M{}),
 	)
 	if err != nil {
 		return 0, err
 	}
+	if result.ModifiedCount > 0 {
+		s.notificationsChanged(userID)
+	}
 
 	return result.ModifiedCount, nil
 }
+
+// ArchiveNotification moves a notification out of the user's list. Archived
+// notifications count as read and follow the same retention period.
+func (s *Store) ArchiveNotification(ctx context.Context, userIDText, notificationIDText string) error {
+	userID, err := primitive.ObjectIDFromHex(strings.TrimSpace(userIDText))
+	if err != nil {
+		return fmt.Errorf("%w: invalid user id", ErrUnauthorizedNotificationOp)
+	}
+	notificationID, err := primitive.ObjectIDFromHex(strings.TrimSpace(notificationIDText))
+	if err != nil {
+		return ErrNotificationNotFound
+	}
+
+	// Read notifications already have their readAt and purgeAt.
+	now := time.Now().UTC()
+	result, err := s.collection(notificationsCollection).UpdateOne(
+		ctx,
+		bson.M{"_id": notificationID, "userId": userID, "isRead": false},
+		s.markNotificationsRead(bson.M{"archivedAt": now}),
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		result, err = s.collection(notificationsCollection).UpdateOne(
+			ctx,
+			bson.M{"_id": notificationID, "userId": userID},
+			bson.M{"$set": bson.M{"archivedAt": now}},
+		)
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return ErrNotificationNotFound
+		}
+	}
+	s.notificationsChanged(userID)
+	return nil
+}
+
+// DeleteNotification removes one of the user's notifications.
+func (s *Store) DeleteNotification(ctx context.Context, userIDText, notificationIDText string) error {
+	userID, err := primitive.ObjectIDFromHex(strings.TrimSpace(userIDText))
+	if err != nil {
+		return fmt.Errorf("%w: invalid user id", ErrUnauthorizedNotificationOp)
+	}
+	notificationID, err := primitive.ObjectIDFromHex(strings.TrimSpace(notificationIDText))
+	if err != nil {
+		return ErrNotificationNotFound
+	}
+
+	result, err := s.collection(notificationsCollection).DeleteOne(ctx, bson.M{"_id": notificationID, "userId": userID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotificationNotFound
+	}
+	s.notificationsChanged(userID)
+	return nil
+}
+
+// markNotificationsRead returns an update pipeline that marks unread
+// notifications as read, sets fields as well and, with a retention period,
+// schedules each notification's purge relative to its creation. Announcement
+// copies are kept, since a broadcast's read total is counted from them.
+func (s *Store) markNotificationsRead(fields bson.M) bson.A {
+	fields["isRead"] = true
+	fields["readAt"] = "$$NOW"
+	if s.notificationRetention > 0 {
+		fields["purgeAt"] = bson.M{"$cond": bson.A{
+			bson.M{"$gt": bson.A{"$broadcastId", nil}},
+			"$$REMOVE",
+			bson.M{"$add": bson.A{"$createdAt", s.notificationRetention.Milliseconds()}},
+		}}
+	}
+	return bson.A{bson.M{"$set": fields}}
+}
+
+func (s *Store) notificationPurgeAt(createdAt time.Time) *time.Time {
+	if s.notificationRetention <= 0 {
+		return nil
+	}
+	purgeAt := createdAt.Add(s.notificationRetention).UTC()
+	return &purgeAt
+}
+
+// ScheduleNotificationPurges gives read notifications without a purge time,
+// such as those read before retention was turned on, one based on the
+// current retention period. It returns how many were updated.
+func (s *Store) ScheduleNotificationPurges(ctx context.Context) (int64, error) {
+	if s.notificationRetention <= 0 {
+		return 0, nil
+	}
+	result, err := s.collection(notificationsCollection).UpdateMany(
+		ctx,
+		bson.M{"isRead": true, "purgeAt": bson.M{"$exists": false}, "broadcastId": bson.M{"$exists": false}},
+		bson.A{bson.M{"$set": bson.M{
+			"purgeAt": bson.M{"$add": bson.A{"$createdAt", s.notificationRetention.Milliseconds()}},
+		}}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// ListNotificationsSince returns the user's notifications created at or
+// after since, oldest first. It backs stream resumption, which overlaps
+// windows because creation times from several instances do not arrive in
+// order.
+func (s *Store) ListNotificationsSince(ctx context.Context, userIDText string, since time.Time, limit int64) ([]bson.M, error) {
+	userID, err := primitive.ObjectIDFromHex(strings.TrimSpace(userIDText))
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid user id", ErrUnauthorizedNotificationOp)
+	}
+
+	if limit <= 0 || limit > 200 {
+		limit = 100
+	}
+
+	cursor, err := s.collection(notificationsCollection).Find(
+		ctx,
+		bson.M{"userId": userID, "createdAt": bson.M{"$gte": since.UTC()}, "hidden": bson.M{"$ne": true}},
+		options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}}).SetLimit(limit),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	items := make([]bson.M, 0)
+	if err := cursor.All(ctx, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (s *Store) CountUnreadNotifications(ctx context.Context, userIDText string) (int64, error) {
+	userID, err := primitive.ObjectIDFromHex(strings.TrimSpace(userIDText))
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid user id", ErrUnauthorizedNotificationOp)
+	}
+	return s.collection(notificationsCollection).CountDocuments(ctx, bson.M{"userId": userID, "isRead": false})
+}
+
+func (s *Store) NotificationsCollection() *mongo.Collection {
+	return s.collection(notificationsCollection)
+}
+
+func (s *Store) notificationsChanged(userID primitive.ObjectID) {
+	if s.notificationListener != nil {
+		s.notificationListener(userID.Hex())
+	}
+}
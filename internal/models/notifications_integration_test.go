@@ -0,0 +1,141 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"easybook/internal/db"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestNotificationListArchiveAndRetention(t *testing.T) {
+	mongoURI := strings.TrimSpace(os.Getenv("MONGO_URI"))
+	if mongoURI == "" {
+		t.Skip("MONGO_URI is not set; skipping integration test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURI))
+	if err != nil {
+		t.Fatalf("connect mongo: %v", err)
+	}
+	defer func() {
+		_ = client.Disconnect(context.Background())
+	}()
+
+	database := client.Database("easybook_test_" + primitive.NewObjectID().Hex())
+	defer func() {
+		_ = database.Drop(context.Background())
+	}()
+	if err := db.EnsureStartupMaintenance(ctx, database); err != nil {
+		t.Fatalf("ensure indexes: %v", err)
+	}
+
+	store := NewStore(database)
+	store.SetNotificationRetention(30 * 24 * time.Hour)
+	userID := primitive.NewObjectID().Hex()
+
+	ids := make([]string, 0, 5)
+	for i := 0; i < 5; i++ {
+		notificationType := NotificationGeneral
+		if i%2 == 0 {
+			notificationType = NotificationBookingReminder
+		}
+		id, err := store.CreateNotification(ctx, userID, notificationType, "Title", "Text", "")
+		if err != nil {
+			t.Fatalf("create notification: %v", err)
+		}
+		ids = append(ids, id)
+	}
+
+	items, total, err := store.ListNotifications(ctx, userID, NotificationQuery{}, 2, 2)
+	if err != nil {
+		t.Fatalf("list notifications: %v", err)
+	}
+	if total != 5 || len(items) != 2 || items[0]["_id"].(primitive.ObjectID).Hex() != ids[2] {
+		t.Fatalf("expected the second page of 5 notifications, got %d of %d", len(items), total)
+	}
+	_, total, err = store.ListNotifications(ctx, userID, NotificationQuery{Type: NotificationBookingReminder}, 0, 10)
+	if err != nil || total != 3 {
+		t.Fatalf("expected 3 reminders, got %d (%v)", total, err)
+	}
+	if _, _, err := store.ListNotifications(ctx, userID, NotificationQuery{Status: "old"}, 0, 10); !errors.Is(err, ErrInvalidNotificationQuery) {
+		t.Fatalf("expected an invalid query error, got %v", err)
+	}
+
+	if _, err := store.MarkNotificationRead(ctx, userID, ids[0]); err != nil {
+		t.Fatalf("mark read: %v", err)
+	}
+	if err := store.ArchiveNotification(ctx, userID, ids[1]); err != nil {
+		t.Fatalf("archive: %v", err)
+	}
+	if err := store.DeleteNotification(ctx, userID, ids[2]); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if err := store.DeleteNotification(ctx, primitive.NewObjectID().Hex(), ids[3]); !errors.Is(err, ErrNotificationNotFound) {
+		t.Fatalf("expected another user's notification to be off limits, got %v", err)
+	}
+
+	counts := map[string]int64{
+		"":                         3,
+		NotificationStatusUnread:   2,
+		NotificationStatusRead:     1,
+		NotificationStatusArchived: 1,
+	}
+	for status, want := range counts {
+		_, total, err := store.ListNotifications(ctx, userID, NotificationQuery{Status: status}, 0, 10)
+		if err != nil || total != want {
+			t.Fatalf("expected %d notifications with status %q, got %d (%v)", want, status, total, err)
+		}
+	}
+	unread, err := store.CountUnreadNotifications(ctx, userID)
+	if err != nil || unread != 2 {
+		t.Fatalf("expected 2 unread notifications, got %d (%v)", unread, err)
+	}
+
+	for _, id := range ids[:2] {
+		notificationID, _ := primitive.ObjectIDFromHex(id)
+		notification, err := store.FindNotificationByID(ctx, notificationID)
+		if err != nil {
+			t.Fatalf("find notification: %v", err)
+		}
+		if notification.PurgeAt == nil || !notification.PurgeAt.Equal(notification.CreatedAt.Add(30*24*time.Hour)) {
+			t.Fatalf("expected read notification %s to be purged 30 days after creation, got %v", id, notification.PurgeAt)
+		}
+	}
+
+	notificationID, _ := primitive.ObjectIDFromHex(ids[3])
+	if _, err := database.Collection(notificationsCollection).UpdateOne(ctx, bson.M{"_id": notificationID}, bson.M{"$set": bson.M{"isRead": true}}); err != nil {
+		t.Fatalf("mark read without purge time: %v", err)
+	}
+	copyID := primitive.NewObjectID()
+	userObjectID, _ := primitive.ObjectIDFromHex(userID)
+	if _, err := database.Collection(notificationsCollection).InsertOne(ctx, bson.M{
+		"_id": copyID, "userId": userObjectID, "type": NotificationAnnouncement, "title": "Title", "text": "Text",
+		"isRead": false, "createdAt": time.Now().UTC(), "broadcastId": primitive.NewObjectID(),
+	}); err != nil {
+		t.Fatalf("insert announcement copy: %v", err)
+	}
+	if _, err := store.MarkNotificationRead(ctx, userID, copyID.Hex()); err != nil {
+		t.Fatalf("mark announcement read: %v", err)
+	}
+	announcement, err := store.FindNotificationByID(ctx, copyID)
+	if err != nil || !announcement.IsRead || announcement.PurgeAt != nil {
+		t.Fatalf("expected a read announcement copy to be kept, got %+v (%v)", announcement, err)
+	}
+
+	scheduled, err := store.ScheduleNotificationPurges(ctx)
+	if err != nil || scheduled != 1 {
+		t.Fatalf("expected one purge to be scheduled, got %d (%v)", scheduled, err)
+	}
+}
@@ -0,0 +1,131 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const passwordResetsCollection = "password_resets"
+
+func (s *Store) CreatePasswordResetToken(ctx context.Context, userIDText string, ttl time.Duration) (string, error) {
+	userID, err := primitive.ObjectIDFromHex(strings.TrimSpace(userIDText))
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid user id", ErrInvalidPasswordResetToken)
+	}
+	if ttl <= 0 {
+		ttl = time.Hour
+	}
+
+	token, err := generateSecretToken()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now().UTC()
+	if _, err := s.collection(passwordResetsCollection).UpdateMany(
+		ctx,
+		bson.M{"userId": userID, "usedAt": nil},
+		bson.M{"$set": bson.M{"usedAt": now, "revoked": true}},
+	); err != nil {
+		return "", err
+	}
+
+	_, err = s.collection(passwordResetsCollection).InsertOne(ctx, bson.M{
+		"userId":    userID,
+		"tokenHash": hashSecretToken(token),
+		"usedAt":    nil,
+		"createdAt": now,
+		"expiresAt": now.Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+func (s *Store) FindPasswordResetUser(ctx context.Context, token string) (*User, error) {
+	var reset bson.M
+	err := s.collection(passwordResetsCollection).FindOne(ctx, activePasswordResetFilter(token)).Decode(&reset)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrInvalidPasswordResetToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	userID, ok := reset["userId"].(primitive.ObjectID)
+	if !ok {
+		return nil, ErrInvalidPasswordResetToken
+	}
+
+	user, err := s.FindUserByID(ctx, userID.Hex())
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrInvalidPasswordResetToken
+	}
+	return user, nil
+}
+
+func (s *Store) ConsumePasswordResetToken(ctx context.Context, token string) (*User, error) {
+	var reset bson.M
+	err := s.collection(passwordResetsCollection).FindOneAndUpdate(
+		ctx,
+		activePasswordResetFilter(token),
+		bson.M{"$set": bson.M{"usedAt": time.Now().UTC()}},
+		options.FindOneAndUpdate().SetReturnDocument(options.Before),
+	).Decode(&reset)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrInvalidPasswordResetToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	userID, ok := reset["userId"].(primitive.ObjectID)
+	if !ok {
+		return nil, ErrInvalidPasswordResetToken
+	}
+
+	user, err := s.FindUserByID(ctx, userID.Hex())
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrInvalidPasswordResetToken
+	}
+	return user, nil
+}
+
+// ReleasePasswordResetToken makes a consumed token usable again, for when
+// the password could not be saved. Expired tokens stay unusable.
+func (s *Store) ReleasePasswordResetToken(ctx context.Context, token string) error {
+	_, err := s.collection(passwordResetsCollection).UpdateOne(
+		ctx,
+		bson.M{
+			"tokenHash": hashSecretToken(strings.TrimSpace(token)),
+			"usedAt":    bson.M{"$ne": nil},
+			"expiresAt": bson.M{"$gt": time.Now().UTC()},
+		},
+		bson.M{"$set": bson.M{"usedAt": nil}},
+	)
+	return err
+}
+
+func activePasswordResetFilter(token string) bson.M {
+	return bson.M{
+		"tokenHash": hashSecretToken(strings.TrimSpace(token)),
+		"usedAt":    nil,
+		"expiresAt": bson.M{"$gt": time.Now().UTC()},
+	}
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"easybook/internal/db"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestPasswordResetTokenRelease(t *testing.T) {
+	mongoURI := strings.TrimSpace(os.Getenv("MONGO_URI"))
+	if mongoURI == "" {
+		t.Skip("MONGO_URI is not set; skipping integration test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURI))
+	if err != nil {
+		t.Fatalf("connect mongo: %v", err)
+	}
+	defer func() {
+		_ = client.Disconnect(context.Background())
+	}()
+
+	database := client.Database("easybook_test_" + primitive.NewObjectID().Hex())
+	defer func() {
+		_ = database.Drop(context.Background())
+	}()
+	if err := db.EnsureStartupMaintenance(ctx, database); err != nil {
+		t.Fatalf("ensure indexes: %v", err)
+	}
+
+	store := NewStore(database)
+	userID := primitive.NewObjectID()
+	if _, err := database.Collection("users").InsertOne(ctx, bson.M{"_id": userID, "email": "guest@example.com"}); err != nil {
+		t.Fatalf("insert user: %v", err)
+	}
+
+	token, err := store.CreatePasswordResetToken(ctx, userID.Hex(), time.Hour)
+	if err != nil {
+		t.Fatalf("create token: %v", err)
+	}
+	if _, err := store.ConsumePasswordResetToken(ctx, token); err != nil {
+		t.Fatalf("consume: %v", err)
+	}
+	if _, err := store.ConsumePasswordResetToken(ctx, token); !errors.Is(err, ErrInvalidPasswordResetToken) {
+		t.Fatalf("expected a used token to be rejected, got %v", err)
+	}
+
+	if err := store.ReleasePasswordResetToken(ctx, token); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	user, err := store.ConsumePasswordResetToken(ctx, token)
+	if err != nil || user.ID != userID {
+		t.Fatalf("expected a released token to work again, got %+v (%v)", user, err)
+	}
+}
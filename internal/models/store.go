@@ -1,17 +1,53 @@
 package models
 
 import (
+	"context"
+	"time"
+
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type Store struct {
 	db *mongo.Database
+
+	notificationListener func(userID string)
+	notificationCreated  func(ctx context.Context, notification Notification)
+
+	waitlistHold          time.Duration
+	notificationRetention time.Duration
 }
 
 func NewStore(db *mongo.Database) *Store {
 	return &Store{db: db}
 }
 
+// SetNotificationListener registers a callback run after a user's
+// notifications are inserted or marked read. Pass nil when a change stream
+// already reports those writes.
+func (s *Store) SetNotificationListener(listener func(userID string)) {
+	s.notificationListener = listener
+}
+
+// SetNotificationCreatedHook registers a callback run after each
+// notification insert made through this store, e.g. to queue deliveries on
+// other channels.
+func (s *Store) SetNotificationCreatedHook(hook func(ctx context.Context, notification Notification)) {
+	s.notificationCreated = hook
+}
+
+// SetWaitlistHold makes ProcessWaitlistForRoom offer a freed room to one
+// subscriber at a time and hold the nights for them during d. Zero, the
+// default, offers the room without a hold.
+func (s *Store) SetWaitlistHold(d time.Duration) {
+	s.waitlistHold = d
+}
+
+// SetNotificationRetention makes read notifications expire d after they were
+// created. Zero, the default, keeps them until the user deletes them.
+func (s *Store) SetNotificationRetention(d time.Duration) {
+	s.notificationRetention = d
+}
+
 func (s *Store) collection(name string) *mongo.Collection {
 	return s.db.Collection(name)
 }
@@ -0,0 +1,131 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func (s *Store) SetPendingTOTPSecret(ctx context.Context, userIDText, secret string) error {
+	userID, err := primitive.ObjectIDFromHex(strings.TrimSpace(userIDText))
+	if err != nil {
+		return fmt.Errorf("%w: invalid user id", ErrInvalidTwoFactorCode)
+	}
+
+	_, err = s.collection("users").UpdateOne(ctx, bson.M{"_id": userID}, bson.M{"$set": bson.M{
+		"totpPendingSecret": secret,
+		"updatedAt":         time.Now().UTC(),
+	}})
+	return err
+}
+
+func (s *Store) EnableTOTP(ctx context.Context, userIDText, secret string, step int64, recoveryCodes []string) error {
+	userID, err := primitive.ObjectIDFromHex(strings.TrimSpace(userIDText))
+	if err != nil {
+		return fmt.Errorf("%w: invalid user id", ErrInvalidTwoFactorCode)
+	}
+
+	result, err := s.collection("users").UpdateOne(
+		ctx,
+		bson.M{"_id": userID, "totpPendingSecret": secret},
+		bson.M{
+			"$set": bson.M{
+				"totpEnabled":   true,
+				"totpSecret":    secret,
+				"totpLastStep":  step,
+				"recoveryCodes": hashRecoveryCodes(recoveryCodes),
+				"updatedAt":     time.Now().UTC(),
+			},
+			"$unset": bson.M{"totpPendingSecret": ""},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+func (s *Store) DisableTOTP(ctx context.Context, userIDText string) error {
+	userID, err := primitive.ObjectIDFromHex(strings.TrimSpace(userIDText))
+	if err != nil {
+		return fmt.Errorf("%w: invalid user id", ErrInvalidTwoFactorCode)
+	}
+
+	_, err = s.collection("users").UpdateOne(ctx, bson.M{"_id": userID}, bson.M{
+		"$set":   bson.M{"totpEnabled": false, "updatedAt": time.Now().UTC()},
+		"$unset": bson.M{"totpSecret": "", "totpPendingSecret": "", "totpLastStep": "", "recoveryCodes": ""},
+	})
+	return err
+}
+
+// ConsumeTOTPStep records step as used and reports false when the same or a later
+// step was already accepted, which prevents a code from being replayed.
+func (s *Store) ConsumeTOTPStep(ctx context.Context, userIDText string, step int64) (bool, error) {
+	userID, err := primitive.ObjectIDFromHex(strings.TrimSpace(userIDText))
+	if err != nil {
+		return false, fmt.Errorf("%w: invalid user id", ErrInvalidTwoFactorCode)
+	}
+
+	result, err := s.collection("users").UpdateOne(
+		ctx,
+		bson.M{
+			"_id":         userID,
+			"totpEnabled": true,
+			"$or": bson.A{
+				bson.M{"totpLastStep": bson.M{"$lt": step}},
+				bson.M{"totpLastStep": bson.M{"$exists": false}},
+			},
+		},
+		bson.M{"$set": bson.M{"totpLastStep": step}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+func (s *Store) ReplaceRecoveryCodes(ctx context.Context, userIDText string, recoveryCodes []string) error {
+	userID, err := primitive.ObjectIDFromHex(strings.TrimSpace(userIDText))
+	if err != nil {
+		return fmt.Errorf("%w: invalid user id", ErrInvalidTwoFactorCode)
+	}
+
+	_, err = s.collection("users").UpdateOne(ctx, bson.M{"_id": userID, "totpEnabled": true}, bson.M{"$set": bson.M{
+		"recoveryCodes": hashRecoveryCodes(recoveryCodes),
+		"updatedAt":     time.Now().UTC(),
+	}})
+	return err
+}
+
+func (s *Store) ConsumeRecoveryCode(ctx context.Context, userIDText, code string) (bool, error) {
+	userID, err := primitive.ObjectIDFromHex(strings.TrimSpace(userIDText))
+	if err != nil {
+		return false, fmt.Errorf("%w: invalid user id", ErrInvalidTwoFactorCode)
+	}
+
+	codeHash := hashSecretToken(code)
+	result, err := s.collection("users").UpdateOne(
+		ctx,
+		bson.M{"_id": userID, "totpEnabled": true, "recoveryCodes": codeHash},
+		bson.M{"$pull": bson.M{"recoveryCodes": codeHash}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+func hashRecoveryCodes(codes []string) []string {
+	hashes := make([]string, 0, len(codes))
+	for _, code := range codes {
+		hashes = append(hashes, hashSecretToken(code))
+	}
+	return hashes
+}
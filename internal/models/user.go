@@ -9,16 +9,26 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type User struct {
-	ID           primitive.ObjectID `bson:"_id,omitempty" json:"_id"`
-	Email        string             `bson:"email" json:"email"`
-	PasswordHash string             `bson:"passwordHash" json:"passwordHash,omitempty"`
-	Role         string             `bson:"role" json:"role"`
-	CreatedAt    time.Time          `bson:"createdAt" json:"createdAt"`
-	UpdatedAt    time.Time          `bson:"updatedAt" json:"updatedAt"`
+	ID                primitive.ObjectID `bson:"_id,omitempty" json:"_id"`
+	Email             string             `bson:"email" json:"email"`
+	PasswordHash      string             `bson:"passwordHash" json:"passwordHash,omitempty"`
+	Role              string             `bson:"role" json:"role"`
+	Name              string             `bson:"name,omitempty" json:"name,omitempty"`
+	Phone             string             `bson:"phone,omitempty" json:"phone,omitempty"`
+	PreferredLanguage string             `bson:"preferredLanguage,omitempty" json:"preferredLanguage,omitempty"`
+	EmailVerified     bool               `bson:"emailVerified" json:"emailVerified"`
+	EmailVerifiedAt   *time.Time         `bson:"emailVerifiedAt,omitempty" json:"emailVerifiedAt,omitempty"`
+	TOTPEnabled       bool               `bson:"totpEnabled" json:"totpEnabled"`
+	TOTPSecret        string             `bson:"totpSecret,omitempty" json:"-"`
+	TOTPPendingSecret string             `bson:"totpPendingSecret,omitempty" json:"-"`
+	RecoveryCodes     []string           `bson:"recoveryCodes,omitempty" json:"-"`
+	CreatedAt         time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt         time.Time          `bson:"updatedAt" json:"updatedAt"`
 }
 
 func normalizeEmail(email string) string {
@@ -43,6 +53,22 @@ func (s *Store) FindUserByEmail(ctx context.Context, email string) (*User, error
 	return &user, nil
 }
 
+func (s *Store) ListUsersByRole(ctx context.Context, role string) ([]User, error) {
+	cursor, err := s.collection("users").Find(
+		ctx,
+		bson.M{"role": role},
+		options.Find().SetSort(bson.D{{Key: "email", Value: 1}}).SetProjection(bson.M{"email": 1, "name": 1, "role": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	users := []User{}
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
 func (s *Store) CreateUser(ctx context.Context, email, password, role string) (string, error) {
 	cleanEmail := normalizeEmail(email)
 	if cleanEmail == "" {
@@ -59,11 +85,12 @@ func (s *Store) CreateUser(ctx context.Context, email, password, role string) (s
 
 	now := time.Now().UTC()
 	result, err := s.collection("users").InsertOne(ctx, bson.M{
-		"email":        cleanEmail,
-		"passwordHash": string(passwordHash),
-		"role":         role,
-		"createdAt":    now,
-		"updatedAt":    now,
+		"email":         cleanEmail,
+		"passwordHash":  string(passwordHash),
+		"role":          role,
+		"emailVerified": false,
+		"createdAt":     now,
+		"updatedAt":     now,
 	})
 	if err != nil {
 		return "", err
@@ -76,3 +103,48 @@ func (s *Store) CreateUser(ctx context.Context, email, password, role string) (s
 
 	return insertedID.Hex(), nil
 }
+
+func (s *Store) FindUserByID(ctx context.Context, userIDText string) (*User, error) {
+	userID, err := primitive.ObjectIDFromHex(strings.TrimSpace(userIDText))
+	if err != nil {
+		return nil, nil
+	}
+
+	var user User
+	err = s.collection("users").FindOne(ctx, bson.M{"_id": userID}).Decode(&user)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (s *Store) UpdateUserPassword(ctx context.Context, userIDText, password string) error {
+	userID, err := primitive.ObjectIDFromHex(strings.TrimSpace(userIDText))
+	if err != nil {
+		return errors.New("invalid user id")
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), 12)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	result, err := s.collection("users").UpdateOne(ctx, bson.M{"_id": userID}, bson.M{"$set": bson.M{
+		"passwordHash":      string(passwordHash),
+		"passwordChangedAt": now,
+		"updatedAt":         now,
+	}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
@@ -0,0 +1,359 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const maxListedWaitlistEntries = 100
+
+// Waitlist entry states shown to users. Only active entries are matched
+// against freed rooms.
+const (
+	WaitlistStatusActive   = "active"
+	WaitlistStatusNotified = "notified"
+	WaitlistStatusHeld     = "held"
+	WaitlistStatusExpired  = "expired"
+)
+
+type WaitlistEntry struct {
+	ID          primitive.ObjectID `bson:"_id" json:"id"`
+	UserID      primitive.ObjectID `bson:"userId" json:"-"`
+	RoomID      primitive.ObjectID `bson:"roomId" json:"roomId"`
+	GroupID     primitive.ObjectID `bson:"groupId,omitempty" json:"groupId,omitempty"`
+	CheckIn     string             `bson:"checkIn" json:"checkIn"`
+	CheckOut    string             `bson:"checkOut" json:"checkOut"`
+	Type        string             `bson:"type" json:"type"`
+	Nights      int                `bson:"nights,omitempty" json:"nights,omitempty"`
+	MinNights   int                `bson:"minNights,omitempty" json:"minNights,omitempty"`
+	IsActive    bool               `bson:"isActive" json:"isActive"`
+	NotifiedAt  *time.Time         `bson:"notifiedAt,omitempty" json:"notifiedAt,omitempty"`
+	HoldUntil   *time.Time         `bson:"holdUntil,omitempty" json:"holdUntil,omitempty"`
+	HoldEndedAt *time.Time         `bson:"holdEndedAt,omitempty" json:"holdEndedAt,omitempty"`
+	ExpiredAt   *time.Time         `bson:"expiredAt,omitempty" json:"expiredAt,omitempty"`
+	CreatedAt   time.Time          `bson:"createdAt" json:"createdAt"`
+
+	// Filled in by ListWaitlistEntries.
+	Status     string `bson:"-" json:"status"`
+	Position   int64  `bson:"-" json:"position,omitempty"`
+	HotelTitle string `bson:"-" json:"hotelTitle"`
+}
+
+// ListWaitlistEntries returns the user's subscriptions, active ones first.
+// Active entries carry their queue position: 1 plus the number of older
+// active entries of the same type for the same room and overlapping dates,
+// which is the order ProcessWaitlistForRoom offers a freed room in.
+func (s *Store) ListWaitlistEntries(ctx context.Context, userIDText string, activeOnly bool) ([]WaitlistEntry, error) {
+	userID, err := primitive.ObjectIDFromHex(strings.TrimSpace(userIDText))
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid user id", ErrInvalidWaitlistPayload)
+	}
+
+	filter := bson.M{"userId": userID}
+	if activeOnly {
+		filter["isActive"] = true
+	}
+	cursor, err := s.collection(waitlistCollection).Find(
+		ctx,
+		filter,
+		options.Find().
+			SetSort(bson.D{{Key: "isActive", Value: -1}, {Key: "checkIn", Value: 1}, {Key: "createdAt", Value: 1}}).
+			SetLimit(maxListedWaitlistEntries),
+	)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]WaitlistEntry, 0)
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+
+	roomIDs := make([]primitive.ObjectID, 0, len(entries))
+	for i := range entries {
+		entry := &entries[i]
+		roomIDs = append(roomIDs, entry.RoomID)
+		switch {
+		case entry.IsActive:
+			entry.Status = WaitlistStatusActive
+			ahead, err := s.collection(waitlistCollection).CountDocuments(ctx, bson.M{
+				"roomId":    entry.RoomID,
+				"type":      entry.Type,
+				"isActive":  true,
+				"checkIn":   bson.M{"$lt": entry.CheckOut},
+				"checkOut":  bson.M{"$gt": entry.CheckIn},
+				"createdAt": bson.M{"$lt": entry.CreatedAt},
+			})
+			if err != nil {
+				return nil, err
+			}
+			entry.Position = ahead + 1
+		case entry.HoldUntil != nil && entry.HoldEndedAt == nil && entry.HoldUntil.After(time.Now()):
+			entry.Status = WaitlistStatusHeld
+		case entry.ExpiredAt != nil:
+			entry.Status = WaitlistStatusExpired
+		default:
+			entry.Status = WaitlistStatusNotified
+		}
+	}
+
+	titles, err := s.hotelTitles(ctx, roomIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		entries[i].HotelTitle = titles[entries[i].RoomID]
+	}
+	return entries, nil
+}
+
+// CancelWaitlistEntry removes one of the user's subscriptions and gives up
+// its hold, if any. It returns nil when the user has no subscription with
+// that id.
+func (s *Store) CancelWaitlistEntry(ctx context.Context, userIDText, idText string) (*WaitlistEntry, error) {
+	userID, err := primitive.ObjectIDFromHex(strings.TrimSpace(userIDText))
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid user id", ErrInvalidWaitlistPayload)
+	}
+	id, err := primitive.ObjectIDFromHex(strings.TrimSpace(idText))
+	if err != nil {
+		return nil, nil
+	}
+
+	var entry WaitlistEntry
+	err = s.collection(waitlistCollection).FindOneAndDelete(ctx, bson.M{"_id": id, "userId": userID}).Decode(&entry)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if entry.HoldUntil != nil {
+		if _, err := s.collection(roomCalendarCollection).DeleteMany(ctx, bson.M{"waitlistId": id}); err != nil {
+			return nil, err
+		}
+	}
+	return &entry, nil
+}
+
+// ExpireWaitlistEntries deactivates subscriptions that no stay can match
+// anymore: exact ones once their check-in date has passed, flexible ones once
+// the last possible check-in has.
+func (s *Store) ExpireWaitlistEntries(ctx context.Context) (int64, error) {
+	now := time.Now()
+	result, err := s.collection(waitlistCollection).UpdateMany(
+		ctx,
+		bson.M{"isActive": true, "$nor": bson.A{stillOpenWaitlistFilter(toLocalDate(now).Format("2006-01-02"))}},
+		bson.M{"$set": bson.M{"isActive": false, "expiredAt": now.UTC(), "updatedAt": now.UTC()}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+func (s *Store) hotelTitles(ctx context.Context, ids []primitive.ObjectID) (map[primitive.ObjectID]string, error) {
+	titles := map[primitive.ObjectID]string{}
+	if len(ids) == 0 {
+		return titles, nil
+	}
+
+	cursor, err := s.collection("hotels").Find(
+		ctx,
+		bson.M{"_id": bson.M{"$in": ids}},
+		options.Find().SetProjection(bson.M{"title": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	var hotels []struct {
+		ID    primitive.ObjectID `bson:"_id"`
+		Title string             `bson:"title"`
+	}
+	if err := cursor.All(ctx, &hotels); err != nil {
+		return nil, err
+	}
+	for _, hotel := range hotels {
+		titles[hotel.ID] = hotel.Title
+	}
+	return titles, nil
+}
+
+// WaitlistFlexibility widens a subscription from one exact stay to any stay
+// inside the subscribed window. Nights is the wanted length (0 means the
+// whole window); MinNights, when set, also accepts shorter stays down to
+// that length.
+type WaitlistFlexibility struct {
+	Nights    int
+	MinNights int
+}
+
+// stayRange is a concrete check-in/check-out pair offered to a subscriber.
+type stayRange struct {
+	CheckIn  string
+	CheckOut string
+}
+
+func (r stayRange) nights() int {
+	checkIn, checkOut, err := parseBookingDateRange(r.CheckIn, r.CheckOut)
+	if err != nil {
+		return 0
+	}
+	return int(checkOut.Sub(checkIn).Hours()/24 + 0.5)
+}
+
+// normalize validates f against a window of windowNights nights and returns
+// the wanted and the shortest acceptable length.
+func (f WaitlistFlexibility) normalize(windowNights int) (int, int, error) {
+	nights := f.Nights
+	if nights == 0 {
+		nights = windowNights
+	}
+	if nights < 1 || nights > windowNights {
+		return 0, 0, fmt.Errorf("nights must be between 1 and %d", windowNights)
+	}
+	minNights := f.MinNights
+	if minNights == 0 {
+		minNights = nights
+	}
+	if minNights < 1 || minNights > nights {
+		return 0, 0, fmt.Errorf("minimum nights must be between 1 and %d", nights)
+	}
+	return nights, minNights, nil
+}
+
+// freeStayCandidates lists the stays inside window (one date per night) that
+// satisfy the wanted length, best first: the earliest stay of nights nights,
+// then the longest free stretches of at least minNights nights.
+func freeStayCandidates(window []string, occupied map[string]bool, nights, minNights int) []stayRange {
+	type run struct{ start, length int }
+	runs := make([]run, 0)
+	for i := 0; i < len(window); {
+		if occupied[window[i]] {
+			i++
+			continue
+		}
+		start := i
+		for i < len(window) && !occupied[window[i]] {
+			i++
+		}
+		runs = append(runs, run{start: start, length: i - start})
+	}
+
+	stay := func(start, length int) stayRange {
+		last, _ := time.ParseInLocation("2006-01-02", window[start+length-1], time.Local)
+		return stayRange{CheckIn: window[start], CheckOut: last.AddDate(0, 0, 1).Format("2006-01-02")}
+	}
+
+	candidates := make([]stayRange, 0)
+	for _, r := range runs {
+		if r.length >= nights {
+			candidates = append(candidates, stay(r.start, nights))
+		}
+	}
+	if minNights < nights {
+		shorter := make([]run, 0)
+		for _, r := range runs {
+			if r.length >= minNights && r.length < nights {
+				shorter = append(shorter, r)
+			}
+		}
+		sort.SliceStable(shorter, func(i, j int) bool { return shorter[i].length > shorter[j].length })
+		for _, r := range shorter {
+			candidates = append(candidates, stay(r.start, r.length))
+		}
+	}
+	return candidates
+}
+
+// findWaitlistStay returns the stay to offer for subscription, or false when
+// nothing that fits is free. Exact subscriptions need their whole range;
+// flexible ones are matched against the free nights left in their window.
+func (s *Store) findWaitlistStay(ctx context.Context, roomID primitive.ObjectID, subscription bson.M) (stayRange, bool, error) {
+	checkIn := strings.TrimSpace(fmt.Sprint(subscription["checkIn"]))
+	checkOut := strings.TrimSpace(fmt.Sprint(subscription["checkOut"]))
+	window, err := buildDateSlots(checkIn, checkOut)
+	if err != nil {
+		return stayRange{}, false, nil
+	}
+
+	var flexibility WaitlistFlexibility
+	flexibility.Nights, _ = toInt(subscription["nights"])
+	flexibility.MinNights, _ = toInt(subscription["minNights"])
+	if flexibility == (WaitlistFlexibility{}) {
+		conflict, err := s.hasBookingConflict(ctx, roomID, checkIn, checkOut, nil, nil)
+		if err != nil || conflict {
+			return stayRange{}, false, err
+		}
+		return stayRange{CheckIn: checkIn, CheckOut: checkOut}, true, nil
+	}
+	nights, minNights, err := flexibility.normalize(len(window))
+	if err != nil {
+		return stayRange{}, false, nil
+	}
+
+	today := toLocalDate(time.Now()).Format("2006-01-02")
+	for len(window) > 0 && window[0] < today {
+		window = window[1:]
+	}
+	if len(window) < minNights {
+		return stayRange{}, false, nil
+	}
+
+	cursor, err := s.collection(roomCalendarCollection).Find(
+		ctx,
+		bson.M{"roomId": roomID, "day": bson.M{"$in": window}},
+		options.Find().SetProjection(bson.M{"day": 1}),
+	)
+	if err != nil {
+		return stayRange{}, false, err
+	}
+	var taken []struct {
+		Day string `bson:"day"`
+	}
+	if err := cursor.All(ctx, &taken); err != nil {
+		return stayRange{}, false, err
+	}
+	occupied := make(map[string]bool, len(taken))
+	for _, day := range taken {
+		occupied[day.Day] = true
+	}
+
+	for _, candidate := range freeStayCandidates(window, occupied, nights, minNights) {
+		// Bookings made before the room calendar existed only show up here.
+		conflict, err := s.hasBookingConflict(ctx, roomID, candidate.CheckIn, candidate.CheckOut, nil, nil)
+		if err != nil {
+			return stayRange{}, false, err
+		}
+		if !conflict {
+			return candidate, true, nil
+		}
+	}
+	return stayRange{}, false, nil
+}
+
+// waitlistLastCheckIn is the last day a stay matching the subscription could
+// start; after it the subscription expires.
+func waitlistLastCheckIn(checkIn, checkOut time.Time, minNights int) string {
+	if minNights <= 0 {
+		return checkIn.Format("2006-01-02")
+	}
+	return checkOut.AddDate(0, 0, -minNights).Format("2006-01-02")
+}
+
+// stillOpenWaitlistFilter matches subscriptions that can still be served on
+// or after today. Entries from before flexible matching have no lastCheckIn.
+func stillOpenWaitlistFilter(today string) bson.M {
+	return bson.M{"$or": bson.A{
+		bson.M{"lastCheckIn": bson.M{"$gte": today}},
+		bson.M{"lastCheckIn": bson.M{"$exists": false}, "checkIn": bson.M{"$gte": today}},
+	}}
+}
@@ -0,0 +1,177 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// A waitlist hold is a set of room_calendar nights without a bookingId. The
+// unique (roomId, day) index keeps anyone else from booking them until
+// holdUntil; the holder's own booking takes them over.
+
+// holdWaitlistStay holds stay for the subscriber of waitlistID. It returns
+// false when a booking or another hold took one of the nights first.
+func (s *Store) holdWaitlistStay(
+	ctx context.Context,
+	roomID, waitlistID, userID primitive.ObjectID,
+	stay stayRange,
+	until time.Time,
+) (bool, error) {
+	days, err := buildDateSlots(stay.CheckIn, stay.CheckOut)
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Now().UTC()
+	documents := make([]any, 0, len(days))
+	for _, day := range days {
+		documents = append(documents, bson.M{
+			"roomId":     roomID,
+			"day":        day,
+			"holdFor":    userID,
+			"waitlistId": waitlistID,
+			"holdUntil":  until.UTC(),
+			"createdAt":  now,
+		})
+	}
+
+	if _, err := s.collection(roomCalendarCollection).InsertMany(ctx, documents); err != nil {
+		// Nights inserted before the conflict must not stay held.
+		_, cleanupErr := s.collection(roomCalendarCollection).DeleteMany(ctx, bson.M{"waitlistId": waitlistID})
+		if IsDuplicateKeyError(err, "roomId") && cleanupErr == nil {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// releaseWaitlistHolds removes the held nights matching filter and marks
+// their subscriptions' holds as ended. It returns the affected rooms.
+func (s *Store) releaseWaitlistHolds(ctx context.Context, filter bson.M) ([]primitive.ObjectID, error) {
+	filter["holdFor"] = bson.M{"$exists": true}
+	cursor, err := s.collection(roomCalendarCollection).Find(
+		ctx,
+		filter,
+		options.Find().SetProjection(bson.M{"roomId": 1, "waitlistId": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	var held []struct {
+		ID         primitive.ObjectID `bson:"_id"`
+		RoomID     primitive.ObjectID `bson:"roomId"`
+		WaitlistID primitive.ObjectID `bson:"waitlistId"`
+	}
+	if err := cursor.All(ctx, &held); err != nil {
+		return nil, err
+	}
+	if len(held) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(held))
+	waitlistIDs := make([]primitive.ObjectID, 0)
+	rooms := make([]primitive.ObjectID, 0)
+	seen := map[primitive.ObjectID]bool{}
+	for _, night := range held {
+		ids = append(ids, night.ID)
+		if !seen[night.WaitlistID] {
+			seen[night.WaitlistID] = true
+			waitlistIDs = append(waitlistIDs, night.WaitlistID)
+		}
+		if !seen[night.RoomID] {
+			seen[night.RoomID] = true
+			rooms = append(rooms, night.RoomID)
+		}
+	}
+
+	if _, err := s.collection(roomCalendarCollection).DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}}); err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	_, err = s.collection(waitlistCollection).UpdateMany(
+		ctx,
+		bson.M{"_id": bson.M{"$in": waitlistIDs}, "holdEndedAt": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"holdEndedAt": now, "updatedAt": now}},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return rooms, nil
+}
+
+// takeOverWaitlistHolds clears the held nights in checkIn–checkOut that do
+// not stand in the way of a booking by userID: the user's own holds and
+// expired ones.
+func (s *Store) takeOverWaitlistHolds(ctx context.Context, roomID, userID primitive.ObjectID, checkIn, checkOut string) error {
+	days, err := buildDateSlots(checkIn, checkOut)
+	if err != nil {
+		return err
+	}
+	_, err = s.releaseWaitlistHolds(ctx, bson.M{
+		"roomId": roomID,
+		"day":    bson.M{"$in": days},
+		"$or": bson.A{
+			bson.M{"holdFor": userID},
+			bson.M{"holdUntil": bson.M{"$lte": time.Now().UTC()}},
+		},
+	})
+	return err
+}
+
+// ReleaseExpiredWaitlistHolds ends the holds whose time is up, for one room
+// or, with an empty roomIDText, for all rooms. It returns the rooms whose
+// nights were freed so they can be offered to the next subscriber.
+func (s *Store) ReleaseExpiredWaitlistHolds(ctx context.Context, roomIDText string) ([]string, error) {
+	filter := bson.M{"holdUntil": bson.M{"$lte": time.Now().UTC()}}
+	if roomIDText = strings.TrimSpace(roomIDText); roomIDText != "" {
+		roomID, err := primitive.ObjectIDFromHex(roomIDText)
+		if err != nil {
+			return nil, nil
+		}
+		filter["roomId"] = roomID
+	}
+
+	rooms, err := s.releaseWaitlistHolds(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	roomIDs := make([]string, 0, len(rooms))
+	for _, room := range rooms {
+		roomIDs = append(roomIDs, room.Hex())
+	}
+	return roomIDs, nil
+}
+
+// NextWaitlistHoldExpiry returns when the first running hold on the room
+// ends, or false when nothing is held.
+func (s *Store) NextWaitlistHoldExpiry(ctx context.Context, roomIDText string) (time.Time, bool, error) {
+	roomID, err := primitive.ObjectIDFromHex(strings.TrimSpace(roomIDText))
+	if err != nil {
+		return time.Time{}, false, nil
+	}
+
+	var night struct {
+		HoldUntil time.Time `bson:"holdUntil"`
+	}
+	err = s.collection(roomCalendarCollection).FindOne(
+		ctx,
+		bson.M{"roomId": roomID, "holdUntil": bson.M{"$gt": time.Now().UTC()}},
+		options.FindOne().SetSort(bson.D{{Key: "holdUntil", Value: 1}}).SetProjection(bson.M{"holdUntil": 1}),
+	).Decode(&night)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return night.HoldUntil, true, nil
+}
@@ -0,0 +1,273 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"easybook/internal/db"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestWaitlistPositionsCancelAndExpiry(t *testing.T) {
+	mongoURI := strings.TrimSpace(os.Getenv("MONGO_URI"))
+	if mongoURI == "" {
+		t.Skip("MONGO_URI is not set; skipping integration test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURI))
+	if err != nil {
+		t.Fatalf("connect mongo: %v", err)
+	}
+	defer func() {
+		_ = client.Disconnect(context.Background())
+	}()
+
+	database := client.Database("easybook_test_" + primitive.NewObjectID().Hex())
+	defer func() {
+		_ = database.Drop(context.Background())
+	}()
+	if err := db.EnsureStartupMaintenance(ctx, database); err != nil {
+		t.Fatalf("ensure indexes: %v", err)
+	}
+
+	store := NewStore(database)
+	roomID := primitive.NewObjectID()
+	if _, err := database.Collection("hotels").InsertOne(ctx, bson.M{"_id": roomID, "title": "Harbour View"}); err != nil {
+		t.Fatalf("insert hotel: %v", err)
+	}
+
+	today := toLocalDate(time.Now())
+	checkIn := today.AddDate(0, 0, 20).Format("2006-01-02")
+	checkOut := today.AddDate(0, 0, 23).Format("2006-01-02")
+	first := primitive.NewObjectID().Hex()
+	second := primitive.NewObjectID().Hex()
+	firstEntryID, _, err := store.SubscribeToWaitlist(ctx, first, roomID.Hex(), checkIn, checkOut, WaitlistMain, WaitlistFlexibility{})
+	if err != nil {
+		t.Fatalf("subscribe first: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if _, _, err := store.SubscribeToWaitlist(ctx, second, roomID.Hex(), checkIn, checkOut, WaitlistMain, WaitlistFlexibility{}); err != nil {
+		t.Fatalf("subscribe second: %v", err)
+	}
+
+	entries, err := store.ListWaitlistEntries(ctx, second, false)
+	if err != nil {
+		t.Fatalf("list entries: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Position != 2 || entries[0].Status != WaitlistStatusActive || entries[0].HotelTitle != "Harbour View" {
+		t.Fatalf("expected the second subscriber at position 2, got %+v", entries)
+	}
+
+	if cancelled, err := store.CancelWaitlistEntry(ctx, second, firstEntryID); err != nil || cancelled != nil {
+		t.Fatalf("expected other users' entries to be untouchable, got %+v %v", cancelled, err)
+	}
+	if cancelled, err := store.CancelWaitlistEntry(ctx, first, firstEntryID); err != nil || cancelled == nil {
+		t.Fatalf("cancel entry: %+v %v", cancelled, err)
+	}
+	entries, _ = store.ListWaitlistEntries(ctx, second, false)
+	if len(entries) != 1 || entries[0].Position != 1 {
+		t.Fatalf("expected the second subscriber to move up, got %+v", entries)
+	}
+
+	userID, _ := primitive.ObjectIDFromHex(second)
+	if _, err := database.Collection(waitlistCollection).InsertOne(ctx, bson.M{
+		"userId":    userID,
+		"roomId":    roomID,
+		"checkIn":   today.AddDate(0, 0, -1).Format("2006-01-02"),
+		"checkOut":  today.AddDate(0, 0, 1).Format("2006-01-02"),
+		"type":      WaitlistMain,
+		"isActive":  true,
+		"createdAt": time.Now().UTC(),
+	}); err != nil {
+		t.Fatalf("insert past entry: %v", err)
+	}
+	expired, err := store.ExpireWaitlistEntries(ctx)
+	if err != nil || expired != 1 {
+		t.Fatalf("expected one expired entry, got %d (%v)", expired, err)
+	}
+	entries, _ = store.ListWaitlistEntries(ctx, second, false)
+	if len(entries) != 2 || entries[1].Status != WaitlistStatusExpired {
+		t.Fatalf("expected the past entry to be listed as expired, got %+v", entries)
+	}
+	active, _ := store.ListWaitlistEntries(ctx, second, true)
+	if len(active) != 1 {
+		t.Fatalf("expected one active entry, got %+v", active)
+	}
+}
+
+func TestFlexibleWaitlistOffersFreeNights(t *testing.T) {
+	mongoURI := strings.TrimSpace(os.Getenv("MONGO_URI"))
+	if mongoURI == "" {
+		t.Skip("MONGO_URI is not set; skipping integration test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURI))
+	if err != nil {
+		t.Fatalf("connect mongo: %v", err)
+	}
+	defer func() {
+		_ = client.Disconnect(context.Background())
+	}()
+
+	database := client.Database("easybook_test_" + primitive.NewObjectID().Hex())
+	defer func() {
+		_ = database.Drop(context.Background())
+	}()
+	if err := db.EnsureStartupMaintenance(ctx, database); err != nil {
+		t.Fatalf("ensure indexes: %v", err)
+	}
+
+	store := NewStore(database)
+	roomID := primitive.NewObjectID()
+	today := toLocalDate(time.Now())
+	day := func(offset int) string { return today.AddDate(0, 0, offset).Format("2006-01-02") }
+
+	// Nights 10 to 14 form the window; only 10, 13 and 14 are free.
+	for _, taken := range []int{11, 12} {
+		if _, err := database.Collection(roomCalendarCollection).InsertOne(ctx, bson.M{
+			"roomId":    roomID,
+			"day":       day(taken),
+			"bookingId": primitive.NewObjectID(),
+		}); err != nil {
+			t.Fatalf("insert calendar night: %v", err)
+		}
+	}
+
+	user := primitive.NewObjectID().Hex()
+	if _, _, err := store.SubscribeToWaitlist(ctx, user, roomID.Hex(), day(10), day(15), WaitlistMain, WaitlistFlexibility{Nights: 3}); err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	if _, _, err := store.SubscribeToWaitlist(ctx, user, roomID.Hex(), day(10), day(15), WaitlistMain, WaitlistFlexibility{Nights: 6}); !errors.Is(err, ErrInvalidWaitlistPayload) {
+		t.Fatalf("expected a stay longer than the window to be rejected, got %v", err)
+	}
+
+	created, err := store.ProcessWaitlistForRoom(ctx, roomID.Hex())
+	if err != nil || created != 0 {
+		t.Fatalf("expected no offer without three free nights, got %d (%v)", created, err)
+	}
+
+	other := primitive.NewObjectID().Hex()
+	if _, _, err := store.SubscribeToWaitlist(ctx, other, roomID.Hex(), day(10), day(15), WaitlistMain, WaitlistFlexibility{Nights: 3, MinNights: 2}); err != nil {
+		t.Fatalf("subscribe flexible: %v", err)
+	}
+	created, err = store.ProcessWaitlistForRoom(ctx, roomID.Hex())
+	if err != nil || created != 1 {
+		t.Fatalf("expected the shorter stay to be offered, got %d (%v)", created, err)
+	}
+	notifications, _, err := store.ListNotifications(ctx, other, NotificationQuery{}, 0, 10)
+	if err != nil || len(notifications) != 1 {
+		t.Fatalf("expected one notification, got %+v (%v)", notifications, err)
+	}
+	wantLink := "/bookings/new?hotelId=" + roomID.Hex() + "&checkIn=" + day(13) + "&checkOut=" + day(15)
+	if notifications[0]["link"] != wantLink {
+		t.Fatalf("expected the offer for the free nights, got %v", notifications[0]["link"])
+	}
+}
+
+func TestWaitlistHoldPassesToNextSubscriber(t *testing.T) {
+	mongoURI := strings.TrimSpace(os.Getenv("MONGO_URI"))
+	if mongoURI == "" {
+		t.Skip("MONGO_URI is not set; skipping integration test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURI))
+	if err != nil {
+		t.Fatalf("connect mongo: %v", err)
+	}
+	defer func() {
+		_ = client.Disconnect(context.Background())
+	}()
+
+	database := client.Database("easybook_test_" + primitive.NewObjectID().Hex())
+	defer func() {
+		_ = database.Drop(context.Background())
+	}()
+	if err := db.EnsureStartupMaintenance(ctx, database); err != nil {
+		t.Fatalf("ensure indexes: %v", err)
+	}
+
+	store := NewStore(database)
+	store.SetWaitlistHold(time.Hour)
+	roomID := primitive.NewObjectID()
+	today := toLocalDate(time.Now())
+	checkIn := today.AddDate(0, 0, 10).Format("2006-01-02")
+	checkOut := today.AddDate(0, 0, 12).Format("2006-01-02")
+
+	first := primitive.NewObjectID().Hex()
+	second := primitive.NewObjectID().Hex()
+	firstEntryID, _, err := store.SubscribeToWaitlist(ctx, first, roomID.Hex(), checkIn, checkOut, WaitlistMain, WaitlistFlexibility{})
+	if err != nil {
+		t.Fatalf("subscribe first: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if _, _, err := store.SubscribeToWaitlist(ctx, second, roomID.Hex(), checkIn, checkOut, WaitlistMain, WaitlistFlexibility{}); err != nil {
+		t.Fatalf("subscribe second: %v", err)
+	}
+
+	created, err := store.ProcessWaitlistForRoom(ctx, roomID.Hex())
+	if err != nil || created != 1 {
+		t.Fatalf("expected only the first subscriber to be offered the room, got %d (%v)", created, err)
+	}
+	if available, _ := store.CheckRoomAvailability(ctx, roomID.Hex(), checkIn, checkOut, "", first); !available {
+		t.Fatal("expected the held nights to be free for the holder")
+	}
+	if available, _ := store.CheckRoomAvailability(ctx, roomID.Hex(), checkIn, checkOut, "", second); available {
+		t.Fatal("expected the held nights to be taken for everyone else")
+	}
+	if _, err := store.CreateBooking(ctx, bson.M{"roomId": roomID, "checkIn": checkIn, "checkOut": checkOut}, second); !errors.Is(err, ErrBookingConflict) {
+		t.Fatalf("expected a booking over the hold to conflict, got %v", err)
+	}
+	if _, held, _ := store.NextWaitlistHoldExpiry(ctx, roomID.Hex()); !held {
+		t.Fatal("expected a running hold")
+	}
+
+	// Let the hold run out.
+	if _, err := database.Collection(roomCalendarCollection).UpdateMany(
+		ctx,
+		bson.M{"waitlistId": bson.M{"$exists": true}},
+		bson.M{"$set": bson.M{"holdUntil": time.Now().Add(-time.Minute).UTC()}},
+	); err != nil {
+		t.Fatalf("expire hold: %v", err)
+	}
+	rooms, err := store.ReleaseExpiredWaitlistHolds(ctx, "")
+	if err != nil || len(rooms) != 1 || rooms[0] != roomID.Hex() {
+		t.Fatalf("expected the room to be released, got %v (%v)", rooms, err)
+	}
+	created, err = store.ProcessWaitlistForRoom(ctx, roomID.Hex())
+	if err != nil || created != 1 {
+		t.Fatalf("expected the hold to pass on, got %d (%v)", created, err)
+	}
+	entries, _ := store.ListWaitlistEntries(ctx, second, false)
+	if len(entries) != 1 || entries[0].Status != WaitlistStatusHeld {
+		t.Fatalf("expected the second subscriber to hold the room, got %+v", entries)
+	}
+	entries, _ = store.ListWaitlistEntries(ctx, first, false)
+	if len(entries) != 1 || entries[0].ID.Hex() != firstEntryID || entries[0].Status != WaitlistStatusNotified {
+		t.Fatalf("expected the first hold to have ended, got %+v", entries)
+	}
+
+	if _, err := store.CreateBooking(ctx, bson.M{"roomId": roomID, "checkIn": checkIn, "checkOut": checkOut}, second); err != nil {
+		t.Fatalf("expected the holder to book, got %v", err)
+	}
+	held, err := database.Collection(roomCalendarCollection).CountDocuments(ctx, bson.M{"holdFor": bson.M{"$exists": true}})
+	if err != nil || held != 0 {
+		t.Fatalf("expected the booking to take over the hold, got %d held nights (%v)", held, err)
+	}
+}
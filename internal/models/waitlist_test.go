@@ -0,0 +1,56 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWaitlistFlexibilityNormalize(t *testing.T) {
+	if nights, minNights, err := (WaitlistFlexibility{}).normalize(7); err != nil || nights != 7 || minNights != 7 {
+		t.Fatalf("expected the whole window by default, got %d %d %v", nights, minNights, err)
+	}
+	if nights, minNights, err := (WaitlistFlexibility{Nights: 3}).normalize(7); err != nil || nights != 3 || minNights != 3 {
+		t.Fatalf("expected no shorter stays by default, got %d %d %v", nights, minNights, err)
+	}
+	if _, _, err := (WaitlistFlexibility{Nights: 8}).normalize(7); err == nil {
+		t.Fatal("expected a stay longer than the window to be rejected")
+	}
+	if _, _, err := (WaitlistFlexibility{Nights: 3, MinNights: 4}).normalize(7); err == nil {
+		t.Fatal("expected a minimum above the wanted length to be rejected")
+	}
+}
+
+func TestFreeStayCandidates(t *testing.T) {
+	window := []string{"2030-05-01", "2030-05-02", "2030-05-03", "2030-05-04", "2030-05-05", "2030-05-06"}
+	occupied := map[string]bool{"2030-05-02": true, "2030-05-06": true}
+
+	got := freeStayCandidates(window, occupied, 3, 3)
+	want := []stayRange{{CheckIn: "2030-05-03", CheckOut: "2030-05-06"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected only the full-length run, got %+v", got)
+	}
+
+	occupied["2030-05-04"] = true
+	got = freeStayCandidates(window, occupied, 3, 1)
+	want = []stayRange{
+		{CheckIn: "2030-05-01", CheckOut: "2030-05-02"},
+		{CheckIn: "2030-05-03", CheckOut: "2030-05-04"},
+		{CheckIn: "2030-05-05", CheckOut: "2030-05-06"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected the shorter runs in date order, got %+v", got)
+	}
+
+	delete(occupied, "2030-05-02")
+	got = freeStayCandidates(window, occupied, 3, 1)
+	if len(got) == 0 || got[0] != (stayRange{CheckIn: "2030-05-01", CheckOut: "2030-05-04"}) {
+		t.Fatalf("expected the full-length run first, got %+v", got)
+	}
+	if len(got) != 2 || got[1] != (stayRange{CheckIn: "2030-05-05", CheckOut: "2030-05-06"}) {
+		t.Fatalf("expected the shorter run after it, got %+v", got)
+	}
+
+	if got := freeStayCandidates(window, occupied, 3, 3); len(got) != 1 {
+		t.Fatalf("expected shorter runs to be ignored without a lower minimum, got %+v", got)
+	}
+}
@@ -0,0 +1,117 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"easybook/internal/jobs"
+	"easybook/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DeliverJob is the job kind that sends one notification on one channel.
+const DeliverJob = "notification.deliver"
+
+// Channel delivers notifications outside the in-app list. Name matches one
+// of models.NotificationChannels so users can turn it off per type. Deliver
+// returns an error wrapping jobs.ErrPermanent for failures that retrying
+// cannot fix.
+type Channel interface {
+	Name() string
+	// EnabledByDefault reports whether notificationType is sent on this
+	// channel for users who have not chosen otherwise.
+	EnabledByDefault(notificationType string) bool
+	Deliver(ctx context.Context, notification models.Notification) error
+}
+
+// Dispatcher queues a delivery job per channel for each new notification;
+// the job queue retries transient failures and dead-letters the rest.
+type Dispatcher struct {
+	store    *models.Store
+	queue    *jobs.Queue
+	channels map[string]Channel
+	order    []string
+}
+
+func NewDispatcher(store *models.Store, queue *jobs.Queue) *Dispatcher {
+	d := &Dispatcher{
+		store:    store,
+		queue:    queue,
+		channels: map[string]Channel{},
+	}
+	queue.Register(DeliverJob, d.deliver)
+	return d
+}
+
+// AddChannel registers channel. Call it before the queue runs.
+func (d *Dispatcher) AddChannel(channel Channel) {
+	if _, ok := d.channels[channel.Name()]; !ok {
+		d.order = append(d.order, channel.Name())
+	}
+	d.channels[channel.Name()] = channel
+}
+
+// Enqueue queues deliveries of notification on the channels the user's
+// preferences allow. It is installed as the store's notification-created
+// hook.
+func (d *Dispatcher) Enqueue(ctx context.Context, notification models.Notification) {
+	preferences, err := d.store.FindNotificationPreferences(ctx, notification.UserID.Hex())
+	if err != nil {
+		log.Printf("Load notification preferences for %s: %v", notification.UserID.Hex(), err)
+		return
+	}
+
+	for _, name := range d.order {
+		if !preferences.Allows(notification.Type, name, d.channels[name].EnabledByDefault(notification.Type)) {
+			continue
+		}
+		err := d.queue.Enqueue(ctx, models.Job{
+			Kind:   DeliverJob,
+			Key:    DeliverJob + ":" + notification.ID.Hex() + ":" + name,
+			UserID: notification.UserID,
+			Payload: map[string]string{
+				"notificationId": notification.ID.Hex(),
+				"channel":        name,
+			},
+		})
+		if err != nil {
+			log.Printf("Queue %s delivery for notification %s: %v", name, notification.ID.Hex(), err)
+		}
+	}
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, job models.Job) error {
+	channel, ok := d.channels[job.Payload["channel"]]
+	if !ok {
+		return fmt.Errorf("%w: unknown channel %q", jobs.ErrPermanent, job.Payload["channel"])
+	}
+	notificationID, err := primitive.ObjectIDFromHex(job.Payload["notificationId"])
+	if err != nil {
+		return fmt.Errorf("%w: invalid notification id", jobs.ErrPermanent)
+	}
+	notification, err := d.store.FindNotificationByID(ctx, notificationID)
+	if errors.Is(err, models.ErrNotificationNotFound) {
+		return fmt.Errorf("%w: %v", jobs.ErrPermanent, err)
+	}
+	if err != nil {
+		return err
+	}
+	return channel.Deliver(ctx, *notification)
+}
+
+// EnabledByDefault reports whether notificationType goes out on channel for
+// users without a saved choice. Unregistered channels send nothing.
+func (d *Dispatcher) EnabledByDefault(notificationType, channel string) bool {
+	if registered, ok := d.channels[channel]; ok {
+		return registered.EnabledByDefault(notificationType)
+	}
+	return false
+}
+
+func (d *Dispatcher) HasChannel(channel string) bool {
+	_, ok := d.channels[channel]
+	return ok
+}
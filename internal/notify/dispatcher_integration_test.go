@@ -0,0 +1,116 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"easybook/internal/db"
+	"easybook/internal/jobs"
+	"easybook/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type flakyChannel struct {
+	failures []error
+	sent     int
+}
+
+func (c *flakyChannel) Name() string { return models.ChannelWebhook }
+
+func (c *flakyChannel) EnabledByDefault(notificationType string) bool {
+	return notificationType == models.NotificationWaitlistAvailable
+}
+
+func (c *flakyChannel) Deliver(context.Context, models.Notification) error {
+	if len(c.failures) > 0 {
+		err := c.failures[0]
+		c.failures = c.failures[1:]
+		return err
+	}
+	c.sent++
+	return nil
+}
+
+func TestDispatcherQueuesDeliveryJobs(t *testing.T) {
+	mongoURI := strings.TrimSpace(os.Getenv("MONGO_URI"))
+	if mongoURI == "" {
+		t.Skip("MONGO_URI is not set; skipping integration test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURI))
+	if err != nil {
+		t.Fatalf("connect mongo: %v", err)
+	}
+	defer func() {
+		_ = client.Disconnect(context.Background())
+	}()
+
+	database := client.Database("easybook_dispatch_test_" + primitive.NewObjectID().Hex())
+	defer func() {
+		_ = database.Drop(context.Background())
+	}()
+	if err := db.EnsureStartupMaintenance(ctx, database); err != nil {
+		t.Fatalf("ensure indexes: %v", err)
+	}
+
+	store := models.NewStore(database)
+	// A negative delay makes retries due immediately.
+	queue := jobs.NewQueue(store, jobs.Config{MaxAttempts: 3, RetryDelay: -time.Second})
+	dispatcher := NewDispatcher(store, queue)
+	channel := &flakyChannel{failures: []error{errors.New("connection refused")}}
+	dispatcher.AddChannel(channel)
+	store.SetNotificationCreatedHook(dispatcher.Enqueue)
+
+	userID := primitive.NewObjectID().Hex()
+	if _, err := store.CreateNotification(ctx, userID, models.NotificationGeneral, "Skipped", "Not sent on this channel", ""); err != nil {
+		t.Fatalf("create notification: %v", err)
+	}
+	notificationID, err := store.CreateNotification(ctx, userID, models.NotificationWaitlistAvailable, "Room is available now", "Book soon", "/hotels")
+	if err != nil {
+		t.Fatalf("create notification: %v", err)
+	}
+
+	queue.Drain(ctx)
+
+	deliveries := deliveryJobsForTests(t, database, notificationID)
+	if len(deliveries) != 1 || deliveries[0].Status != models.JobDone || deliveries[0].Attempts != 2 || channel.sent != 1 {
+		t.Fatalf("expected one delivery sent on the second attempt, got %+v (sent %d)", deliveries, channel.sent)
+	}
+
+	channel.failures = []error{fmt.Errorf("%w: mailbox unavailable", jobs.ErrPermanent)}
+	notificationID, err = store.CreateNotification(ctx, userID, models.NotificationWaitlistAvailable, "Room is available now", "Book soon", "/hotels")
+	if err != nil {
+		t.Fatalf("create notification: %v", err)
+	}
+	queue.Drain(ctx)
+
+	deliveries = deliveryJobsForTests(t, database, notificationID)
+	if len(deliveries) != 1 || deliveries[0].Status != models.JobDead || deliveries[0].Attempts != 1 || !strings.Contains(deliveries[0].LastError, "mailbox unavailable") {
+		t.Fatalf("expected a permanent failure without retries, got %+v", deliveries)
+	}
+}
+
+func deliveryJobsForTests(t *testing.T, database *mongo.Database, notificationID string) []models.Job {
+	t.Helper()
+	cursor, err := database.Collection("jobs").Find(context.Background(), bson.M{"kind": DeliverJob, "payload.notificationId": notificationID})
+	if err != nil {
+		t.Fatalf("find delivery jobs: %v", err)
+	}
+	var deliveries []models.Job
+	if err := cursor.All(context.Background(), &deliveries); err != nil {
+		t.Fatalf("decode delivery jobs: %v", err)
+	}
+	return deliveries
+}
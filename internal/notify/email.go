@@ -0,0 +1,108 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+	"strings"
+
+	"easybook/internal/jobs"
+	"easybook/internal/mail"
+	"easybook/internal/models"
+	"easybook/internal/view"
+)
+
+const defaultEmailTemplate = "default"
+
+// EmailChannel mails notifications to the user's verified address. Types
+// lists what is emailed unless the user opts out. Each type renders
+// views/email/<type>.html and .txt, falling back to default.html and
+// default.txt.
+type EmailChannel struct {
+	Store    *models.Store
+	Sender   mail.Sender
+	Renderer *view.Renderer
+	BaseURL  string
+	Types    []string
+}
+
+func (c *EmailChannel) Name() string {
+	return models.ChannelEmail
+}
+
+func (c *EmailChannel) EnabledByDefault(notificationType string) bool {
+	return slices.Contains(c.Types, notificationType)
+}
+
+func (c *EmailChannel) Deliver(ctx context.Context, notification models.Notification) error {
+	user, err := c.Store.FindUserByID(ctx, notification.UserID.Hex())
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return fmt.Errorf("%w: user no longer exists", jobs.ErrPermanent)
+	}
+	if !user.EmailVerified {
+		return fmt.Errorf("%w: email address is not verified", jobs.ErrPermanent)
+	}
+
+	message, err := c.Render(notification, user.Email)
+	if err != nil {
+		return fmt.Errorf("%w: %v", jobs.ErrPermanent, err)
+	}
+	if err := c.Sender.Send(ctx, message); err != nil {
+		if !mail.IsTransient(err) {
+			return fmt.Errorf("%w: %v", jobs.ErrPermanent, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// Render builds the email for notification from its type's templates.
+func (c *EmailChannel) Render(notification models.Notification, to string) (mail.Message, error) {
+	link := strings.TrimSpace(notification.Link)
+	if strings.HasPrefix(link, "/") {
+		link = strings.TrimRight(c.BaseURL, "/") + link
+	}
+
+	linkLine := ""
+	linkButton := ""
+	if link != "" {
+		linkLine = "Open: " + link
+		linkButton = fmt.Sprintf(`<p><a href="%s" style="display:inline-block; padding:10px 16px; background:#0f766e; color:#ffffff; text-decoration:none; border-radius:6px;">Open</a></p>`, view.EscapeHTML(link))
+	}
+
+	textValues := map[string]string{
+		"title":    notification.Title,
+		"text":     notification.Text,
+		"link":     link,
+		"linkLine": linkLine,
+		"email":    to,
+	}
+	htmlValues := map[string]any{
+		"title":      notification.Title,
+		"text":       notification.Text,
+		"link":       link,
+		"linkButton": view.Safe(linkButton),
+		"email":      to,
+	}
+
+	name := notification.Type
+	text, err := c.Renderer.RenderText("email/"+name+".txt", textValues)
+	if errors.Is(err, fs.ErrNotExist) {
+		name = defaultEmailTemplate
+		text, err = c.Renderer.RenderText("email/"+name+".txt", textValues)
+	}
+	if err != nil {
+		return mail.Message{}, err
+	}
+	html, err := c.Renderer.Render("email/"+name+".html", htmlValues)
+	if err != nil {
+		return mail.Message{}, err
+	}
+
+	return mail.Message{To: to, Subject: notification.Title, Text: text, HTML: html}, nil
+}
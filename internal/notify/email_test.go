@@ -0,0 +1,40 @@
+package notify
+
+import (
+	"strings"
+	"testing"
+
+	"easybook/internal/models"
+	"easybook/internal/view"
+)
+
+func TestEmailChannelRendersTypeTemplates(t *testing.T) {
+	channel := &EmailChannel{Renderer: view.NewRenderer("../../views"), BaseURL: "https://easybook.example"}
+
+	message, err := channel.Render(models.Notification{
+		Type:  models.NotificationWaitlistAvailable,
+		Title: "Room is available now",
+		Text:  "Room is now available for 2030-01-10 to 2030-01-12.",
+		Link:  "/bookings/new?hotelId=abc&checkIn=2030-01-10",
+	}, "guest@example.com")
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if message.Subject != "Room is available now" || message.To != "guest@example.com" {
+		t.Fatalf("unexpected headers: %+v", message)
+	}
+	if !strings.Contains(message.Text, "https://easybook.example/bookings/new?hotelId=abc&checkIn=2030-01-10") {
+		t.Fatalf("expected an absolute, unescaped link in the text part, got %q", message.Text)
+	}
+	if !strings.Contains(message.HTML, "hotelId=abc&amp;checkIn=2030-01-10") || !strings.Contains(message.HTML, "waitlist") {
+		t.Fatalf("expected the waitlist HTML template, got %q", message.HTML)
+	}
+
+	fallback, err := channel.Render(models.Notification{Type: "unknown", Title: "Hello <team>", Text: "Body"}, "guest@example.com")
+	if err != nil {
+		t.Fatalf("render fallback: %v", err)
+	}
+	if !strings.Contains(fallback.HTML, "Hello &lt;team&gt;") || strings.Contains(fallback.Text, "Open:") {
+		t.Fatalf("expected the default templates, got %+v", fallback)
+	}
+}
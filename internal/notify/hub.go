@@ -0,0 +1,91 @@
+package notify
+
+import "sync"
+
+// Hub fans notification changes out to the streams open in this process.
+// A signal only says "something changed for this user"; subscribers reload
+// what they need, so pending signals are coalesced instead of queued.
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan struct{}]struct{}
+	closed      bool
+}
+
+func NewHub() *Hub {
+	return &Hub{subscribers: map[string]map[chan struct{}]struct{}{}}
+}
+
+// Subscribe returns a channel that receives a value after each change for
+// userID. The channel is closed when cancel is called or the hub closes.
+func (h *Hub) Subscribe(userID string) (<-chan struct{}, func()) {
+	signal := make(chan struct{}, 1)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		close(signal)
+		return signal, func() {}
+	}
+	if h.subscribers[userID] == nil {
+		h.subscribers[userID] = map[chan struct{}]struct{}{}
+	}
+	h.subscribers[userID][signal] = struct{}{}
+
+	var once sync.Once
+	return signal, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			if _, ok := h.subscribers[userID][signal]; !ok {
+				return
+			}
+			delete(h.subscribers[userID], signal)
+			if len(h.subscribers[userID]) == 0 {
+				delete(h.subscribers, userID)
+			}
+			close(signal)
+		})
+	}
+}
+
+func (h *Hub) Publish(userID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for signal := range h.subscribers[userID] {
+		select {
+		case signal <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// PublishAll signals every subscriber, for changes whose user is unknown.
+func (h *Hub) PublishAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, signals := range h.subscribers {
+		for signal := range signals {
+			select {
+			case signal <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// Close ends every open subscription so long-lived streams return during
+// server shutdown.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return
+	}
+	h.closed = true
+	for _, signals := range h.subscribers {
+		for signal := range signals {
+			close(signal)
+		}
+	}
+	h.subscribers = map[string]map[chan struct{}]struct{}{}
+}
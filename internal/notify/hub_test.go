@@ -0,0 +1,67 @@
+package notify
+
+import "testing"
+
+func TestHubCoalescesSignalsPerUser(t *testing.T) {
+	hub := NewHub()
+	changes, cancel := hub.Subscribe("alice")
+	other, cancelOther := hub.Subscribe("bob")
+	defer cancelOther()
+
+	hub.Publish("alice")
+	hub.Publish("alice")
+
+	if _, ok := <-changes; !ok {
+		t.Fatal("expected a signal for alice")
+	}
+	select {
+	case <-changes:
+		t.Fatal("expected pending signals to be coalesced")
+	default:
+	}
+	select {
+	case <-other:
+		t.Fatal("expected bob not to be signalled")
+	default:
+	}
+
+	cancel()
+	cancel()
+	if _, ok := <-changes; ok {
+		t.Fatal("expected the channel to close after cancel")
+	}
+	hub.Publish("alice")
+}
+
+func TestHubCloseEndsSubscriptions(t *testing.T) {
+	hub := NewHub()
+	changes, cancel := hub.Subscribe("alice")
+	hub.Close()
+	cancel()
+
+	if _, ok := <-changes; ok {
+		t.Fatal("expected the channel to close with the hub")
+	}
+	late, _ := hub.Subscribe("alice")
+	if _, ok := <-late; ok {
+		t.Fatal("expected subscriptions after Close to be closed")
+	}
+}
+
+func TestHubPublishAllSignalsEveryUser(t *testing.T) {
+	hub := NewHub()
+	alice, cancelAlice := hub.Subscribe("alice")
+	defer cancelAlice()
+	bob, cancelBob := hub.Subscribe("bob")
+	defer cancelBob()
+
+	hub.PublishAll()
+
+	for name, changes := range map[string]<-chan struct{}{"alice": alice, "bob": bob} {
+		select {
+		case <-changes:
+		default:
+			t.Fatalf("expected %s to be signalled", name)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package notify
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const watchRetryDelay = 2 * time.Second
+
+type changeEvent struct {
+	OperationType string `bson:"operationType"`
+	FullDocument  struct {
+		UserID primitive.ObjectID `bson:"userId"`
+	} `bson:"fullDocument"`
+	FullDocumentBeforeChange struct {
+		UserID primitive.ObjectID `bson:"userId"`
+	} `bson:"fullDocumentBeforeChange"`
+}
+
+// Watch feeds hub from a change stream on the notifications collection, so
+// writes made by any instance reach streams open in this one. It returns
+// an error when the stream cannot be opened, e.g. on a standalone server
+// without replica set support; callers then publish in-process instead.
+//
+// Delete events, including TTL purges, only name the deleted document. Where
+// the server keeps pre-images (MongoDB 6.0 and later) they tell whose
+// notification it was; otherwise every open stream is told to check.
+func Watch(ctx context.Context, collection *mongo.Collection, hub *Hub) error {
+	preImages := enablePreImages(ctx, collection)
+	stream, err := openStream(ctx, collection, preImages, nil)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for {
+			resumeToken := consume(ctx, stream, hub)
+			_ = stream.Close(context.Background())
+			if ctx.Err() != nil {
+				return
+			}
+
+			for stream = nil; stream == nil; {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(watchRetryDelay):
+				}
+				if stream, err = openStream(ctx, collection, preImages, resumeToken); err != nil {
+					log.Printf("Notification change stream: %v", err)
+					// The token may have fallen off the oplog; start fresh.
+					resumeToken = nil
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// enablePreImages asks the server to keep pre-images of notifications. It
+// reports false on servers that do not support them.
+func enablePreImages(ctx context.Context, collection *mongo.Collection) bool {
+	err := collection.Database().RunCommand(ctx, bson.D{
+		{Key: "collMod", Value: collection.Name()},
+		{Key: "changeStreamPreAndPostImages", Value: bson.M{"enabled": true}},
+	}).Err()
+	return err == nil
+}
+
+func openStream(ctx context.Context, collection *mongo.Collection, preImages bool, resumeToken bson.Raw) (*mongo.ChangeStream, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"operationType": bson.M{"$in": bson.A{"insert", "update", "replace", "delete"}}}}},
+		{{Key: "$project", Value: bson.M{
+			"operationType":                   1,
+			"fullDocument.userId":             1,
+			"fullDocumentBeforeChange.userId": 1,
+		}}},
+	}
+	opts := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+	if preImages {
+		opts.SetFullDocumentBeforeChange(options.WhenAvailable)
+	}
+	if resumeToken != nil {
+		opts.SetResumeAfter(resumeToken)
+	}
+	return collection.Watch(ctx, pipeline, opts)
+}
+
+func consume(ctx context.Context, stream *mongo.ChangeStream, hub *Hub) bson.Raw {
+	for stream.Next(ctx) {
+		var event changeEvent
+		if err := stream.Decode(&event); err != nil {
+			continue
+		}
+		userID := event.FullDocument.UserID
+		if userID.IsZero() {
+			userID = event.FullDocumentBeforeChange.UserID
+		}
+		switch {
+		case !userID.IsZero():
+			hub.Publish(userID.Hex())
+		case event.OperationType == "delete":
+			hub.PublishAll()
+		}
+	}
+	if err := stream.Err(); err != nil && ctx.Err() == nil {
+		log.Printf("Notification change stream: %v", err)
+	}
+	return stream.ResumeToken()
+}
@@ -0,0 +1,82 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"easybook/internal/oidc/oidctest"
+)
+
+const testRedirectURI = "http://app.test/login/sso/corp/callback"
+
+func authorizeForTest(t *testing.T, provider *Provider, nonce, challenge string) string {
+	t.Helper()
+
+	authURL, err := provider.AuthCodeURL(context.Background(), testRedirectURI, "state-1", nonce, challenge)
+	if err != nil {
+		t.Fatalf("auth url: %v", err)
+	}
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	response, err := client.Get(authURL)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	response.Body.Close()
+
+	location, err := url.Parse(response.Header.Get("Location"))
+	if err != nil || location.Query().Get("state") != "state-1" {
+		t.Fatalf("unexpected authorize redirect %q", response.Header.Get("Location"))
+	}
+	return location.Query().Get("code")
+}
+
+func TestExchangeVerifiesIDTokenWithPKCE(t *testing.T) {
+	issuer := oidctest.NewIssuer("easybook", "client-secret")
+	defer issuer.Close()
+	issuer.SetUser(oidctest.User{Subject: "emp-42", Email: "Ada@Example.com", EmailVerified: true, Name: "Ada"})
+
+	provider := NewProvider(Config{ID: "corp", Issuer: issuer.URL, ClientID: "easybook", ClientSecret: "client-secret"}, nil)
+
+	verifier, challenge, err := NewPKCE()
+	if err != nil {
+		t.Fatalf("pkce: %v", err)
+	}
+	code := authorizeForTest(t, provider, "nonce-1", challenge)
+
+	claims, err := provider.Exchange(context.Background(), code, verifier, testRedirectURI, "nonce-1")
+	if err != nil {
+		t.Fatalf("exchange: %v", err)
+	}
+	if claims.Subject != "emp-42" || claims.Email != "ada@example.com" || !claims.EmailVerified {
+		t.Fatalf("unexpected claims %+v", claims)
+	}
+}
+
+func TestExchangeRejectsWrongVerifierAndNonce(t *testing.T) {
+	issuer := oidctest.NewIssuer("easybook", "")
+	defer issuer.Close()
+	issuer.SetUser(oidctest.User{Subject: "emp-7", Email: "bob@example.com", EmailVerified: true})
+
+	provider := NewProvider(Config{ID: "corp", Issuer: issuer.URL, ClientID: "easybook"}, nil)
+
+	verifier, challenge, err := NewPKCE()
+	if err != nil {
+		t.Fatalf("pkce: %v", err)
+	}
+
+	code := authorizeForTest(t, provider, "nonce-1", challenge)
+	if _, err := provider.Exchange(context.Background(), code, verifier+"x", testRedirectURI, "nonce-1"); !errors.Is(err, ErrExchangeFailed) {
+		t.Fatalf("expected exchange failure for wrong verifier, got %v", err)
+	}
+
+	code = authorizeForTest(t, provider, "nonce-1", challenge)
+	if _, err := provider.Exchange(context.Background(), code, verifier, testRedirectURI, "nonce-2"); !errors.Is(err, ErrInvalidIDToken) {
+		t.Fatalf("expected nonce mismatch, got %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+const memorySweepEvery = 1000
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	period  time.Duration
+}
+
+// MemoryStore keeps buckets in process memory. Counters are lost on restart
+// and not shared between instances.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	takes   int
+	now     func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: map[string]*bucket{}, now: time.Now}
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit) (Result, error) {
+	if !limit.Enabled() {
+		return Result{Allowed: true}, nil
+	}
+
+	now := s.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.buckets[key]
+	if !ok {
+		current = &bucket{tokens: float64(limit.Requests), updated: now}
+		s.buckets[key] = current
+	}
+	current.period = limit.Period
+	elapsed := now.Sub(current.updated).Seconds()
+	if elapsed > 0 {
+		current.tokens = math.Min(float64(limit.Requests), current.tokens+elapsed*limit.rate())
+	}
+	current.updated = now
+
+	allowed := current.tokens >= 1
+	if allowed {
+		current.tokens--
+	}
+
+	s.takes++
+	if s.takes%memorySweepEvery == 0 {
+		s.sweep(now)
+	}
+	return result(limit, allowed, current.tokens), nil
+}
+
+// sweep drops buckets that have been idle long enough to be full again.
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, item := range s.buckets {
+		if now.Sub(item.updated) > item.period {
+			delete(s.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreRefillsAtLimitRate(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	limit := Limit{Requests: 3, Period: 3 * time.Second}
+
+	for i := 0; i < 3; i++ {
+		result, _ := store.Take(context.Background(), "ip:1", limit)
+		if !result.Allowed || result.Remaining != 2-i {
+			t.Fatalf("request %d: expected allowed with %d remaining, got %+v", i, 2-i, result)
+		}
+	}
+
+	denied, _ := store.Take(context.Background(), "ip:1", limit)
+	if denied.Allowed || denied.RetryAfter != time.Second {
+		t.Fatalf("expected denial with 1s retry, got %+v", denied)
+	}
+	if other, _ := store.Take(context.Background(), "ip:2", limit); !other.Allowed {
+		t.Fatal("expected buckets to be independent per key")
+	}
+
+	now = now.Add(time.Second)
+	if result, _ := store.Take(context.Background(), "ip:1", limit); !result.Allowed || result.Remaining != 0 {
+		t.Fatalf("expected one refilled token after a second, got %+v", result)
+	}
+
+	now = now.Add(time.Hour)
+	if result, _ := store.Take(context.Background(), "ip:1", limit); result.Remaining != 2 || result.ResetAfter != time.Second {
+		t.Fatalf("expected refill capped at the burst size, got %+v", result)
+	}
+}
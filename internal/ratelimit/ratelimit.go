@@ -0,0 +1,62 @@
+// Package ratelimit implements token-bucket rate limiting with pluggable
+// storage: an in-process store for single instances and a Mongo store that
+// is shared by every instance of the app.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Limit allows Requests requests per Period with bursts up to Requests. The
+// bucket refills continuously at Requests/Period.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+func (l Limit) Enabled() bool {
+	return l.Requests > 0 && l.Period > 0
+}
+
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter is how long until the next request is allowed; zero when
+	// this one was.
+	RetryAfter time.Duration
+	// ResetAfter is how long until the bucket is full again.
+	ResetAfter time.Duration
+}
+
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// result derives the caller-facing numbers from the tokens left in a bucket.
+func result(limit Limit, allowed bool, tokens float64) Result {
+	rate := limit.rate()
+	out := Result{
+		Allowed:    allowed,
+		Limit:      limit.Requests,
+		Remaining:  int(math.Max(0, math.Floor(tokens))),
+		ResetAfter: seconds((float64(limit.Requests) - tokens) / rate),
+	}
+	if !allowed {
+		out.RetryAfter = seconds((1 - tokens) / rate)
+	}
+	return out
+}
+
+func seconds(value float64) time.Duration {
+	if value <= 0 {
+		return 0
+	}
+	return time.Duration(value * float64(time.Second))
+}
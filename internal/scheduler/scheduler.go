@@ -0,0 +1,65 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// Task is a job the scheduler runs every Interval. Run must be safe to repeat:
+// it runs once at start-up and again after every interval, and several
+// server instances may run it at the same time.
+type Task struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// Scheduler runs periodic background tasks until its context is cancelled.
+type Scheduler struct {
+	tasks []Task
+}
+
+func New() *Scheduler {
+	return &Scheduler{}
+}
+
+// Add registers task. Tasks without a positive interval are ignored. Call it
+// before Run.
+func (s *Scheduler) Add(task Task) {
+	if task.Interval <= 0 || task.Run == nil {
+		return
+	}
+	s.tasks = append(s.tasks, task)
+}
+
+// Run starts every task and blocks until ctx is done and all of them have
+// returned.
+func (s *Scheduler) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, task := range s.tasks {
+		wg.Add(1)
+		go func(task Task) {
+			defer wg.Done()
+			runTask(ctx, task)
+		}(task)
+	}
+	wg.Wait()
+}
+
+func runTask(ctx context.Context, task Task) {
+	ticker := time.NewTicker(task.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := task.Run(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Scheduled task %s: %v", task.Name, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
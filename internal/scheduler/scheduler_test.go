@@ -0,0 +1,42 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunRepeatsTasksUntilCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var runs atomic.Int32
+	s := New()
+	s.Add(Task{Name: "count", Interval: 10 * time.Millisecond, Run: func(context.Context) error {
+		if runs.Add(1) == 3 {
+			cancel()
+		}
+		return errors.New("keeps going after errors")
+	}})
+	s.Add(Task{Name: "disabled", Interval: 0, Run: func(context.Context) error {
+		t.Error("task without an interval should not run")
+		return nil
+	}})
+
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run did not return after cancel")
+	}
+	if got := runs.Load(); got != 3 {
+		t.Fatalf("expected 3 runs, got %d", got)
+	}
+}
@@ -0,0 +1,48 @@
+package session
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"easybook/internal/types"
+)
+
+// BearerAuthenticator resolves a personal API token to the user it acts for.
+// It returns nil when the token is unknown, expired or revoked.
+type BearerAuthenticator func(ctx context.Context, token string) (*types.CurrentUser, error)
+
+func (m *Manager) SetBearerAuthenticator(authenticator BearerAuthenticator) {
+	m.bearer = authenticator
+}
+
+// UsesBearerAuth reports whether the request authenticates with an
+// Authorization: Bearer header. Such requests ignore the session cookie, so
+// they are not exposed to CSRF.
+func UsesBearerAuth(r *http.Request) bool {
+	_, ok := bearerToken(r)
+	return ok
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	if r == nil || !strings.HasPrefix(r.URL.Path, "/api/") {
+		return "", false
+	}
+	header := strings.TrimSpace(r.Header.Get("Authorization"))
+	if len(header) < 7 || !strings.EqualFold(header[:7], "bearer ") {
+		return "", false
+	}
+	token := strings.TrimSpace(header[7:])
+	return token, token != ""
+}
+
+func (m *Manager) loadBearerUser(r *http.Request, token string) *types.CurrentUser {
+	if m.bearer == nil {
+		return nil
+	}
+	user, err := m.bearer(r.Context(), token)
+	if err != nil {
+		return nil
+	}
+	return user
+}
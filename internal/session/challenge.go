@@ -0,0 +1,128 @@
+package session
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	challengeCookieName  = "easybook.2fa"
+	challengeTTL         = 5 * time.Minute
+	challengeMaxAttempts = 5
+)
+
+var ErrChallengeNotFound = errors.New("login challenge not found or expired")
+
+// LoginChallenge is the state kept between a correct password and the second
+// login factor. No session cookie is issued until the challenge is completed.
+type LoginChallenge struct {
+	ID         string    `bson:"_id"`
+	UserID     string    `bson:"userId"`
+	Email      string    `bson:"email"`
+	Next       string    `bson:"next"`
+	RememberMe bool      `bson:"rememberMe"`
+	Attempts   int       `bson:"attempts"`
+	CreatedAt  time.Time `bson:"createdAt"`
+	ExpiresAt  time.Time `bson:"expiresAt"`
+}
+
+func (m *Manager) StartLoginChallenge(w http.ResponseWriter, r *http.Request, challenge LoginChallenge) error {
+	token, err := generateToken()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	challenge.ID = token
+	challenge.Attempts = 0
+	challenge.CreatedAt = now
+	challenge.ExpiresAt = now.Add(challengeTTL)
+	if _, err := m.challenges.InsertOne(r.Context(), challenge); err != nil {
+		return err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     challengeCookieName,
+		Value:    m.encodeCookieValue(token),
+		Path:     "/login",
+		HttpOnly: true,
+		Secure:   m.secure,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   int(challengeTTL.Seconds()),
+		Expires:  challenge.ExpiresAt,
+	})
+	return nil
+}
+
+func (m *Manager) CurrentLoginChallenge(r *http.Request) (*LoginChallenge, error) {
+	cookie, err := r.Cookie(challengeCookieName)
+	if err != nil || cookie.Value == "" {
+		return nil, ErrChallengeNotFound
+	}
+	token, ok := m.decodeCookieValue(cookie.Value)
+	if !ok {
+		return nil, ErrChallengeNotFound
+	}
+
+	var challenge LoginChallenge
+	err = m.challenges.FindOne(r.Context(), bson.M{
+		"_id":       token,
+		"expiresAt": bson.M{"$gt": time.Now().UTC()},
+	}).Decode(&challenge)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrChallengeNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+// FailLoginChallenge counts a wrong code and discards the challenge once the
+// attempt limit is reached. It reports whether the challenge is still usable.
+func (m *Manager) FailLoginChallenge(w http.ResponseWriter, r *http.Request, challenge *LoginChallenge) (bool, error) {
+	var updated LoginChallenge
+	err := m.challenges.FindOneAndUpdate(
+		r.Context(),
+		bson.M{"_id": challenge.ID},
+		bson.M{"$inc": bson.M{"attempts": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		m.ClearLoginChallenge(w, r)
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if updated.Attempts >= challengeMaxAttempts {
+		m.ClearLoginChallenge(w, r)
+		return false, nil
+	}
+	return true, nil
+}
+
+func (m *Manager) ClearLoginChallenge(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(challengeCookieName); err == nil && cookie.Value != "" {
+		if token, ok := m.decodeCookieValue(cookie.Value); ok {
+			_, _ = m.challenges.DeleteOne(r.Context(), bson.M{"_id": token})
+		}
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     challengeCookieName,
+		Value:    "",
+		Path:     "/login",
+		HttpOnly: true,
+		Secure:   m.secure,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   -1,
+		Expires:  time.Unix(0, 0),
+	})
+}
@@ -0,0 +1,82 @@
+package session
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"strings"
+)
+
+const (
+	csrfCookieName = "easybook.csrf"
+	CSRFHeaderName = "X-CSRF-Token"
+	CSRFFormField  = "_csrf"
+)
+
+type csrfContextKey struct{}
+
+// CSRFToken returns the token that state-changing requests from the current
+// client must echo back. It is derived from the session token, or from an
+// anonymous cookie for visitors who are not signed in, so signing in or out
+// rotates it.
+func CSRFToken(r *http.Request) string {
+	if r == nil {
+		return ""
+	}
+	token, _ := r.Context().Value(csrfContextKey{}).(string)
+	return token
+}
+
+func ValidCSRFToken(r *http.Request, provided string) bool {
+	expected := CSRFToken(r)
+	provided = strings.TrimSpace(provided)
+	if expected == "" || provided == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(provided)) == 1
+}
+
+func (m *Manager) withCSRFToken(w http.ResponseWriter, r *http.Request, signedIn bool) context.Context {
+	binding := ""
+	if signedIn {
+		if token, ok := m.requestToken(r); ok {
+			binding = "session:" + token
+		}
+	}
+	if binding == "" {
+		anonymous := m.anonymousCSRFBinding(w, r)
+		if anonymous == "" {
+			return r.Context()
+		}
+		binding = "anonymous:" + anonymous
+	}
+
+	mac := hmac.New(sha256.New, m.secret)
+	mac.Write([]byte("csrf:" + binding))
+	return context.WithValue(r.Context(), csrfContextKey{}, base64.RawURLEncoding.EncodeToString(mac.Sum(nil)))
+}
+
+func (m *Manager) anonymousCSRFBinding(w http.ResponseWriter, r *http.Request) string {
+	if cookie, err := r.Cookie(csrfCookieName); err == nil && cookie.Value != "" {
+		if value, ok := m.decodeCookieValue(cookie.Value); ok {
+			return value
+		}
+	}
+
+	value, err := generateToken()
+	if err != nil {
+		return ""
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfCookieName,
+		Value:    m.encodeCookieValue(value),
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   m.secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return value
+}
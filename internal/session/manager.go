@@ -7,12 +7,14 @@ import (
 	"crypto/sha256"
 	"crypto/subtle"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"net/http"
 	"strings"
 	"time"
 
 	"easybook/internal/types"
+	"easybook/internal/utils"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -20,27 +22,69 @@ import (
 )
 
 const (
-	cookieName        = "easybook.sid"
-	defaultSessionTTL = 24 * time.Hour
+	cookieName       = "easybook.sid"
+	lastSeenInterval = 5 * time.Minute
+	maxUserAgentLen  = 256
 )
 
+// Timeouts controls how long sessions live. Idle is the sliding window that is
+// extended on activity; Absolute caps the lifetime regardless of activity. The
+// Remember pair applies to sessions started with "remember me".
+type Timeouts struct {
+	Idle             time.Duration
+	Absolute         time.Duration
+	RememberIdle     time.Duration
+	RememberAbsolute time.Duration
+}
+
+var DefaultTimeouts = Timeouts{
+	Idle:             2 * time.Hour,
+	Absolute:         24 * time.Hour,
+	RememberIdle:     7 * 24 * time.Hour,
+	RememberAbsolute: 30 * 24 * time.Hour,
+}
+
 type contextKey struct{}
 
 type sessionDocument struct {
-	ID        string    `bson:"_id"`
-	UserID    string    `bson:"userId"`
-	Email     string    `bson:"email"`
-	Role      string    `bson:"role"`
-	CreatedAt time.Time `bson:"createdAt"`
-	UpdatedAt time.Time `bson:"updatedAt"`
-	ExpiresAt time.Time `bson:"expiresAt"`
+	ID     string `bson:"_id"`
+	UserID string `bson:"userId"`
+	Email  string `bson:"email"`
+	Role   string `bson:"role"`
+	// EmailVerified is nil for sessions created before verification existed.
+	EmailVerified *bool     `bson:"emailVerified,omitempty"`
+	TwoFactor     bool      `bson:"twoFactor"`
+	RememberMe    bool      `bson:"rememberMe"`
+	IP            string    `bson:"ip,omitempty"`
+	UserAgent     string    `bson:"userAgent,omitempty"`
+	CreatedAt     time.Time `bson:"createdAt"`
+	UpdatedAt     time.Time `bson:"updatedAt"`
+	LastSeenAt    time.Time `bson:"lastSeenAt,omitempty"`
+	ExpiresAt     time.Time `bson:"expiresAt"`
+	// AbsoluteExpiresAt is zero for sessions created before sliding expiry.
+	AbsoluteExpiresAt time.Time `bson:"absoluteExpiresAt,omitempty"`
+}
+
+// Info describes an active session without exposing its cookie token. ID is a
+// hash of the token and is safe to hand out for revocation.
+type Info struct {
+	ID         string    `json:"id"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"userAgent"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	Current    bool      `json:"current"`
 }
 
 type Manager struct {
 	collection *mongo.Collection
+	challenges *mongo.Collection
+	ssoLogins  *mongo.Collection
 	secure     bool
-	ttl        time.Duration
+	timeouts   Timeouts
 	secret     []byte
+	bearer     BearerAuthenticator
 }
 
 func NewManager(ctx context.Context, db *mongo.Database, secure bool, sessionSecret string) (*Manager, error) {
@@ -49,7 +93,30 @@ func NewManager(ctx context.Context, db *mongo.Database, secure bool, sessionSec
 	}
 
 	collection := db.Collection("sessions")
-	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+		{
+			Keys: bson.D{{Key: "userId", Value: 1}},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	challenges := db.Collection("login_challenges")
+	_, err = challenges.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ssoLogins := db.Collection("sso_logins")
+	_, err = ssoLogins.Indexes().CreateOne(ctx, mongo.IndexModel{
 		Keys:    bson.D{{Key: "expiresAt", Value: 1}},
 		Options: options.Index().SetExpireAfterSeconds(0),
 	})
@@ -59,15 +126,31 @@ func NewManager(ctx context.Context, db *mongo.Database, secure bool, sessionSec
 
 	return &Manager{
 		collection: collection,
+		challenges: challenges,
+		ssoLogins:  ssoLogins,
 		secure:     secure,
-		ttl:        defaultSessionTTL,
+		timeouts:   DefaultTimeouts,
 		secret:     []byte(sessionSecret),
 	}, nil
 }
 
+func (m *Manager) SetTimeouts(timeouts Timeouts) {
+	if timeouts.Idle <= 0 || timeouts.Absolute <= 0 || timeouts.RememberIdle <= 0 || timeouts.RememberAbsolute <= 0 {
+		return
+	}
+	m.timeouts = timeouts
+}
+
 func (m *Manager) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token, ok := bearerToken(r); ok {
+			ctx := context.WithValue(r.Context(), contextKey{}, m.loadBearerUser(r, token))
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
 		user := m.loadUser(r)
+		r = r.WithContext(m.withCSRFToken(w, r, user != nil))
 		ctx := context.WithValue(r.Context(), contextKey{}, user)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
@@ -85,10 +168,12 @@ func CurrentUser(r *http.Request) *types.CurrentUser {
 	return user
 }
 
-func (m *Manager) StartSession(w http.ResponseWriter, r *http.Request, userID, email, role string) error {
+func (m *Manager) StartSession(w http.ResponseWriter, r *http.Request, user types.CurrentUser, rememberMe bool) error {
+	role := user.Role
 	if role == "" {
 		role = "user"
 	}
+	emailVerified := user.EmailVerified
 
 	if existing, err := r.Cookie(cookieName); err == nil && existing.Value != "" {
 		if token, ok := m.decodeCookieValue(existing.Value); ok {
@@ -102,30 +187,44 @@ func (m *Manager) StartSession(w http.ResponseWriter, r *http.Request, userID, e
 	}
 
 	now := time.Now().UTC()
+	idle, absolute := m.lifetime(rememberMe)
+	absoluteExpiresAt := now.Add(absolute)
 	doc := sessionDocument{
-		ID:        token,
-		UserID:    userID,
-		Email:     email,
-		Role:      role,
-		CreatedAt: now,
-		UpdatedAt: now,
-		ExpiresAt: now.Add(m.ttl),
+		ID:                token,
+		UserID:            user.ID,
+		Email:             user.Email,
+		Role:              role,
+		EmailVerified:     &emailVerified,
+		TwoFactor:         user.TwoFactorVerified,
+		RememberMe:        rememberMe,
+		IP:                utils.ClientIP(r),
+		UserAgent:         truncate(r.UserAgent(), maxUserAgentLen),
+		CreatedAt:         now,
+		UpdatedAt:         now,
+		LastSeenAt:        now,
+		ExpiresAt:         earliest(now.Add(idle), absoluteExpiresAt),
+		AbsoluteExpiresAt: absoluteExpiresAt,
 	}
 
 	if _, err := m.collection.InsertOne(r.Context(), doc); err != nil {
 		return err
 	}
 
-	http.SetCookie(w, &http.Cookie{
+	// Without "remember me" the cookie lives until the browser closes; the
+	// server-side expiry still applies either way.
+	cookie := &http.Cookie{
 		Name:     cookieName,
 		Value:    m.encodeCookieValue(token),
 		Path:     "/",
 		HttpOnly: true,
 		Secure:   m.secure,
 		SameSite: http.SameSiteLaxMode,
-		MaxAge:   int(m.ttl.Seconds()),
-		Expires:  now.Add(m.ttl),
-	})
+	}
+	if rememberMe {
+		cookie.MaxAge = int(absolute.Seconds())
+		cookie.Expires = absoluteExpiresAt
+	}
+	http.SetCookie(w, cookie)
 
 	return nil
 }
@@ -149,37 +248,234 @@ func (m *Manager) DestroySession(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func (m *Manager) loadUser(r *http.Request) *types.CurrentUser {
+func (m *Manager) DestroyUserSessions(ctx context.Context, userID string) (int64, error) {
+	userID = strings.TrimSpace(userID)
+	if userID == "" {
+		return 0, nil
+	}
+
+	result, err := m.collection.DeleteMany(ctx, bson.M{"userId": userID})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+func (m *Manager) ListUserSessions(r *http.Request, userID string) ([]Info, error) {
+	userID = strings.TrimSpace(userID)
+	if userID == "" {
+		return []Info{}, nil
+	}
+
+	cursor, err := m.collection.Find(
+		r.Context(),
+		bson.M{"userId": userID, "expiresAt": bson.M{"$gt": time.Now().UTC()}},
+		options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(r.Context())
+
+	var docs []sessionDocument
+	if err := cursor.All(r.Context(), &docs); err != nil {
+		return nil, err
+	}
+
+	currentToken, _ := m.requestToken(r)
+	items := make([]Info, 0, len(docs))
+	for _, doc := range docs {
+		lastSeen := doc.LastSeenAt
+		if lastSeen.IsZero() {
+			lastSeen = doc.UpdatedAt
+		}
+		items = append(items, Info{
+			ID:         publicSessionID(doc.ID),
+			IP:         doc.IP,
+			UserAgent:  doc.UserAgent,
+			CreatedAt:  doc.CreatedAt,
+			LastSeenAt: lastSeen,
+			ExpiresAt:  doc.ExpiresAt,
+			Current:    doc.ID == currentToken,
+		})
+	}
+	return items, nil
+}
+
+// RevokeUserSession deletes the session of userID whose public ID matches
+// sessionID. Tokens are not stored by public ID, so the user's sessions are
+// scanned; a user only ever has a handful of them.
+func (m *Manager) RevokeUserSession(ctx context.Context, userID, sessionID string) (bool, error) {
+	userID = strings.TrimSpace(userID)
+	sessionID = strings.TrimSpace(sessionID)
+	if userID == "" || sessionID == "" {
+		return false, nil
+	}
+
+	cursor, err := m.collection.Find(ctx, bson.M{"userId": userID}, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return false, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID string `bson:"_id"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return false, err
+		}
+		if subtle.ConstantTimeCompare([]byte(publicSessionID(doc.ID)), []byte(sessionID)) != 1 {
+			continue
+		}
+
+		result, err := m.collection.DeleteOne(ctx, bson.M{"_id": doc.ID, "userId": userID})
+		if err != nil {
+			return false, err
+		}
+		return result.DeletedCount > 0, nil
+	}
+	return false, cursor.Err()
+}
+
+func (m *Manager) DestroyOtherUserSessions(r *http.Request, userID string) (int64, error) {
+	userID = strings.TrimSpace(userID)
+	currentToken, ok := m.requestToken(r)
+	if userID == "" || !ok {
+		return 0, nil
+	}
+
+	result, err := m.collection.DeleteMany(r.Context(), bson.M{"userId": userID, "_id": bson.M{"$ne": currentToken}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+func (m *Manager) MarkEmailVerified(ctx context.Context, userID, email string) error {
+	userID = strings.TrimSpace(userID)
+	if userID == "" {
+		return nil
+	}
+
+	_, err := m.collection.UpdateMany(ctx, bson.M{"userId": userID}, bson.M{"$set": bson.M{
+		"email":         email,
+		"emailVerified": true,
+		"updatedAt":     time.Now().UTC(),
+	}})
+	return err
+}
+
+func (m *Manager) MarkTwoFactorVerified(r *http.Request) error {
+	token, ok := m.requestToken(r)
+	if !ok {
+		return errors.New("no active session")
+	}
+
+	_, err := m.collection.UpdateOne(r.Context(), bson.M{"_id": token}, bson.M{"$set": bson.M{
+		"twoFactor": true,
+		"updatedAt": time.Now().UTC(),
+	}})
+	return err
+}
+
+func (m *Manager) requestToken(r *http.Request) (string, bool) {
 	cookie, err := r.Cookie(cookieName)
 	if err != nil || cookie.Value == "" {
-		return nil
+		return "", false
 	}
-	token, ok := m.decodeCookieValue(cookie.Value)
+	return m.decodeCookieValue(cookie.Value)
+}
+
+func (m *Manager) loadUser(r *http.Request) *types.CurrentUser {
+	token, ok := m.requestToken(r)
 	if !ok {
 		return nil
 	}
 
 	var doc sessionDocument
-	err = m.collection.FindOne(r.Context(), bson.M{"_id": token}).Decode(&doc)
+	err := m.collection.FindOne(r.Context(), bson.M{"_id": token}).Decode(&doc)
 	if err != nil {
 		return nil
 	}
 
-	if doc.ExpiresAt.Before(time.Now().UTC()) {
+	now := time.Now().UTC()
+	idle, absolute := m.lifetime(doc.RememberMe)
+	absoluteExpiresAt := doc.AbsoluteExpiresAt
+	if absoluteExpiresAt.IsZero() {
+		absoluteExpiresAt = doc.CreatedAt.Add(absolute)
+	}
+	if doc.ExpiresAt.Before(now) || absoluteExpiresAt.Before(now) {
 		_, _ = m.collection.DeleteOne(r.Context(), bson.M{"_id": doc.ID})
 		return nil
 	}
 
+	// Activity slides the idle window forward. The write is throttled so that
+	// only a fraction of requests touch the session document.
+	if now.Sub(doc.LastSeenAt) >= touchInterval(idle) {
+		_, _ = m.collection.UpdateOne(r.Context(), bson.M{"_id": doc.ID}, bson.M{"$set": bson.M{
+			"lastSeenAt":        now,
+			"ip":                utils.ClientIP(r),
+			"expiresAt":         earliest(now.Add(idle), absoluteExpiresAt),
+			"absoluteExpiresAt": absoluteExpiresAt,
+		}})
+	}
+
 	role := doc.Role
 	if role == "" {
 		role = "user"
 	}
 
+	emailVerified := true
+	if doc.EmailVerified != nil {
+		emailVerified = *doc.EmailVerified
+	}
+
 	return &types.CurrentUser{
-		ID:    doc.UserID,
-		Email: doc.Email,
-		Role:  role,
+		ID:                doc.UserID,
+		Email:             doc.Email,
+		Role:              role,
+		EmailVerified:     emailVerified,
+		TwoFactorVerified: doc.TwoFactor,
+	}
+}
+
+func (m *Manager) lifetime(rememberMe bool) (time.Duration, time.Duration) {
+	if rememberMe {
+		return m.timeouts.RememberIdle, m.timeouts.RememberAbsolute
+	}
+	return m.timeouts.Idle, m.timeouts.Absolute
+}
+
+func touchInterval(idle time.Duration) time.Duration {
+	interval := idle / 10
+	if interval > lastSeenInterval {
+		return lastSeenInterval
+	}
+	if interval < time.Second {
+		return time.Second
+	}
+	return interval
+}
+
+func earliest(a, b time.Time) time.Time {
+	if b.Before(a) {
+		return b
+	}
+	return a
+}
+
+func publicSessionID(token string) string {
+	sum := sha256.Sum256([]byte("session:" + token))
+	return hex.EncodeToString(sum[:16])
+}
+
+func truncate(value string, limit int) string {
+	value = strings.TrimSpace(value)
+	if len(value) > limit {
+		return value[:limit]
 	}
+	return value
 }
 
 func generateToken() (string, error) {
@@ -0,0 +1,97 @@
+package session
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	ssoCookieName = "easybook.sso"
+	ssoCookiePath = "/login/sso"
+	ssoLoginTTL   = 10 * time.Minute
+)
+
+var ErrSSOLoginNotFound = errors.New("single sign-on request not found or expired")
+
+// SSOLogin is the state kept while the browser is away at the identity
+// provider. Its ID is sent as the OAuth "state" parameter and is also bound to
+// the browser through a signed cookie.
+type SSOLogin struct {
+	ID           string    `bson:"_id"`
+	Provider     string    `bson:"provider"`
+	CodeVerifier string    `bson:"codeVerifier"`
+	Nonce        string    `bson:"nonce"`
+	Next         string    `bson:"next"`
+	RememberMe   bool      `bson:"rememberMe"`
+	CreatedAt    time.Time `bson:"createdAt"`
+	ExpiresAt    time.Time `bson:"expiresAt"`
+}
+
+func (m *Manager) StartSSOLogin(w http.ResponseWriter, r *http.Request, login SSOLogin) (string, error) {
+	token, err := generateToken()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now().UTC()
+	login.ID = token
+	login.CreatedAt = now
+	login.ExpiresAt = now.Add(ssoLoginTTL)
+	if _, err := m.ssoLogins.InsertOne(r.Context(), login); err != nil {
+		return "", err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     ssoCookieName,
+		Value:    m.encodeCookieValue(token),
+		Path:     ssoCookiePath,
+		HttpOnly: true,
+		Secure:   m.secure,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   int(ssoLoginTTL.Seconds()),
+		Expires:  login.ExpiresAt,
+	})
+	return token, nil
+}
+
+// ConsumeSSOLogin returns and deletes the pending login for the returned
+// state. It fails unless the state was issued to this browser.
+func (m *Manager) ConsumeSSOLogin(w http.ResponseWriter, r *http.Request, state string) (*SSOLogin, error) {
+	defer http.SetCookie(w, &http.Cookie{
+		Name:     ssoCookieName,
+		Value:    "",
+		Path:     ssoCookiePath,
+		HttpOnly: true,
+		Secure:   m.secure,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   -1,
+		Expires:  time.Unix(0, 0),
+	})
+
+	cookie, err := r.Cookie(ssoCookieName)
+	if err != nil || cookie.Value == "" || state == "" {
+		return nil, ErrSSOLoginNotFound
+	}
+	token, ok := m.decodeCookieValue(cookie.Value)
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(state)) != 1 {
+		return nil, ErrSSOLoginNotFound
+	}
+
+	var login SSOLogin
+	err = m.ssoLogins.FindOneAndDelete(r.Context(), bson.M{
+		"_id":       token,
+		"expiresAt": bson.M{"$gt": time.Now().UTC()},
+	}).Decode(&login)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrSSOLoginNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &login, nil
+}
@@ -0,0 +1,53 @@
+package totp
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+)
+
+func TestCodeAtMatchesRFC6238Vectors(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+	vectors := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, vector := range vectors {
+		code, err := CodeAt(secret, Step(time.Unix(vector.unix, 0)))
+		if err != nil {
+			t.Fatalf("code at %d: %v", vector.unix, err)
+		}
+		if code != vector.code {
+			t.Fatalf("expected %s at %d, got %s", vector.code, vector.unix, code)
+		}
+	}
+}
+
+func TestValidateAcceptsAdjacentStepWithinSkew(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("generate secret: %v", err)
+	}
+
+	now := time.Unix(1700000000, 0)
+	previous, err := CodeAt(secret, Step(now)-1)
+	if err != nil {
+		t.Fatalf("code: %v", err)
+	}
+
+	step, ok := Validate(secret, previous, now, 1)
+	if !ok || step != Step(now)-1 {
+		t.Fatalf("expected previous step to validate, got step=%d ok=%v", step, ok)
+	}
+	if _, ok := Validate(secret, previous, now, 0); ok {
+		t.Fatal("expected previous step to be rejected without skew")
+	}
+}
@@ -1,7 +1,28 @@
 package types
 
 type CurrentUser struct {
-	ID    string
-	Email string
-	Role  string
+	ID                string
+	Email             string
+	Role              string
+	EmailVerified     bool
+	TwoFactorVerified bool
+	// APITokenID is set when the request was authenticated with a personal API
+	// token instead of a session cookie; Scopes then limits what it may do.
+	APITokenID string
+	Scopes     []string
+}
+
+func (u *CurrentUser) HasScope(scope string) bool {
+	if u == nil {
+		return false
+	}
+	if u.APITokenID == "" {
+		return true
+	}
+	for _, granted := range u.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+)
+
+type clientIPKey struct{}
+
+// WithClientIP returns a copy of r whose ClientIP is ip.
+func WithClientIP(r *http.Request, ip string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), clientIPKey{}, ip))
+}
+
+// ClientIP identifies the calling client: the address resolved by
+// ResolveClientIP earlier in the request, or the connection's remote
+// address. Forwarding headers are never read here, since any client can
+// send them.
+func ClientIP(r *http.Request) string {
+	if r == nil {
+		return "unknown"
+	}
+	if ip, ok := r.Context().Value(clientIPKey{}).(string); ok && ip != "" {
+		return ip
+	}
+	return remoteHost(r)
+}
+
+// ResolveClientIP returns the client address behind trusted proxies. When
+// the connection comes from one of trusted, X-Forwarded-For is read from the
+// right and the first hop that is not a trusted proxy wins; X-Real-Ip is
+// used when there is no X-Forwarded-For. Other connections are taken as the
+// client itself.
+func ResolveClientIP(r *http.Request, trusted []*net.IPNet) string {
+	remote := remoteHost(r)
+	if !isTrustedProxy(remote, trusted) {
+		return remote
+	}
+
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(header, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+	if len(hops) > 0 {
+		client := remote
+		for i := len(hops) - 1; i >= 0; i-- {
+			if net.ParseIP(hops[i]) == nil {
+				// Anything left of a malformed hop cannot be trusted.
+				break
+			}
+			client = hops[i]
+			if !isTrustedProxy(client, trusted) {
+				break
+			}
+		}
+		return client
+	}
+
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-Ip")); net.ParseIP(realIP) != nil {
+		return realIP
+	}
+	return remote
+}
+
+func isTrustedProxy(address string, trusted []*net.IPNet) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+	for _, network := range trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func remoteHost(r *http.Request) string {
+	remoteAddr := strings.TrimSpace(r.RemoteAddr)
+	if remoteAddr == "" {
+		return "unknown"
+	}
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	if strings.TrimSpace(host) == "" {
+		return remoteAddr
+	}
+	return host
+}
@@ -25,6 +25,15 @@ type RegisterUser struct {
 	Password string
 }
 
+// SupportedLanguages are the values accepted for a user's preferred language.
+var SupportedLanguages = []string{"en", "ru", "kk"}
+
+type Profile struct {
+	Name              string
+	Phone             string
+	PreferredLanguage string
+}
+
 type PasswordRules struct {
 	LengthRule  bool
 	LowerRule   bool
@@ -217,6 +226,56 @@ func ValidateRegisterPayload(payload map[string]any) ([]string, RegisterUser) {
 	return errors, RegisterUser{Email: email, Password: password}
 }
 
+func ValidateNewPasswordPayload(payload map[string]any, email string) ([]string, string) {
+	password := ToTrimmedString(payload["password"])
+	confirmPassword := ToTrimmedString(payload["confirmPassword"])
+
+	errors := make([]string, 0)
+	rules := EvaluatePasswordRules(password, email)
+	if !rules.LengthRule || !rules.LowerRule || !rules.UpperRule || !rules.DigitRule || !rules.SpecialRule || !rules.OverlapRule {
+		errors = append(errors, "Password does not meet security requirements.")
+	}
+	if password != confirmPassword {
+		errors = append(errors, "Password confirmation does not match.")
+	}
+
+	return errors, password
+}
+
+func ValidateProfilePayload(payload map[string]any) ([]string, Profile) {
+	profile := Profile{
+		Name:              strings.Join(strings.Fields(ToTrimmedString(payload["name"])), " "),
+		Phone:             ToTrimmedString(payload["phone"]),
+		PreferredLanguage: strings.ToLower(ToTrimmedString(payload["preferredLanguage"])),
+	}
+
+	errors := make([]string, 0)
+	if len([]rune(profile.Name)) > 80 {
+		errors = append(errors, "Name must be at most 80 characters.")
+	}
+	if profile.Phone != "" && !phoneRegex.MatchString(profile.Phone) {
+		errors = append(errors, "Phone number is invalid.")
+	}
+	if profile.PreferredLanguage == "" {
+		profile.PreferredLanguage = SupportedLanguages[0]
+	}
+	supported := false
+	for _, language := range SupportedLanguages {
+		if language == profile.PreferredLanguage {
+			supported = true
+		}
+	}
+	if !supported {
+		errors = append(errors, "Preferred language must be one of: "+strings.Join(SupportedLanguages, ", ")+".")
+	}
+
+	return errors, profile
+}
+
+func ValidateEmail(email string) bool {
+	return emailRegex.MatchString(strings.ToLower(strings.TrimSpace(email)))
+}
+
 func EvaluatePasswordRules(password string, email string) PasswordRules {
 	specialCount := 0
 	for _, char := range password {
@@ -0,0 +1,15 @@
+package view
+
+import (
+	"regexp"
+)
+
+// CSPNonceKey is the replacement key that makes Render stamp the request's
+// Content-Security-Policy nonce on every script tag of the template.
+const CSPNonceKey = "cspNonce"
+
+var scriptTagPattern = regexp.MustCompile(`(?i)<script\b`)
+
+func injectCSPNonce(html, nonce string) string {
+	return scriptTagPattern.ReplaceAllString(html, `<script nonce="`+EscapeHTML(nonce)+`"`)
+}
@@ -0,0 +1,25 @@
+package view
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	// CSRFTokenKey is the replacement key that makes Render add the CSRF token
+	// to every POST form and to a csrf-token meta tag for scripts.
+	CSRFTokenKey = "csrfToken"
+	// csrfFieldName must match session.CSRFFormField.
+	csrfFieldName = "_csrf"
+)
+
+var postFormPattern = regexp.MustCompile(`(?i)<form\b[^>]*\bmethod\s*=\s*["']?post\b[^>]*>`)
+
+func injectCSRFToken(html, token string) string {
+	escaped := EscapeHTML(token)
+	field := `<input type="hidden" name="` + csrfFieldName + `" value="` + escaped + `" />`
+	html = postFormPattern.ReplaceAllStringFunc(html, func(tag string) string {
+		return tag + field
+	})
+	return strings.Replace(html, "</head>", `  <meta name="csrf-token" content="`+escaped+`" />`+"\n</head>", 1)
+}
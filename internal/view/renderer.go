@@ -40,6 +40,12 @@ func (r *Renderer) Render(fileName string, replacements map[string]any) (string,
 	}
 	html := string(bytes)
 
+	// Only the template's own script tags get the nonce, so markup that comes
+	// in through a replacement value cannot run.
+	if nonce, ok := replacements[CSPNonceKey].(string); ok && nonce != "" {
+		html = injectCSPNonce(html, nonce)
+	}
+
 	for key, rawValue := range replacements {
 		value := ""
 		switch typed := rawValue.(type) {
@@ -53,5 +59,22 @@ func (r *Renderer) Render(fileName string, replacements map[string]any) (string,
 		html = strings.ReplaceAll(html, "{{"+key+"}}", value)
 	}
 
+	if token, ok := replacements[CSRFTokenKey].(string); ok && token != "" {
+		html = injectCSRFToken(html, token)
+	}
 	return html, nil
 }
+
+// RenderText fills a plain-text template such as an email body. Values are
+// inserted as-is, without HTML escaping.
+func (r *Renderer) RenderText(fileName string, replacements map[string]string) (string, error) {
+	bytes, err := os.ReadFile(filepath.Join(r.viewsDir, fileName))
+	if err != nil {
+		return "", err
+	}
+	text := string(bytes)
+	for key, value := range replacements {
+		text = strings.ReplaceAll(text, "{{"+key+"}}", value)
+	}
+	return text, nil
+}
@@ -0,0 +1,30 @@
+package view
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRenderNoncesOnlyTemplateScripts(t *testing.T) {
+	dir := t.TempDir()
+	template := "<div>{{content}}</div><script src='/app.js'></script>"
+	if err := os.WriteFile(filepath.Join(dir, "page.html"), []byte(template), 0o644); err != nil {
+		t.Fatalf("write template: %v", err)
+	}
+
+	html, err := NewRenderer(dir).Render("page.html", map[string]any{
+		"content":   Safe("<script>alert(1)</script>"),
+		CSPNonceKey: "abc123",
+	})
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if !strings.Contains(html, `<script nonce="abc123" src='/app.js'>`) {
+		t.Fatalf("expected the template script to carry the nonce, got %s", html)
+	}
+	if !strings.Contains(html, "<div><script>alert(1)</script></div>") {
+		t.Fatalf("expected inserted markup to stay without a nonce, got %s", html)
+	}
+}
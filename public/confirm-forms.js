@@ -0,0 +1,14 @@
+(() => {
+  // Forms with a data-confirm message ask before submitting; inline handlers
+  // are blocked by the Content-Security-Policy.
+  document.addEventListener('submit', (event) => {
+    const form = event.target;
+    if (!(form instanceof HTMLFormElement)) {
+      return;
+    }
+    const message = form.dataset.confirm;
+    if (message && !window.confirm(message)) {
+      event.preventDefault();
+    }
+  });
+})();
@@ -0,0 +1,36 @@
+(() => {
+  const meta = document.querySelector('meta[name="csrf-token"]');
+  let token = meta ? meta.getAttribute('content') || '' : '';
+
+  const safeMethods = ['GET', 'HEAD', 'OPTIONS', 'TRACE'];
+  const nativeFetch = window.fetch.bind(window);
+
+  const isSameOrigin = (input) => {
+    const url = new URL(typeof input === 'string' ? input : input.url, window.location.href);
+    return url.origin === window.location.origin;
+  };
+
+  window.easybookCsrf = {
+    token: () => token,
+    setToken: (value) => {
+      if (typeof value === 'string' && value) {
+        token = value;
+      }
+    },
+  };
+
+  // Same-origin state-changing requests carry the token in X-CSRF-Token so the
+  // page scripts do not have to add it themselves.
+  window.fetch = (input, init = {}) => {
+    const method = String(init.method || (input && input.method) || 'GET').toUpperCase();
+    if (!token || safeMethods.includes(method) || !isSameOrigin(input)) {
+      return nativeFetch(input, init);
+    }
+
+    const headers = new Headers(init.headers || (input && input.headers) || {});
+    if (!headers.has('X-CSRF-Token')) {
+      headers.set('X-CSRF-Token', token);
+    }
+    return nativeFetch(input, { ...init, headers });
+  };
+})();
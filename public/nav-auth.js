@@ -76,19 +76,28 @@
     hidden.name = 'next';
     hidden.value = nextPath;
 
+    const csrf = document.createElement('input');
+    csrf.type = 'hidden';
+    csrf.name = '_csrf';
+    csrf.value = window.easybookCsrf ? window.easybookCsrf.token() : '';
+
     const button = document.createElement('button');
     button.type = 'submit';
     button.className = 'nav-logout-btn';
     button.textContent = 'Logout';
 
     form.appendChild(hidden);
+    form.appendChild(csrf);
     form.appendChild(button);
     return form;
   };
 
   fetch('/api/auth/session', { credentials: 'same-origin' })
-    .then((response) => (response.ok ? response.json() : null))
+    .then((response) => (response.ok || response.status === 401 ? response.json() : null))
     .then((data) => {
+      if (data && data.csrfToken && window.easybookCsrf) {
+        window.easybookCsrf.setToken(data.csrfToken);
+      }
       const authenticated = Boolean(data && data.authenticated);
       if (!authenticated) {
         navs.forEach((nav) => {
@@ -7,11 +7,22 @@
   const listNode = document.getElementById('notificationsList');
   const unreadNode = document.getElementById('notificationsUnreadCount');
   const readAllButton = document.getElementById('notificationsReadAllButton');
-  if (!listNode || !unreadNode || !readAllButton) {
+  const statusSelect = document.getElementById('notificationsStatus');
+  const typeSelect = document.getElementById('notificationsType');
+  const prevButton = document.getElementById('notificationsPrevButton');
+  const nextButton = document.getElementById('notificationsNextButton');
+  const pageInfoNode = document.getElementById('notificationsPageInfo');
+  if (!listNode || !unreadNode || !readAllButton || !statusSelect || !typeSelect || !prevButton || !nextButton || !pageInfoNode) {
     return;
   }
 
+  const pageSize = 20;
   let items = [];
+  let unreadCount = 0;
+  let page = 1;
+  let totalPages = 1;
+  let stream = null;
+  let pollTimer = null;
 
   const formatDate = (value) => {
     if (!value) return '';
@@ -20,11 +31,14 @@
     return date.toLocaleString();
   };
 
-  const render = (unreadCount) => {
+  const render = () => {
     unreadNode.textContent = String(unreadCount);
+    prevButton.disabled = page <= 1;
+    nextButton.disabled = page >= totalPages;
+    pageInfoNode.textContent = `Page ${page} of ${totalPages}`;
 
     if (items.length === 0) {
-      listNode.innerHTML = '<div class="notification-empty">No notifications yet.</div>';
+      listNode.innerHTML = '<div class="notification-empty">No notifications here.</div>';
       return;
     }
 
@@ -35,6 +49,9 @@
       const readButton = item.isRead
         ? ''
         : `<button class="btn btn-outline btn-small notification-read-btn" data-id="${item.id}" type="button">Mark as read</button>`;
+      const archiveButton = item.archived
+        ? ''
+        : `<button class="btn btn-outline btn-small notification-archive-btn" data-id="${item.id}" type="button">Archive</button>`;
 
       return `
         <article class="notification-item ${item.isRead ? 'read' : 'unread'}">
@@ -45,6 +62,8 @@
             <div class="notification-actions">
               ${linkHtml}
               ${readButton}
+              ${archiveButton}
+              <button class="btn btn-outline btn-small notification-delete-btn" data-id="${item.id}" type="button">Delete</button>
             </div>
           </div>
         </article>
@@ -53,7 +72,11 @@
   };
 
   const loadNotifications = () => {
-    fetch('/api/notifications', { credentials: 'same-origin' })
+    const params = new URLSearchParams({ page: String(page), limit: String(pageSize) });
+    if (statusSelect.value) params.set('status', statusSelect.value);
+    if (typeSelect.value) params.set('type', typeSelect.value);
+
+    fetch(`/api/notifications?${params}`, { credentials: 'same-origin' })
       .then((response) => {
         if (response.status === 401) {
           return { __unauthorized: true };
@@ -73,7 +96,10 @@
 
         readAllButton.disabled = false;
         items = Array.isArray(payload.items) ? payload.items : [];
-        render(Number(payload.unreadCount || 0));
+        unreadCount = Number(payload.unreadCount || 0);
+        totalPages = Math.max(1, Number((payload.meta && payload.meta.totalPages) || 1));
+        render();
+        openStream();
       })
       .catch(() => {
         readAllButton.disabled = false;
@@ -81,6 +107,52 @@
       });
   };
 
+  const startPolling = () => {
+    if (!pollTimer) {
+      pollTimer = window.setInterval(loadNotifications, 30000);
+    }
+  };
+
+  // New notifications and unread-count changes arrive over Server-Sent
+  // Events; polling is only used when the browser cannot keep a stream open.
+  const openStream = () => {
+    if (stream || pollTimer) {
+      return;
+    }
+    if (typeof window.EventSource !== 'function') {
+      startPolling();
+      return;
+    }
+
+    const lastEventId = page === 1 && items.length > 0 ? items[0].id : '';
+    stream = new EventSource(`/api/notifications/stream?lastEventId=${encodeURIComponent(lastEventId)}`);
+    stream.addEventListener('notification', (event) => {
+      const item = JSON.parse(event.data);
+      // Only the first page of the inbox or the unread list shows new items.
+      const shown = page === 1
+        && (statusSelect.value === '' || statusSelect.value === 'unread')
+        && (typeSelect.value === '' || typeSelect.value === item.type);
+      if (shown && !items.some((existing) => existing.id === item.id)) {
+        items = [item, ...items];
+        render();
+      }
+    });
+    stream.addEventListener('unread', (event) => {
+      unreadCount = Number(JSON.parse(event.data).unreadCount || 0);
+      render();
+    });
+    // Reads, archives and deletes, possibly from another tab or device.
+    stream.addEventListener('changed', () => {
+      loadNotifications();
+    });
+    stream.addEventListener('error', () => {
+      if (stream.readyState === EventSource.CLOSED) {
+        stream = null;
+        startPolling();
+      }
+    });
+  };
+
   const markAsRead = (id) => {
     fetch(`/api/notifications/${encodeURIComponent(id)}/read`, {
       method: 'POST',
@@ -90,6 +162,24 @@
     });
   };
 
+  const archive = (id) => {
+    fetch(`/api/notifications/${encodeURIComponent(id)}/archive`, {
+      method: 'POST',
+      credentials: 'same-origin',
+    }).then(() => {
+      loadNotifications();
+    });
+  };
+
+  const remove = (id) => {
+    fetch(`/api/notifications/${encodeURIComponent(id)}`, {
+      method: 'DELETE',
+      credentials: 'same-origin',
+    }).then(() => {
+      loadNotifications();
+    });
+  };
+
   const markAllAsRead = () => {
     readAllButton.disabled = true;
     fetch('/api/notifications/read-all', {
@@ -104,19 +194,41 @@
       });
   };
 
+  const actions = {
+    'notification-read-btn': markAsRead,
+    'notification-archive-btn': archive,
+    'notification-delete-btn': remove,
+  };
+
   listNode.addEventListener('click', (event) => {
-    const button = event.target.closest('.notification-read-btn');
+    const button = event.target.closest('button[data-id]');
     if (!button) {
       return;
     }
     const id = button.dataset.id;
-    if (!id) {
+    const action = Object.keys(actions).find((name) => button.classList.contains(name));
+    if (!id || !action) {
       return;
     }
-    markAsRead(id);
+    actions[action](id);
   });
 
+  const showFirstPage = () => {
+    page = 1;
+    loadNotifications();
+  };
+
   readAllButton.addEventListener('click', markAllAsRead);
+  statusSelect.addEventListener('change', showFirstPage);
+  typeSelect.addEventListener('change', showFirstPage);
+  prevButton.addEventListener('click', () => {
+    page = Math.max(1, page - 1);
+    loadNotifications();
+  });
+  nextButton.addEventListener('click', () => {
+    page = Math.min(totalPages, page + 1);
+    loadNotifications();
+  });
 
   loadNotifications();
 })();
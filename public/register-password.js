@@ -83,6 +83,9 @@
   };
 
   const updateTerms = () => {
+    if (!termsInput) {
+      return true;
+    }
     termsInput.setCustomValidity(termsInput.checked ? '' : 'You must accept the terms to continue.');
     return termsInput.checked;
   };
@@ -107,7 +110,9 @@
   });
 
   confirmInput.addEventListener('input', updateConfirm);
-  termsInput.addEventListener('change', updateTerms);
+  if (termsInput) {
+    termsInput.addEventListener('change', updateTerms);
+  }
 
   form.addEventListener('submit', (event) => {
     const ok = updateRules() && updateConfirm() && updateTerms();